@@ -6,7 +6,6 @@ import (
 	"fmt"
 
 	"github.com/oscal-compass/compliance-to-policy-go/v2/framework"
-	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
 	"github.com/oscal-compass/oscal-sdk-go/extensions"
 	"github.com/oscal-compass/oscal-sdk-go/validation"
 	"github.com/spf13/cobra"
@@ -18,8 +17,12 @@ import (
 // generateOptions defines options for the "generate" subcommand
 type generateOptions struct {
 	*option.Common
-	complyTimeOpts   *option.ComplyTime
-	withPluginConfig string
+	complyTimeOpts    *option.ComplyTime
+	launchOpts        *option.Launch
+	withPluginConfig  string
+	pluginConfigRoots map[string]string
+	requiredOptions   []string
+	pluginProfiles    map[string]string
 }
 
 // generateCmd creates a new cobra.Command for the "generate" subcommand
@@ -27,6 +30,7 @@ func generateCmd(common *option.Common) *cobra.Command {
 	generateOpts := &generateOptions{
 		Common:         common,
 		complyTimeOpts: &option.ComplyTime{},
+		launchOpts:     &option.Launch{},
 	}
 	cmd := &cobra.Command{
 		Use:     "generate [flags]",
@@ -38,7 +42,11 @@ func generateCmd(common *option.Common) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&generateOpts.withPluginConfig, "plugin-config", "c", "", "Directory where user customized plugin manifests located.")
+	cmd.Flags().StringToStringVar(&generateOpts.pluginConfigRoots, "plugin-config-dirs", nil, "Directories where user customized plugin manifests are located by plugin id, e.g. openscap=/path. They take precedence over --plugin-config.")
+	cmd.Flags().StringToStringVar(&generateOpts.pluginProfiles, "plugin-profiles", nil, "Profiles used by plugins by plugin id, e.g. openscap=cis. They take precedence over the framework of the assessment plan.")
+	cmd.Flags().StringSliceVar(&generateOpts.requiredOptions, "required-plugin-options", nil, "Plugin options that must be explicitly configured for every plugin, in the user customized plugin manifests or the environment.")
 	generateOpts.complyTimeOpts.BindFlags(cmd.Flags())
+	generateOpts.launchOpts.BindFlags(cmd.Flags())
 	return cmd
 }
 
@@ -84,16 +92,25 @@ func runGenerate(cmd *cobra.Command, opts *generateOptions) error {
 
 	pluginOptions := opts.complyTimeOpts.ToPluginOptions()
 	pluginOptions.UserConfigRoot = opts.withPluginConfig
-	plugins, cleanup, err := complytime.Plugins(manager, inputContext, pluginOptions, logger)
+	pluginOptions.PluginConfigRoots = opts.pluginConfigRoots
+	pluginOptions.RequiredOptions = opts.requiredOptions
+	pluginOptions.PluginProfiles = opts.pluginProfiles
+	launchOptions, err := opts.launchOpts.ToLaunchOptions()
+	if err != nil {
+		return err
+	}
+	plugins, pluginSelections, cleanup, err := complytime.PluginsWithSelections(manager, inputContext, pluginOptions, launchOptions, logger)
 	if cleanup != nil {
 		defer cleanup()
 	}
+	logPluginSelections(pluginSelections)
 	if err != nil {
 		return fmt.Errorf("errors launching plugins: %w", err)
 	}
 
-	err = actions.GeneratePolicy(cmd.Context(), inputContext, plugins)
-	if err != nil {
+	// a plugin failing to generate its remediation files is reported
+	// without failing the generation, its policy can still be scanned
+	if err := complytime.GeneratePolicies(cmd.Context(), inputContext, plugins, logger); err != nil {
 		return err
 	}
 
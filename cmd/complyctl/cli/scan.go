@@ -3,13 +3,20 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
 	"github.com/oscal-compass/compliance-to-policy-go/v2/framework"
 	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
 	"github.com/oscal-compass/oscal-sdk-go/extensions"
 	"github.com/oscal-compass/oscal-sdk-go/settings"
 	"github.com/oscal-compass/oscal-sdk-go/validation"
@@ -25,8 +32,12 @@ const assessmentResultsLocationMd = "assessment-results.md"
 // scanOptions defined options for the scan subcommand.
 type scanOptions struct {
 	*option.Common
-	complyTimeOpts   *option.ComplyTime
-	withPluginConfig string
+	complyTimeOpts    *option.ComplyTime
+	launchOpts        *option.Launch
+	withPluginConfig  string
+	pluginConfigRoots map[string]string
+	requiredOptions   []string
+	pluginProfiles    map[string]string
 }
 
 // scanCmd creates a new cobra.Command for the version subcommand.
@@ -34,6 +45,7 @@ func scanCmd(common *option.Common) *cobra.Command {
 	scanOpts := &scanOptions{
 		Common:         common,
 		complyTimeOpts: &option.ComplyTime{},
+		launchOpts:     &option.Launch{},
 	}
 	cmd := &cobra.Command{
 		Use:          "scan [flags]",
@@ -46,8 +58,12 @@ func scanCmd(common *option.Common) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&scanOpts.withPluginConfig, "plugin-config", "c", "", "Directory where user customized plugin manifests located.")
+	cmd.Flags().StringToStringVar(&scanOpts.pluginConfigRoots, "plugin-config-dirs", nil, "Directories where user customized plugin manifests are located by plugin id, e.g. openscap=/path. They take precedence over --plugin-config.")
+	cmd.Flags().StringToStringVar(&scanOpts.pluginProfiles, "plugin-profiles", nil, "Profiles used by plugins by plugin id, e.g. openscap=cis. They take precedence over the framework of the assessment plan.")
+	cmd.Flags().StringSliceVar(&scanOpts.requiredOptions, "required-plugin-options", nil, "Plugin options that must be explicitly configured for every plugin, in the user customized plugin manifests or the environment.")
 	cmd.Flags().BoolP("with-md", "m", false, "If true, assessement-result markdown will be generated")
 	scanOpts.complyTimeOpts.BindFlags(cmd.Flags())
+	scanOpts.launchOpts.BindFlags(cmd.Flags())
 	return cmd
 }
 
@@ -95,26 +111,39 @@ func runScan(cmd *cobra.Command, opts *scanOptions) error {
 
 	pluginOptions := opts.complyTimeOpts.ToPluginOptions()
 	pluginOptions.UserConfigRoot = opts.withPluginConfig
-	plugins, cleanup, err := complytime.Plugins(manager, inputContext, pluginOptions, logger)
+	pluginOptions.PluginConfigRoots = opts.pluginConfigRoots
+	pluginOptions.RequiredOptions = opts.requiredOptions
+	pluginOptions.PluginProfiles = opts.pluginProfiles
+	launchOptions, err := opts.launchOpts.ToLaunchOptions()
+	if err != nil {
+		return err
+	}
+	plugins, pluginSelections, cleanup, err := complytime.PluginsWithSelections(manager, inputContext, pluginOptions, launchOptions, logger)
 	if cleanup != nil {
 		defer cleanup()
 	}
+	logPluginSelections(pluginSelections)
 	if err != nil {
 		return fmt.Errorf("errors launching plugins: %w", err)
 	}
 	logger.Info(fmt.Sprintf("Successfully loaded %v plugin(s).", len(plugins)))
 
-	allResults, err := actions.AggregateResults(cmd.Context(), inputContext, plugins)
-	if err != nil {
-		return err
+	// all the plugins are queried even if one of them fails, and the results
+	// of the plugins that succeeded are reported before their errors are returned
+	aggregatedResults, resultsErr := complytime.AggregateResults(cmd.Context(), inputContext, plugins, logger)
+	if resultsErr != nil {
+		resultsErr = fmt.Errorf("errors getting plugin results: %w", resultsErr)
 	}
 
 	// Collect results in a single report
 	planHref := fmt.Sprintf("file://%s", apCleanedPath)
-	assessmentResults, err := actions.Report(cmd.Context(), inputContext, planHref, *ap, allResults)
+	assessmentResults, err := actions.Report(cmd.Context(), inputContext, planHref, *ap, []policy.PVPResult{aggregatedResults})
 	if err != nil {
 		return err
 	}
+	if err := logControlRollups(cmd.Context(), ap, inputContext, aggregatedResults); err != nil {
+		return err
+	}
 	arJsonPath := filepath.Join(opts.complyTimeOpts.UserWorkspace, assessmentResultsLocationJson)
 	err = complytime.WriteAssessmentResults(assessmentResults, arJsonPath)
 	if err != nil {
@@ -179,5 +208,43 @@ func runScan(cmd *cobra.Command, opts *scanOptions) error {
 	} else {
 		logger.Info("No assessment result in markdown will be generated.")
 	}
+	return resultsErr
+}
+
+// logPluginSelections logs the configuration each plugin was launched with,
+// with the values of sensitive options redacted.
+func logPluginSelections(pluginSelections map[plugin.ID]complytime.Selections) {
+	for _, pluginId := range slices.Sorted(maps.Keys(pluginSelections)) {
+		logger.Debug(fmt.Sprintf("Plugin %s configuration: %v", pluginId, pluginSelections[pluginId].Redacted()))
+	}
+}
+
+// logControlRollups logs the status of each control of the assessment plan
+// rolled up from the results of its rules, and the number of controls by
+// status.
+func logControlRollups(ctx context.Context, ap *oscalTypes.AssessmentPlan, inputContext *actions.InputContext, results policy.PVPResult) error {
+	rulesByControl, err := complytime.RulesByControl(ap)
+	if err != nil {
+		return err
+	}
+	rollups, err := complytime.RollupControls(ctx, results, inputContext.Store(), rulesByControl)
+	if err != nil {
+		return err
+	}
+	statuses := make(map[complytime.ControlStatus]int)
+	for _, rollup := range rollups {
+		statuses[rollup.Status]++
+		ruleResults := make([]string, 0, len(rollup.Rules))
+		for _, rule := range rollup.Rules {
+			result := "no result"
+			if rule.Result != policy.ResultInvalid {
+				result = rule.Result.String()
+			}
+			ruleResults = append(ruleResults, fmt.Sprintf("%s (%s)", rule.RuleID, result))
+		}
+		logger.Debug(fmt.Sprintf("Control %s: %s, rules: %s", rollup.ControlID, rollup.Status, strings.Join(ruleResults, ", ")))
+	}
+	logger.Info(fmt.Sprintf("Controls: %d passed, %d failed, %d partially assessed.",
+		statuses[complytime.ControlPass], statuses[complytime.ControlFail], statuses[complytime.ControlPartial]))
 	return nil
 }
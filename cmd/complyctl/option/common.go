@@ -3,8 +3,13 @@
 package option
 
 import (
+	"fmt"
 	"io"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
 
 	"github.com/spf13/pflag"
 
@@ -54,3 +59,43 @@ func (o *ComplyTime) ToPluginOptions() complytime.PluginOptions {
 	pluginOptions.Profile = o.FrameworkID
 	return pluginOptions
 }
+
+// Launch options control how plugins are launched by the commands running them.
+type Launch struct {
+	// MaxConcurrentLaunches is the maximum number of plugins launched at the same time.
+	MaxConcurrentLaunches int
+	// BestEffort runs the plugins that are installed when some requested plugins are missing.
+	BestEffort bool
+	// LaunchTimeout is the time given to each plugin to launch.
+	LaunchTimeout time.Duration
+	// Dependencies are plugin dependencies in the form <plugin id>=<dependency id>.
+	Dependencies []string
+}
+
+// BindFlags populate Launch options from user-specified flags.
+func (o *Launch) BindFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.MaxConcurrentLaunches, "max-concurrent-launches", 1, "maximum number of plugins launched at the same time")
+	fs.BoolVar(&o.BestEffort, "best-effort", false, "skip requested plugins that are not installed instead of failing")
+	fs.DurationVar(&o.LaunchTimeout, "plugin-launch-timeout", 5*time.Minute, "time given to each plugin to launch, 0 to wait indefinitely")
+	fs.StringSliceVar(&o.Dependencies, "plugin-dependencies", nil, "plugins launched before other plugins, e.g. analyzer=collector to launch the collector plugin before the analyzer plugin")
+}
+
+// ToLaunchOptions returns the complytime LaunchOptions based on Launch options.
+func (o *Launch) ToLaunchOptions() (complytime.LaunchOptions, error) {
+	launchOptions := complytime.LaunchOptions{
+		MaxConcurrentLaunches: o.MaxConcurrentLaunches,
+		BestEffort:            o.BestEffort,
+		LaunchTimeout:         o.LaunchTimeout,
+	}
+	for _, dependency := range o.Dependencies {
+		pluginId, dependencyId, ok := strings.Cut(dependency, "=")
+		if !ok || !plugin.ID(pluginId).Validate() || !plugin.ID(dependencyId).Validate() {
+			return launchOptions, fmt.Errorf("invalid plugin dependency %q: must be <plugin id>=<dependency id>", dependency)
+		}
+		if launchOptions.Dependencies == nil {
+			launchOptions.Dependencies = make(map[plugin.ID][]plugin.ID)
+		}
+		launchOptions.Dependencies[plugin.ID(pluginId)] = append(launchOptions.Dependencies[plugin.ID(pluginId)], plugin.ID(dependencyId))
+	}
+	return launchOptions, nil
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+// ManifestFile is the name of the file listing the generated artifacts
+// in the plugin directory of the workspace.
+const ManifestFile string = "artifacts.json"
+
+// Artifact types produced by the plugin.
+const (
+	TypeTailoring   string = "tailoring"
+	TypeRemediation string = "remediation"
+)
+
+// ErrArtifactModified is returned when a file changed since it was recorded
+// in the manifest.
+var ErrArtifactModified = errors.New("artifact modified since it was generated")
+
+// Artifact describes a file generated by the plugin.
+type Artifact struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Format   string `json:"format"`
+	Checksum string `json:"sha256"`
+	// Command is the command line that generated the file, when recorded.
+	Command string `json:"command,omitempty"`
+}
+
+// Manifest lists the artifacts produced by the latest generate command.
+type Manifest struct {
+	GeneratedAt time.Time  `json:"generatedAt"`
+	Artifacts   []Artifact `json:"artifacts"`
+}
+
+// NewArtifact describes the file at the given path, computing its checksum.
+func NewArtifact(path, artifactType, format string) (Artifact, error) {
+	checksum, err := config.FileChecksum(path)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to describe artifact %s: %w", path, err)
+	}
+	return Artifact{
+		Path:     path,
+		Type:     artifactType,
+		Format:   format,
+		Checksum: checksum,
+	}, nil
+}
+
+// Verify checks that the file at path still has the checksum recorded in the
+// manifest. It returns false when the manifest does not record the file.
+func (m Manifest) Verify(path string) (bool, error) {
+	for _, artifact := range m.Artifacts {
+		if artifact.Path != path {
+			continue
+		}
+		checksum, err := config.FileChecksum(path)
+		if err != nil {
+			return true, fmt.Errorf("failed to verify artifact %s: %w", path, err)
+		}
+		if checksum != artifact.Checksum {
+			return true, fmt.Errorf("%w: %s has checksum %s, expected %s", ErrArtifactModified, path, checksum, artifact.Checksum)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// ManifestPath returns the location of the artifacts manifest in a workspace.
+func ManifestPath(workspace string) string {
+	return filepath.Join(workspace, config.PluginDir, ManifestFile)
+}
+
+// WriteManifest atomically replaces the manifest at manifestPath.
+func WriteManifest(manifestPath string, manifest Manifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode artifacts manifest: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(manifestPath), "."+ManifestFile+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create artifacts manifest: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write artifacts manifest: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write artifacts manifest: %w", err)
+	}
+	return os.Rename(tmpFile.Name(), manifestPath)
+}
+
+// ReadManifest loads the manifest at manifestPath.
+func ReadManifest(manifestPath string) (Manifest, error) {
+	var manifest Manifest
+	content, err := os.ReadFile(filepath.Clean(manifestPath))
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read artifacts manifest: %w", err)
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse artifacts manifest %s: %w", manifestPath, err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package artifacts
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArtifact(t *testing.T) {
+	tempDir := t.TempDir()
+	artifactPath := filepath.Join(tempDir, "remediation-script.sh")
+	require.NoError(t, os.WriteFile(artifactPath, []byte("echo test\n"), 0600))
+
+	artifact, err := NewArtifact(artifactPath, TypeRemediation, "bash")
+	require.NoError(t, err)
+	require.Equal(t, Artifact{
+		Path:     artifactPath,
+		Type:     TypeRemediation,
+		Format:   "bash",
+		Checksum: "056302317aae93b3c0cfcf9b2d8300c6f77fca580d1848d229799cc4edd47901",
+	}, artifact)
+
+	_, err = NewArtifact(filepath.Join(tempDir, "absent.sh"), TypeRemediation, "bash")
+	require.Error(t, err)
+}
+
+func TestManifestVerify(t *testing.T) {
+	tempDir := t.TempDir()
+	tailoringPath := filepath.Join(tempDir, "tailoring_policy.xml")
+	require.NoError(t, os.WriteFile(tailoringPath, []byte("<Tailoring/>"), 0600))
+	tailoring, err := NewArtifact(tailoringPath, TypeTailoring, "xccdf")
+	require.NoError(t, err)
+	manifest := Manifest{Artifacts: []Artifact{tailoring}}
+
+	recorded, err := manifest.Verify(tailoringPath)
+	require.NoError(t, err)
+	require.True(t, recorded)
+
+	recorded, err = manifest.Verify(filepath.Join(tempDir, "other.xml"))
+	require.NoError(t, err)
+	require.False(t, recorded)
+
+	require.NoError(t, os.WriteFile(tailoringPath, []byte("<Tailoring edited=\"true\"/>"), 0600))
+	recorded, err = manifest.Verify(tailoringPath)
+	require.ErrorIs(t, err, ErrArtifactModified)
+	require.True(t, recorded)
+}
+
+func TestWriteManifest(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), ManifestFile)
+	manifest := Manifest{
+		GeneratedAt: time.Date(2025, 6, 10, 10, 0, 0, 0, time.UTC),
+		Artifacts: []Artifact{
+			{Path: "tailoring_policy.xml", Type: TypeTailoring, Format: "xccdf", Checksum: "abc"},
+		},
+	}
+	require.NoError(t, WriteManifest(manifestPath, manifest))
+	got, err := ReadManifest(manifestPath)
+	require.NoError(t, err)
+	require.Equal(t, manifest, got)
+
+	// The manifest is replaced by following writes
+	manifest.Artifacts = nil
+	require.NoError(t, WriteManifest(manifestPath, manifest))
+	got, err = ReadManifest(manifestPath)
+	require.NoError(t, err)
+	require.Empty(t, got.Artifacts)
+
+	entries, err := os.ReadDir(filepath.Dir(manifestPath))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestWriteBundle(t *testing.T) {
+	tempDir := t.TempDir()
+	arfPath := filepath.Join(tempDir, "results", "arf.xml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(arfPath), 0700))
+	require.NoError(t, os.WriteFile(arfPath, []byte("<arf/>"), 0600))
+	tailoringPath := filepath.Join(tempDir, "policy", "tailoring_policy.xml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(tailoringPath), 0700))
+	require.NoError(t, os.WriteFile(tailoringPath, []byte("<tailoring/>"), 0600))
+	manifest := Manifest{
+		GeneratedAt: time.Date(2025, 6, 10, 10, 0, 0, 0, time.UTC),
+		Artifacts: []Artifact{
+			{Path: arfPath, Type: TypeARF, Format: "xml", Checksum: "abc"},
+			{Path: tailoringPath, Type: TypeTailoring, Format: "xccdf", Checksum: "def"},
+		},
+	}
+	bundlePath := filepath.Join(tempDir, "evidence.tar.gz")
+	require.NoError(t, WriteBundle(bundlePath, manifest))
+
+	bundleFile, err := os.Open(bundlePath)
+	require.NoError(t, err)
+	defer bundleFile.Close()
+	gzipReader, err := gzip.NewReader(bundleFile)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	contents := make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		contents[header.Name] = string(content)
+	}
+	require.Equal(t, "<arf/>", contents["arf.xml"])
+	require.Equal(t, "<tailoring/>", contents["tailoring_policy.xml"])
+
+	// artifacts are referenced relative to the bundle
+	var bundleManifest Manifest
+	require.NoError(t, json.Unmarshal([]byte(contents[ManifestFile]), &bundleManifest))
+	require.Equal(t, "arf.xml", bundleManifest.Artifacts[0].Path)
+	require.Equal(t, "tailoring_policy.xml", bundleManifest.Artifacts[1].Path)
+	require.Equal(t, "def", bundleManifest.Artifacts[1].Checksum)
+
+	manifest.Artifacts = append(manifest.Artifacts, Artifact{Path: filepath.Join(tempDir, "absent.sh"), Type: TypeRemediation})
+	require.Error(t, WriteBundle(bundlePath, manifest))
+}
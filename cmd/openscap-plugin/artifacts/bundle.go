@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package artifacts
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Artifact types only found in evidence bundles.
+const (
+	TypeARF                   string = "arf"
+	TypeSummary               string = "summary"
+	TypeReport                string = "report"
+	TypeSystemCharacteristics string = "system-characteristics"
+)
+
+// WriteBundle writes the artifacts of the manifest to a tar.gz archive at
+// bundlePath, along with the manifest. Artifacts are stored at the root of the
+// archive under their file name, which is the path recorded in the manifest of
+// the bundle.
+func WriteBundle(bundlePath string, manifest Manifest) error {
+	bundleFile, err := os.Create(filepath.Clean(bundlePath))
+	if err != nil {
+		return fmt.Errorf("failed to create evidence bundle: %w", err)
+	}
+	defer bundleFile.Close()
+	gzipWriter := gzip.NewWriter(bundleFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	bundleManifest := Manifest{GeneratedAt: manifest.GeneratedAt}
+	for _, artifact := range manifest.Artifacts {
+		name := filepath.Base(artifact.Path)
+		if err := addFile(tarWriter, artifact.Path, name); err != nil {
+			return fmt.Errorf("failed to add %s to evidence bundle: %w", artifact.Path, err)
+		}
+		artifact.Path = name
+		bundleManifest.Artifacts = append(bundleManifest.Artifacts, artifact)
+	}
+
+	content, err := json.MarshalIndent(bundleManifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode evidence bundle manifest: %w", err)
+	}
+	header := &tar.Header{
+		Name:    ManifestFile,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: manifest.GeneratedAt,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add manifest to evidence bundle: %w", err)
+	}
+	if _, err := tarWriter.Write(content); err != nil {
+		return fmt.Errorf("failed to add manifest to evidence bundle: %w", err)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write evidence bundle: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write evidence bundle: %w", err)
+	}
+	return bundleFile.Close()
+}
+
+// addFile writes the file at path to the archive under the given name.
+func addFile(tarWriter *tar.Writer, path, name string) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
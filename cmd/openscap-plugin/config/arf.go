@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// arfTimestampFormat is the format of the ${timestamp} placeholder of the ARF
+// file name, in UTC.
+const arfTimestampFormat = "20060102T150405Z"
+
+// isARFTemplate reports whether the ARF file name has placeholders.
+func isARFTemplate(arf string) bool {
+	return strings.Contains(arf, "$")
+}
+
+// expandARFTemplate replaces the ${profile}, ${timestamp} and ${hostname}
+// placeholders of an ARF file name template.
+func expandARFTemplate(template, profile string, now time.Time, hostname string) (string, error) {
+	var unknown []string
+	arf := os.Expand(template, func(name string) string {
+		switch name {
+		case "profile":
+			return profile
+		case "timestamp":
+			return now.UTC().Format(arfTimestampFormat)
+		case "hostname":
+			return hostname
+		}
+		unknown = append(unknown, name)
+		return ""
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("arf template %q references unknown placeholders: %s", template, strings.Join(unknown, ", "))
+	}
+	return arf, nil
+}
+
+// validateARFTemplate checks that the ARF file name template results in a
+// valid file name.
+func (c *Config) validateARFTemplate() error {
+	arf, err := expandARFTemplate(c.Files.ARF, c.Parameters.Profile, time.Now(), "localhost")
+	if err != nil {
+		return err
+	}
+	if _, err := SanitizeInput(arf); err != nil {
+		return fmt.Errorf("invalid arf template %q: %w", c.Files.ARF, err)
+	}
+	return nil
+}
+
+// ResolveARF sets the ARF path from the ARF file name template, if any, with
+// the profile, the given time and the hostname of the system. The template is
+// kept, so the ARF path is resolved again for each scan and the results of
+// previous scans are not overwritten.
+func (c *Config) ResolveARF(now time.Time) error {
+	if c.arfTemplate == "" {
+		if !isARFTemplate(filepath.Base(c.Files.ARF)) {
+			return nil
+		}
+		c.arfTemplate = c.Files.ARF
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to resolve arf template: %w", err)
+	}
+	// only the file name is a template, the results directory is not expanded
+	arf, err := expandARFTemplate(filepath.Base(c.arfTemplate), c.Parameters.Profile, now, hostname)
+	if err != nil {
+		return err
+	}
+	c.Files.ARF = filepath.Join(filepath.Dir(c.arfTemplate), arf)
+	return nil
+}
+
+// arfPipeFile is the name of the file in the results directory the ARF is
+// written to, and its results read from, when the arf option is a named pipe.
+const arfPipeFile = "arf.xml"
+
+// resolveARFPipe sets the named pipe the ARF is written to when the arf option
+// is the absolute path of a named pipe. The ARF is then buffered in a file of
+// the results directory, since its results, the HTML report and the evidence
+// are read from a file that can be rewound. The standard output of the plugin
+// cannot be used, since complyctl does not forward it.
+func (c *Config) resolveARFPipe() error {
+	if c.Files.ARF == "-" {
+		return errors.New("invalid arf \"-\": the standard output of the plugin is not forwarded by complyctl, use a named pipe")
+	}
+	if !filepath.IsAbs(c.Files.ARF) {
+		return nil
+	}
+	cleanPath, err := SanitizePath(c.Files.ARF)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return fmt.Errorf("invalid arf: %w", err)
+	}
+	if info.Mode()&fs.ModeNamedPipe == 0 {
+		return fmt.Errorf("invalid arf %s: an absolute path must be a named pipe", cleanPath)
+	}
+	c.arfPipe = cleanPath
+	c.Files.ARF = arfPipeFile
+	return nil
+}
+
+// ARFPipe returns the named pipe the ARF is written to once the system is
+// scanned, or an empty string when the ARF is only written to a file.
+func (c *Config) ARFPipe() string {
+	return c.arfPipe
+}
+
+// validateARFPipe checks the options that cannot be combined with writing the
+// ARF to a named pipe.
+func (c *Config) validateARFPipe() error {
+	if c.arfPipe == "" {
+		return nil
+	}
+	// a single ARF is written to the pipe
+	switch {
+	case len(c.ScanHosts()) > 0:
+		return errors.New("an arf named pipe cannot be combined with hosts")
+	case len(c.AdditionalDatastreams()) > 0:
+		return errors.New("an arf named pipe cannot be combined with datastreams")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandARFTemplate(t *testing.T) {
+	now := time.Date(2025, 3, 14, 9, 26, 53, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name      string
+		template  string
+		wantARF   string
+		wantError string
+	}{
+		{
+			name:     "Valid/AllPlaceholders",
+			template: "arf-${profile}-${hostname}-${timestamp}.xml",
+			wantARF:  "arf-cis-rhel10-20250314T082653Z.xml",
+		},
+		{
+			name:     "Valid/NoPlaceholders",
+			template: "arf.xml",
+			wantARF:  "arf.xml",
+		},
+		{
+			name:      "Invalid/UnknownPlaceholder",
+			template:  "arf-${user}-${date}.xml",
+			wantError: `arf template "arf-${user}-${date}.xml" references unknown placeholders: user, date`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arf, err := expandARFTemplate(tt.template, "cis", now, "rhel10")
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantARF, arf)
+		})
+	}
+}
+
+func TestResolveARF(t *testing.T) {
+	resultsDir := filepath.Join(t.TempDir(), "openscap", "results")
+	cfg := NewConfig()
+	cfg.Parameters.Profile = "cis"
+
+	// an ARF path without placeholders is left as is
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	require.NoError(t, cfg.ResolveARF(time.Now()))
+	require.Equal(t, filepath.Join(resultsDir, "arf.xml"), cfg.Files.ARF)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf-${profile}-${hostname}-${timestamp}.xml")
+	first := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+	require.NoError(t, cfg.ResolveARF(first))
+	require.Equal(t, filepath.Join(resultsDir, "arf-cis-"+hostname+"-20250314T092653Z.xml"), cfg.Files.ARF)
+
+	// each scan resolves its own ARF path from the template
+	require.NoError(t, cfg.ResolveARF(first.Add(time.Hour)))
+	require.Equal(t, filepath.Join(resultsDir, "arf-cis-"+hostname+"-20250314T102653Z.xml"), cfg.Files.ARF)
+}
+
+func TestResolveARFPipe(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Files.ARF = "arf.xml"
+	require.NoError(t, cfg.resolveARFPipe())
+	require.Empty(t, cfg.ARFPipe())
+	require.Equal(t, "arf.xml", cfg.Files.ARF)
+
+	pipePath := filepath.Join(t.TempDir(), "arf.pipe")
+	require.NoError(t, syscall.Mkfifo(pipePath, 0600))
+	cfg.Files.ARF = pipePath
+	require.NoError(t, cfg.resolveARFPipe())
+	require.Equal(t, pipePath, cfg.ARFPipe())
+	// the ARF is buffered in the results directory
+	require.Equal(t, arfPipeFile, cfg.Files.ARF)
+	require.NoError(t, cfg.validateARFPipe())
+
+	cfg.Scan.Hosts = "rhel10"
+	require.EqualError(t, cfg.validateARFPipe(), "an arf named pipe cannot be combined with hosts")
+
+	// the ARF of a single rule is not written to the pipe
+	ruleConfig, err := cfg.ForRule("aide_build_database")
+	require.NoError(t, err)
+	require.Empty(t, ruleConfig.ARFPipe())
+}
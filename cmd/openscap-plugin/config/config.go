@@ -13,9 +13,15 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/defenseunicorns/go-oscal/src/pkg/versioning"
 	"github.com/hashicorp/go-hclog"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
 const (
@@ -23,10 +29,84 @@ const (
 	PolicyDir      string = "policy"
 	ResultsDir     string = "results"
 	RemediationDir string = "remediations"
+	ContentDir     string = "content"
 	DatastreamsDir string = "/usr/share/xml/scap/ssg/content"
 	SystemInfoFile string = "/etc/os-release"
 )
 
+// Supported parsers for reading the ARF file when collecting results.
+const (
+	// TreeParser loads the whole ARF document in memory before processing it.
+	TreeParser string = "tree"
+	// StreamParser decodes the ARF incrementally to bound memory usage.
+	StreamParser string = "stream"
+)
+
+// Supported checks of the platform of the system against the platforms of the
+// profile before scanning.
+const (
+	// PlatformCheckWarn logs a warning when the platform does not match.
+	PlatformCheckWarn string = "warn"
+	// PlatformCheckFail fails the scan when the platform does not match.
+	PlatformCheckFail string = "fail"
+	// PlatformCheckSkip does not check the platform.
+	PlatformCheckSkip string = "skip"
+)
+
+// Supported privileges needed by the profile to evaluate the system.
+const (
+	// PrivilegesRoot fails the scan when the plugin does not run as root.
+	PrivilegesRoot string = "root"
+	// PrivilegesUnprivileged fails the scan when oscap would run as root.
+	PrivilegesUnprivileged string = "unprivileged"
+)
+
+// Supported handling of scan results with fewer observations than the minimum,
+// which usually means the profile or the policy selects no evaluated rule.
+const (
+	// EmptyResultsWarn logs a warning when there are too few observations.
+	EmptyResultsWarn string = "warn"
+	// EmptyResultsFail fails the scan when there are too few observations.
+	EmptyResultsFail string = "fail"
+	// EmptyResultsSkip does not check the number of observations.
+	EmptyResultsSkip string = "skip"
+)
+
+// Supported formats of the tailoring document written next to the tailoring
+// file.
+const (
+	TailoringDocumentJSON string = "json"
+	TailoringDocumentYAML string = "yaml"
+)
+
+// Supported sources of the collection time of the observations.
+const (
+	// TimestampProcessing uses the time the results are processed at.
+	TimestampProcessing string = "processing"
+	// TimestampScan uses the time oscap evaluated each rule at, read from
+	// the ARF, and the processing time when the ARF has none.
+	TimestampScan string = "scan"
+)
+
+// Supported time zones of the collection time of the observations.
+const (
+	// TimeZoneUTC writes the times in UTC.
+	TimeZoneUTC string = "utc"
+	// TimeZoneLocal writes the times in the local time zone of the plugin.
+	TimeZoneLocal string = "local"
+)
+
+// Supported policies for a tailoring file that already exists when generating.
+const (
+	// OverwriteAlways replaces the existing tailoring file.
+	OverwriteAlways string = "overwrite"
+	// OverwriteFail fails the generation when the tailoring file exists.
+	OverwriteFail string = "fail"
+	// OverwriteKeep keeps the existing tailoring file, for example when it
+	// was edited manually.
+	OverwriteKeep string = "keep"
+)
+
 type Config struct {
 	Files struct {
 		Workspace  string `config:"workspace"`
@@ -38,6 +118,700 @@ type Config struct {
 	Parameters struct {
 		Profile string `config:"profile"`
 	}
+	// Content holds optional settings about the SCAP content.
+	Content struct {
+		DatastreamChecksum string `config:"datastreamchecksum,optional"`
+		// BenchmarkID is the id of the XCCDF benchmark evaluated when the
+		// datastream has several benchmarks.
+		BenchmarkID string `config:"benchmarkid,optional"`
+		// XCCDF and OVAL are used instead of a datastream when the content
+		// is distributed as separate files.
+		XCCDF string `config:"xccdf,optional"`
+		OVAL  string `config:"oval,optional"`
+		// Datastreams is a comma separated list of additional datastreams
+		// evaluated along with the datastream, for example the baseline of
+		// an application next to the baseline of the operating system.
+		Datastreams string `config:"datastreams,optional"`
+		// DatastreamSignature is a detached OpenPGP signature of the
+		// datastream, verified with the public keys of DatastreamKey
+		// before the datastream is used.
+		DatastreamSignature string `config:"datastreamsignature,optional"`
+		DatastreamKey       string `config:"datastreamkey,optional"`
+	}
+	// Tailoring holds optional settings used when generating the tailoring file.
+	Tailoring struct {
+		// SelectedRules is a comma separated list of rule ids to evaluate
+		// instead of all the rules in the policy.
+		SelectedRules string `config:"selectedrules,optional"`
+		// SelectedControls is a comma separated list of control ids whose
+		// rules are evaluated instead of all the rules in the policy.
+		SelectedControls string `config:"selectedcontrols,optional"`
+		// Overwrite is what to do with an existing tailoring file: replace
+		// it, fail or keep it.
+		Overwrite string `config:"overwrite,optional"`
+		// BaseProfile is the id of the datastream profile extended by the
+		// tailoring profile. Defaults to the profile.
+		BaseProfile string `config:"baseprofile,optional"`
+		// NamespacePrefix is the prefix of the XCCDF 1.2 namespace in the
+		// tailoring file, and Namespaces a comma separated list of
+		// <prefix>=<uri> additional namespaces declared in it.
+		NamespacePrefix string `config:"namespaceprefix,optional"`
+		Namespaces      string `config:"namespaces,optional"`
+		// Validate validates the generated tailoring against the XCCDF
+		// schema before writing it.
+		Validate bool `config:"validate,optional"`
+		// Document is the format, json or yaml, of a document of the
+		// tailoring written next to the tailoring file, if any.
+		Document string `config:"tailoringdocument,optional"`
+	}
+	// Layout holds optional directory templates of the artifacts in the
+	// workspace, replacing the default directories in the plugin directory.
+	Layout struct {
+		PolicyDir       string `config:"policydir,optional"`
+		ResultsDir      string `config:"resultsdir,optional"`
+		RemediationsDir string `config:"remediationsdir,optional"`
+		ContentDir      string `config:"contentdir,optional"`
+	}
+	// Scan holds optional settings used when evaluating the system.
+	Scan struct {
+		// Progress logs the progress of the evaluation while oscap runs.
+		Progress bool `config:"progress,optional"`
+		// RuleTiming records the time taken to evaluate each rule in the
+		// observations.
+		RuleTiming bool `config:"ruletiming,optional"`
+		// Root is the directory where an alternate filesystem is mounted,
+		// which is then evaluated offline instead of the live system.
+		Root string `config:"root,optional"`
+		// Hosts is a comma separated list of [user@]host[:port] remote
+		// hosts evaluated over SSH instead of the local system.
+		Hosts string `config:"hosts,optional"`
+		// Image is a container image reference evaluated with oscap-podman
+		// instead of the local system.
+		Image string `config:"image,optional"`
+		// Env is a comma separated list of NAME=value environment variables
+		// of oscap, such as OSCAP_PROBE_MEMORY_USAGE_RATIO=0.5, set when it
+		// evaluates the system.
+		Env string `config:"env,optional"`
+		// ExtraArgs is a comma separated list of additional oscap xccdf
+		// eval options, such as --thin-results, among the allowed ones.
+		ExtraArgs string `config:"extraargs,optional"`
+		// Concurrency is the maximum number of hosts evaluated in parallel.
+		Concurrency int `config:"concurrency,optional"`
+		// RecordCommands records the oscap command lines in the results
+		// summary and the artifacts manifest.
+		RecordCommands bool `config:"recordcommands,optional"`
+		// SkipTailoringCheck disables the verification of the tailoring
+		// file against the checksum recorded by the generate command.
+		SkipTailoringCheck bool `config:"skiptailoringcheck,optional"`
+		// SkipOscapCheck disables the verification that oscap is installed
+		// on configure, for environments installing it later.
+		SkipOscapCheck bool `config:"skiposcapcheck,optional"`
+		// ScanRetries is the number of times a failed evaluation is retried
+		// when oscap exits with one of RetryExitCodes, a comma separated
+		// list, or its output matches the RetryPattern regular expression,
+		// waiting ScanRetryDelay before each retry.
+		ScanRetries    int    `config:"scanretries,optional"`
+		ScanRetryDelay string `config:"scanretrydelay,optional"`
+		RetryExitCodes string `config:"retryexitcodes,optional"`
+		RetryPattern   string `config:"retrypattern,optional"`
+		// PlatformCheck is what to do when the system is not a platform of
+		// the profile: warn, fail or skip the check.
+		PlatformCheck string `config:"platformcheck,optional"`
+		// Privileges is the privileges the profile needs, root or
+		// unprivileged, checked before the scan. User is the name or id
+		// of the user oscap runs as when the plugin runs as root.
+		Privileges string `config:"privileges,optional"`
+		User       string `config:"scanuser,optional"`
+	}
+	// Results holds optional settings used when processing scan results.
+	Results struct {
+		Parser       string `config:"arfparser,optional"`
+		FailSeverity string `config:"failseverity,optional"`
+		// MaxFailures is the number of failures of any severity above
+		// which the results are blocking. Zero disables the budget.
+		MaxFailures int `config:"maxfailures,optional"`
+		// MinObservations is the number of observations below which the
+		// results are handled according to EmptyResults: warn, fail or
+		// skip the check. Defaults to one, so empty results are reported.
+		MinObservations int    `config:"minobservations,optional"`
+		EmptyResults    string `config:"emptyresults,optional"`
+		// TimestampSource is where the collection time of the observations
+		// comes from: the processing of the results or the scan. TimeZone
+		// is the zone the times are written in: utc, the default, or local.
+		TimestampSource string `config:"timestampsource,optional"`
+		TimeZone        string `config:"timezone,optional"`
+		// ResourceID is a static value or a template for the subject resource id.
+		ResourceID string `config:"resourceid,optional"`
+		// DocumentOrder keeps observations in ARF document order instead
+		// of sorting them.
+		DocumentOrder bool `config:"documentorder,optional"`
+		// Deduplicate collapses observations with the same check id,
+		// resource id and result into one.
+		Deduplicate bool `config:"deduplicate,optional"`
+		// ARFRetries is the number of times reading the ARF is retried when
+		// it is missing or incomplete, waiting ARFRetryDelay before the
+		// first retry and twice as long before each following retry.
+		ARFRetries    int    `config:"arfretries,optional"`
+		ARFRetryDelay string `config:"arfretrydelay,optional"`
+		// ARFBufferSize is the size in bytes of the buffer the ARF is read
+		// through. Zero uses the default size.
+		ARFBufferSize int `config:"arfbuffersize,optional"`
+		// ARFMaxSize is the size in bytes above which the ARF is rejected
+		// rather than parsed. Zero uses the default size.
+		ARFMaxSize int `config:"arfmaxsize,optional"`
+		// ARFMmap maps the ARF in memory instead of reading it through a
+		// buffer, which avoids copying large ARFs.
+		ARFMmap bool `config:"arfmmap,optional"`
+		// ARFXSLT is the path of an XSLT stylesheet the ARF is transformed
+		// with before its results are read.
+		ARFXSLT string `config:"arfxslt,optional"`
+		// RulePrefix restricts the collected results to the rules whose id
+		// starts with it.
+		RulePrefix string `config:"ruleprefix,optional"`
+		// Waivers is the path of a JSON file of waivers accepting the
+		// failures of rules.
+		Waivers string `config:"waivers,optional"`
+		// ResourceGroups is the path of a JSON file mapping evaluated
+		// targets to the resources their results apply to.
+		ResourceGroups string `config:"resourcegroups,optional"`
+		// TargetNames is the path of a JSON file mapping the targets
+		// reported in the ARF to their canonical names in the inventory.
+		TargetNames string `config:"targetnames,optional"`
+		// TestResult is the id of the TestResult whose results are
+		// collected when the ARF has several. Defaults to the latest one.
+		TestResult string `config:"testresult,optional"`
+		// ResultMapping is a comma separated list of <xccdf result>=<result>
+		// pairs overriding how rule results are mapped to policy results.
+		ResultMapping string `config:"resultmapping,optional"`
+		// CheckMapping is a comma separated list of <oval check>=<check id>
+		// pairs matching OVAL checks with OSCAL check ids named differently.
+		CheckMapping string `config:"checkmapping,optional"`
+		// LogSkipped logs the rule results not reported as observations
+		// and the reason, to diagnose mismatches of the policy and content.
+		LogSkipped bool `config:"logskipped,optional"`
+		// OVALVariables adds the values of the variables used by the OVAL
+		// checks, read from the OVAL results of the ARF, to the subjects.
+		OVALVariables bool `config:"ovalvariables,optional"`
+		// Provenance adds the version of the evaluated Benchmark, the name
+		// and version of the scanner and the evaluated profile, read from
+		// the TestResult of the ARF, to the subjects.
+		Provenance bool `config:"provenance,optional"`
+		// EvidenceURL is a base URL or a template for the href of the ARF
+		// evidence, used instead of the local file path.
+		EvidenceURL string `config:"evidenceurl,optional"`
+		// PropertyPrefix is prepended to the names of the properties emitted
+		// on subjects, so they can be told apart from properties of other sources.
+		PropertyPrefix string `config:"propertyprefix,optional"`
+		// AssessmentResults is the file name of the OSCAL assessment results
+		// written from the scan results.
+		AssessmentResults string `config:"assessmentresults,optional"`
+		// EvidenceBundle is the file name of a tar.gz archive packaging the
+		// evidence of the scan, written from the scan results.
+		EvidenceBundle string `config:"evidencebundle,optional"`
+		// HTMLReport is the file name of the HTML report generated by oscap
+		// from the ARF.
+		HTMLReport string `config:"htmlreport,optional"`
+		// SystemCharacteristics is the file name of the OVAL system
+		// characteristics exported from the ARF as evidence of the
+		// collected system state.
+		SystemCharacteristics string `config:"systemcharacteristics,optional"`
+		// OSCALVersion and AssessmentTitle are set in the metadata of the
+		// assessment results.
+		OSCALVersion    string `config:"oscalversion,optional"`
+		AssessmentTitle string `config:"assessmenttitle,optional"`
+		// Findings adds findings and risks for the failed rules to the
+		// assessment results, targeting the controls of FindingsFramework
+		// the rules are mapped to.
+		Findings          bool   `config:"findings,optional"`
+		FindingsFramework string `config:"findingsframework,optional"`
+		// SubjectType is the OSCAL type of the subjects of the observations.
+		SubjectType string `config:"subjecttype,optional"`
+	}
+
+	// arfTemplate is the ARF path with placeholders, set once the ARF path
+	// is first resolved.
+	arfTemplate string
+	// arfPipe is the named pipe the ARF is written to, set when the arf
+	// option is the path of a named pipe.
+	arfPipe string
+}
+
+// BaseProfile returns the id of the datastream profile extended by the
+// tailoring profile.
+func (c *Config) BaseProfile() string {
+	if c.Tailoring.BaseProfile == "" {
+		return c.Parameters.Profile
+	}
+	return c.Tailoring.BaseProfile
+}
+
+// DefaultFindingsFramework is the framework of the controls targeted by the
+// findings when not configured.
+const DefaultFindingsFramework = "nist"
+
+// FindingsFramework returns the short name of the framework of the controls
+// targeted by the findings of the assessment results.
+func (c *Config) FindingsFramework() string {
+	if c.Results.FindingsFramework == "" {
+		return DefaultFindingsFramework
+	}
+	return c.Results.FindingsFramework
+}
+
+// TailoringDocumentPath returns the path of the tailoring document of the
+// tailoring file at policyPath: the same path with the extension of the
+// document format.
+func (c *Config) TailoringDocumentPath(policyPath string) string {
+	return strings.TrimSuffix(policyPath, filepath.Ext(policyPath)) + "." + c.Tailoring.Document
+}
+
+// MinObservations returns the number of observations below which the scan
+// results are considered empty, at least one by default.
+func (c *Config) MinObservations() int {
+	if c.Results.MinObservations == 0 {
+		return 1
+	}
+	return c.Results.MinObservations
+}
+
+// DefaultSubjectType is the type of the subjects of the observations when not
+// configured.
+const DefaultSubjectType = "inventory-item"
+
+// subjectTypes are the OSCAL subject types complyctl accepts in the
+// assessment results it writes.
+var subjectTypes = []string{"inventory-item", "resource"}
+
+// SubjectType returns the OSCAL type of the subjects of the observations.
+func (c *Config) SubjectType() string {
+	if c.Results.SubjectType == "" {
+		return DefaultSubjectType
+	}
+	return c.Results.SubjectType
+}
+
+// validateSubjectType checks the subject type is one complyctl accepts.
+func (c *Config) validateSubjectType() error {
+	if subjectType := c.SubjectType(); !slices.Contains(subjectTypes, subjectType) {
+		return fmt.Errorf("invalid subject type %q: must be %q or %q", subjectType, subjectTypes[0], subjectTypes[1])
+	}
+	return nil
+}
+
+// Bounds of the retries of reading the ARF and of the configured delay before
+// the first retry, and this delay when not configured.
+const (
+	maxARFRetries         = 10
+	maxARFFirstRetryDelay = 10 * time.Second
+	defaultARFRetryDelay  = time.Second
+)
+
+// ARFRetryDelay returns the delay before the first retry of reading the ARF.
+func (c *Config) ARFRetryDelay() time.Duration {
+	delay, err := time.ParseDuration(c.Results.ARFRetryDelay)
+	if err != nil {
+		return defaultARFRetryDelay
+	}
+	return delay
+}
+
+// validateARFRetries checks the retries of reading the ARF are bounded.
+func (c *Config) validateARFRetries() error {
+	if c.Results.ARFRetries < 0 || c.Results.ARFRetries > maxARFRetries {
+		return fmt.Errorf("invalid ARF retries %d: must be between 0 and %d", c.Results.ARFRetries, maxARFRetries)
+	}
+	if c.Results.ARFRetryDelay == "" {
+		return nil
+	}
+	delay, err := time.ParseDuration(c.Results.ARFRetryDelay)
+	if err != nil {
+		return fmt.Errorf("invalid ARF retry delay: %w", err)
+	}
+	if delay <= 0 || delay > maxARFFirstRetryDelay {
+		return fmt.Errorf("invalid ARF retry delay %s: must be positive and at most %s", delay, maxARFFirstRetryDelay)
+	}
+	return nil
+}
+
+// Bounds of the size of the buffer the ARF is read through, and its size
+// when not configured.
+const (
+	minARFBufferSize     = 512
+	maxARFBufferSize     = 64 << 20
+	defaultARFBufferSize = 4096
+	// defaultARFMaxSize is larger than the ARF of a full profile with OVAL
+	// results, while bounding the memory used to parse untrusted ARFs.
+	defaultARFMaxSize = 1 << 30
+)
+
+// ARFBufferSize returns the size in bytes of the buffer the ARF is read
+// through.
+func (c *Config) ARFBufferSize() int {
+	if c.Results.ARFBufferSize == 0 {
+		return defaultARFBufferSize
+	}
+	return c.Results.ARFBufferSize
+}
+
+// ARFMaxSize returns the size in bytes above which the ARF is rejected.
+func (c *Config) ARFMaxSize() int {
+	if c.Results.ARFMaxSize == 0 {
+		return defaultARFMaxSize
+	}
+	return c.Results.ARFMaxSize
+}
+
+// validateARFBufferSize checks the size of the buffer the ARF is read
+// through is bounded.
+func (c *Config) validateARFBufferSize() error {
+	size := c.Results.ARFBufferSize
+	if size != 0 && (size < minARFBufferSize || size > maxARFBufferSize) {
+		return fmt.Errorf("invalid ARF buffer size %d: must be between %d and %d", size, minARFBufferSize, maxARFBufferSize)
+	}
+	if c.Results.ARFMaxSize < 0 {
+		return fmt.Errorf("invalid ARF max size %d: must not be negative", c.Results.ARFMaxSize)
+	}
+	return nil
+}
+
+// hostPattern matches a [user@]host[:port] remote host, where the host is a
+// name, an IPv4 address or an IPv6 address in brackets. The user and the host
+// name start with an alphanumeric character, so they cannot be taken for ssh
+// options such as -oProxyCommand.
+var hostPattern = regexp.MustCompile(`^([a-zA-Z0-9][a-zA-Z0-9._-]*@)?([a-zA-Z0-9][a-zA-Z0-9.-]*|\[[0-9a-fA-F:.]+\])(:[0-9]+)?$`)
+
+// imagePattern matches a container image reference, such as
+// registry.example.com/ubi10:latest, or an image id.
+var imagePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$`)
+
+// severityLevels are the known XCCDF rule severities, from lowest to highest.
+var severityLevels = []string{"info", "low", "medium", "high"}
+
+// MeetsSeverity reports whether a rule severity is at or above the threshold.
+// Unknown severities always meet the threshold, as does any severity when
+// no threshold is set.
+func MeetsSeverity(severity, threshold string) bool {
+	if threshold == "" {
+		return true
+	}
+	severityRank := slices.Index(severityLevels, severity)
+	if severityRank < 0 {
+		return true
+	}
+	return severityRank >= slices.Index(severityLevels, threshold)
+}
+
+// xccdfResults are the rule result statuses reported by oscap.
+var xccdfResults = []string{"pass", "fail", "error", "unknown", "notapplicable", "notchecked", "notselected", "informational", "fixed"}
+
+// mappedResults are the policy results rule result statuses can be mapped to.
+var mappedResults = []string{"pass", "fail", "error", "warning"}
+
+// ResultMapping returns the policy result overrides set in the resultmapping
+// option by rule result status, or nil when the default mapping is used.
+func (c *Config) ResultMapping() map[string]string {
+	mapping, _ := parseResultMapping(c.Results.ResultMapping)
+	return mapping
+}
+
+func parseResultMapping(value string) (map[string]string, error) {
+	var mapping map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		status, result, ok := strings.Cut(pair, "=")
+		status, result = strings.TrimSpace(status), strings.TrimSpace(result)
+		if !ok || status == "" || result == "" {
+			return nil, fmt.Errorf("invalid result mapping %q: must be <xccdf result>=<result>", pair)
+		}
+		if !slices.Contains(xccdfResults, status) {
+			return nil, fmt.Errorf("invalid result mapping %q: unknown xccdf result %q, must be one of %v", pair, status, xccdfResults)
+		}
+		if !slices.Contains(mappedResults, result) {
+			return nil, fmt.Errorf("invalid result mapping %q: unknown result %q, must be one of %v", pair, result, mappedResults)
+		}
+		if mapping == nil {
+			mapping = make(map[string]string)
+		}
+		mapping[status] = result
+	}
+	return mapping, nil
+}
+
+// CheckMapping returns the OSCAL check ids set in the checkmapping option by
+// OVAL check short name, or nil when checks are matched by name.
+func (c *Config) CheckMapping() map[string]string {
+	mapping, _ := parseCheckMapping(c.Results.CheckMapping)
+	return mapping
+}
+
+func parseCheckMapping(value string) (map[string]string, error) {
+	var mapping map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		ovalCheck, checkID, ok := strings.Cut(pair, "=")
+		ovalCheck, checkID = strings.TrimSpace(ovalCheck), strings.TrimSpace(checkID)
+		if !ok || ovalCheck == "" || checkID == "" {
+			return nil, fmt.Errorf("invalid check mapping %q: must be <oval check>=<check id>", pair)
+		}
+		if _, ok := mapping[ovalCheck]; ok {
+			return nil, fmt.Errorf("invalid check mapping %q: check %s is mapped several times", pair, ovalCheck)
+		}
+		if mapping == nil {
+			mapping = make(map[string]string)
+		}
+		mapping[ovalCheck] = checkID
+	}
+	return mapping, nil
+}
+
+// SelectedRuleIDs returns the rule ids set in the selectedrules option, or
+// nil when all the rules in the policy are evaluated.
+func (c *Config) SelectedRuleIDs() []string {
+	var ruleIDs []string
+	for _, ruleID := range strings.Split(c.Tailoring.SelectedRules, ",") {
+		if ruleID = strings.TrimSpace(ruleID); ruleID != "" {
+			ruleIDs = append(ruleIDs, ruleID)
+		}
+	}
+	return ruleIDs
+}
+
+// controlIDPattern matches a control id, such as ac-6.1 or AC-6(1), or a
+// family of controls, optionally restricted to a framework, such as
+// nist:ac-6.
+var controlIDPattern = regexp.MustCompile(`^([a-z0-9_-]+:)?[a-zA-Z0-9._()-]+$`)
+
+// SelectedControlIDs returns the control ids set in the selectedcontrols
+// option, or nil when the rules of all the controls are evaluated.
+func (c *Config) SelectedControlIDs() []string {
+	var controlIDs []string
+	for _, controlID := range strings.Split(c.Tailoring.SelectedControls, ",") {
+		if controlID = strings.TrimSpace(controlID); controlID != "" {
+			controlIDs = append(controlIDs, controlID)
+		}
+	}
+	return controlIDs
+}
+
+// ScanHosts returns the remote hosts set in the hosts option, or nil when the
+// local system is evaluated.
+func (c *Config) ScanHosts() []string {
+	var hosts []string
+	for _, host := range strings.Split(c.Scan.Hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// validateHosts checks the remote hosts and the options that cannot be
+// combined with them.
+func (c *Config) validateHosts() error {
+	if c.Scan.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d: must not be negative", c.Scan.Concurrency)
+	}
+	hosts := c.ScanHosts()
+	if len(hosts) == 0 {
+		return nil
+	}
+	for _, host := range hosts {
+		if !hostPattern.MatchString(host) {
+			return fmt.Errorf("invalid host %q: must be [user@]host[:port]", host)
+		}
+	}
+	switch {
+	case c.Scan.Root != "":
+		return errors.New("hosts cannot be combined with root")
+	case c.Results.HTMLReport != "":
+		return errors.New("hosts cannot be combined with htmlreport")
+	case c.Results.SystemCharacteristics != "":
+		return errors.New("hosts cannot be combined with systemcharacteristics")
+	case c.Results.EvidenceBundle != "":
+		return errors.New("hosts cannot be combined with evidencebundle")
+	// the privileges on the remote hosts are the ones of the SSH user
+	case c.Scan.Privileges != "":
+		return errors.New("hosts cannot be combined with privileges")
+	case c.Scan.User != "":
+		return errors.New("hosts cannot be combined with scanuser")
+	}
+	return nil
+}
+
+// validateImage checks the container image reference and the options that
+// cannot be combined with it.
+func (c *Config) validateImage() error {
+	if c.Scan.Image == "" {
+		return nil
+	}
+	if !imagePattern.MatchString(c.Scan.Image) {
+		return fmt.Errorf("invalid image %q: must be an image reference or id", c.Scan.Image)
+	}
+	switch {
+	case c.Scan.Root != "":
+		return errors.New("image cannot be combined with root")
+	case len(c.ScanHosts()) > 0:
+		return errors.New("image cannot be combined with hosts")
+	// oscap-podman needs root privileges to inspect the image
+	case c.Scan.User != "":
+		return errors.New("image cannot be combined with scanuser")
+	case c.Scan.Privileges == PrivilegesUnprivileged:
+		return errors.New("image cannot be combined with privileges unprivileged")
+	}
+	return nil
+}
+
+// oscapEnvPattern matches the names of the environment variables of oscap
+// and of its probes that can be set in the env option.
+var oscapEnvPattern = regexp.MustCompile(`^(OSCAP|SEXP)_[A-Z0-9_]+$`)
+
+// ScanEnv returns the NAME=value environment variables set in the env
+// option, or nil when oscap runs with the environment of the plugin only.
+func (c *Config) ScanEnv() []string {
+	var env []string
+	for _, variable := range strings.Split(c.Scan.Env, ",") {
+		if variable = strings.TrimSpace(variable); variable != "" {
+			env = append(env, variable)
+		}
+	}
+	return env
+}
+
+// validateEnv checks the environment variables of oscap are known oscap
+// variables and the options that cannot be combined with them.
+func (c *Config) validateEnv() error {
+	env := c.ScanEnv()
+	if len(env) == 0 {
+		return nil
+	}
+	for _, variable := range env {
+		name, _, ok := strings.Cut(variable, "=")
+		if !ok {
+			return fmt.Errorf("invalid environment variable %q: must be NAME=value", variable)
+		}
+		if !oscapEnvPattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable %q: must be an OSCAP_ or SEXP_ variable", name)
+		}
+	}
+	// oscap-ssh does not pass the environment to the remote hosts
+	if len(c.ScanHosts()) > 0 {
+		return errors.New("env cannot be combined with hosts")
+	}
+	return nil
+}
+
+// AdditionalDatastreams returns the datastreams set in the datastreams option,
+// or nil when only the datastream is evaluated.
+func (c *Config) AdditionalDatastreams() []string {
+	var datastreams []string
+	for _, datastream := range strings.Split(c.Content.Datastreams, ",") {
+		if datastream = strings.TrimSpace(datastream); datastream != "" {
+			datastreams = append(datastreams, datastream)
+		}
+	}
+	return datastreams
+}
+
+// ForDatastream returns a copy of the configuration for one of the additional
+// datastreams, with its own tailoring and result files named after the
+// configured files and the datastream.
+func (c *Config) ForDatastream(datastream string) *Config {
+	dsConfig := *c
+	name := datastreamName(datastream)
+	dsConfig.Files.Datastream = datastream
+	dsConfig.Files.Policy = suffixedFile(c.Files.Policy, name)
+	dsConfig.Files.Results = suffixedFile(c.Files.Results, name)
+	dsConfig.Files.ARF = suffixedFile(c.Files.ARF, name)
+	dsConfig.Content.Datastreams = ""
+	return &dsConfig
+}
+
+// ForRule returns a copy of the configuration evaluating only the given rule
+// of the policy on the local system, with its own tailoring and result files
+// named after the configured files and the rule.
+func (c *Config) ForRule(ruleID string) (*Config, error) {
+	if _, err := SanitizeInput(ruleID); err != nil {
+		return nil, fmt.Errorf("invalid rule id: %w", err)
+	}
+	ruleConfig := *c
+	ruleConfig.Tailoring.SelectedRules = ruleID
+	ruleConfig.Tailoring.SelectedControls = ""
+	ruleConfig.Files.Policy = suffixedFile(c.Files.Policy, ruleID)
+	ruleConfig.Files.Results = suffixedFile(c.Files.Results, ruleID)
+	ruleConfig.Files.ARF = suffixedFile(c.Files.ARF, ruleID)
+	ruleConfig.Content.Datastreams = ""
+	ruleConfig.Scan.Hosts = ""
+	// only the ARF of the policy is written to the pipe
+	ruleConfig.arfPipe = ""
+	// the tailoring of the rule is not recorded in the artifacts manifest
+	ruleConfig.Scan.SkipTailoringCheck = true
+	return &ruleConfig, nil
+}
+
+// datastreamName returns the file name of a datastream without extension.
+func datastreamName(datastream string) string {
+	return strings.TrimSuffix(filepath.Base(datastream), filepath.Ext(datastream))
+}
+
+// suffixedFile returns the path of a file named after path and the suffix.
+func suffixedFile(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), suffix, ext)
+}
+
+// validateDatastreams checks the additional datastreams and the options that
+// cannot be combined with them.
+func (c *Config) validateDatastreams() error {
+	datastreams := c.AdditionalDatastreams()
+	if len(datastreams) == 0 {
+		return nil
+	}
+	names := make(map[string]string)
+	cleanPaths := make([]string, 0, len(datastreams))
+	for _, datastream := range datastreams {
+		cleanPath, err := SanitizePath(datastream)
+		if err != nil {
+			return err
+		}
+		cleanPaths = append(cleanPaths, cleanPath)
+		if _, err := validatePath(cleanPath, false); err != nil {
+			return fmt.Errorf("%w: path %s: %w", pluginerr.ErrDatastreamInvalid, datastream, err)
+		}
+		if isXML, err := IsXMLFile(cleanPath); err != nil || !isXML {
+			return fmt.Errorf("%w: file %s is not valid XML: %w", pluginerr.ErrDatastreamInvalid, datastream, err)
+		}
+		// the files of the datastreams are named after their file name
+		name := datastreamName(cleanPath)
+		if _, err := SanitizeInput(name); err != nil {
+			return fmt.Errorf("invalid datastream file name: %w", err)
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("datastreams %s and %s have the same file name", other, datastream)
+		}
+		names[name] = datastream
+	}
+	c.Content.Datastreams = strings.Join(cleanPaths, ",")
+	switch {
+	case len(c.ScanHosts()) > 0:
+		return errors.New("datastreams cannot be combined with hosts")
+	case c.Results.HTMLReport != "":
+		return errors.New("datastreams cannot be combined with htmlreport")
+	case c.Results.SystemCharacteristics != "":
+		return errors.New("datastreams cannot be combined with systemcharacteristics")
+	case c.Results.EvidenceBundle != "":
+		return errors.New("datastreams cannot be combined with evidencebundle")
+	}
+	return nil
+}
+
+// PropertyName returns the name of a property emitted on subjects, prefixed
+// with the configured property prefix.
+func (c *Config) PropertyName(name string) string {
+	return c.Results.PropertyPrefix + name
 }
 
 // NewConfig creates a new, empty Config.
@@ -45,28 +819,55 @@ func NewConfig() *Config {
 	return &Config{}
 }
 
-// LoadSettings sets the values in the Config from a given config map and
-// performs validation.
+// LoadSettings sets the values in the Config from a given config map,
+// performs validation and sets up the content in the workspace. Values in the config map always take precedence over
+// values previously loaded in the Config.
 func (c *Config) LoadSettings(config map[string]string) error {
-	filesVal := reflect.ValueOf(&c.Files).Elem()
-	if err := setConfigStruct(filesVal, config); err != nil {
+	sections := []reflect.Value{
+		reflect.ValueOf(&c.Files).Elem(),
+		reflect.ValueOf(&c.Parameters).Elem(),
+		reflect.ValueOf(&c.Content).Elem(),
+		reflect.ValueOf(&c.Tailoring).Elem(),
+		reflect.ValueOf(&c.Layout).Elem(),
+		reflect.ValueOf(&c.Scan).Elem(),
+		reflect.ValueOf(&c.Results).Elem(),
+	}
+	for _, sectionVal := range sections {
+		if err := setConfigStruct(sectionVal, config); err != nil {
+			return err
+		}
+	}
+	if err := c.validate(); err != nil {
 		return err
 	}
-	paramVal := reflect.ValueOf(&c.Parameters).Elem()
-	if err := setConfigStruct(paramVal, config); err != nil {
+	// the content is only set up in the workspace once the settings are valid
+	if err := c.setupContent(); err != nil {
 		return err
 	}
-	return c.validate()
+	return c.validateDatastream()
 }
 
 func (c *Config) validate() error {
+	// the profile is resolved by complyctl, which gives precedence to the
+	// profile selected for the invocation over the plugin configuration files.
+	if c.Parameters.Profile == "" {
+		return errors.New("no profile set: it must be selected by complyctl or set in the plugin configuration")
+	}
+
+	if err := c.resolveARFPipe(); err != nil {
+		return err
+	}
+
 	// String values to sanitize
 	inputValues := []*string{
 		&c.Files.Policy,
 		&c.Files.Results,
-		&c.Files.ARF,
 		&c.Parameters.Profile,
 	}
+	// an ARF template is checked once its placeholders are resolved
+	if !isARFTemplate(c.Files.ARF) {
+		inputValues = append(inputValues, &c.Files.ARF)
+	}
 
 	for _, inputValue := range inputValues {
 		sanitized, err := SanitizeInput(*inputValue)
@@ -76,6 +877,272 @@ func (c *Config) validate() error {
 		*inputValue = sanitized
 	}
 
+	if isARFTemplate(c.Files.ARF) {
+		if err := c.validateARFTemplate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateLayout(); err != nil {
+		return err
+	}
+
+	for _, ruleID := range c.SelectedRuleIDs() {
+		if _, err := SanitizeInput(ruleID); err != nil {
+			return fmt.Errorf("invalid selected rule: %w", err)
+		}
+	}
+
+	for _, controlID := range c.SelectedControlIDs() {
+		if !controlIDPattern.MatchString(controlID) {
+			return fmt.Errorf("invalid selected control %q: must be a control id, optionally prefixed with a framework and ':'", controlID)
+		}
+	}
+
+	if c.Results.RulePrefix != "" {
+		if _, err := SanitizeInput(c.Results.RulePrefix); err != nil {
+			return fmt.Errorf("invalid rule prefix: %w", err)
+		}
+	}
+	if c.Results.TestResult != "" {
+		if _, err := SanitizeInput(c.Results.TestResult); err != nil {
+			return fmt.Errorf("invalid test result: %w", err)
+		}
+	}
+	if c.Content.BenchmarkID != "" {
+		if _, err := SanitizeInput(c.Content.BenchmarkID); err != nil {
+			return fmt.Errorf("invalid benchmark id: %w", err)
+		}
+	}
+	if c.Tailoring.BaseProfile != "" {
+		if _, err := SanitizeInput(c.Tailoring.BaseProfile); err != nil {
+			return fmt.Errorf("invalid base profile: %w", err)
+		}
+	}
+
+	if c.Results.PropertyPrefix != "" {
+		if _, err := SanitizeInput(c.Results.PropertyPrefix); err != nil {
+			return fmt.Errorf("invalid property prefix: %w", err)
+		}
+	}
+
+	if c.Results.AssessmentResults != "" {
+		if _, err := SanitizeInput(c.Results.AssessmentResults); err != nil {
+			return fmt.Errorf("invalid assessment results file: %w", err)
+		}
+	}
+	if c.Results.EvidenceBundle != "" {
+		if _, err := SanitizeInput(c.Results.EvidenceBundle); err != nil {
+			return fmt.Errorf("invalid evidence bundle file: %w", err)
+		}
+	}
+	if c.Results.HTMLReport != "" {
+		if _, err := SanitizeInput(c.Results.HTMLReport); err != nil {
+			return fmt.Errorf("invalid HTML report file: %w", err)
+		}
+	}
+	if c.Results.SystemCharacteristics != "" {
+		if _, err := SanitizeInput(c.Results.SystemCharacteristics); err != nil {
+			return fmt.Errorf("invalid system characteristics file: %w", err)
+		}
+	}
+	if c.Results.OSCALVersion != "" {
+		if err := versioning.IsValidOscalVersion(c.Results.OSCALVersion); err != nil {
+			return fmt.Errorf("invalid OSCAL version: %w", err)
+		}
+	}
+	if c.Results.FindingsFramework != "" {
+		if _, err := SanitizeInput(c.Results.FindingsFramework); err != nil {
+			return fmt.Errorf("invalid findings framework: %w", err)
+		}
+	}
+	if err := c.validateSubjectType(); err != nil {
+		return err
+	}
+
+	if c.Results.Findings && c.Results.AssessmentResults == "" {
+		return errors.New("findings requires assessmentresults")
+	}
+
+	switch c.Results.Parser {
+	case "", TreeParser, StreamParser:
+	default:
+		return fmt.Errorf("invalid ARF parser %q: must be %q or %q", c.Results.Parser, TreeParser, StreamParser)
+	}
+	// the stream parser does not read the OVAL results
+	if c.Results.OVALVariables && c.Results.Parser == StreamParser {
+		return errors.New("ovalvariables cannot be combined with the stream parser")
+	}
+
+	switch c.Scan.PlatformCheck {
+	case "", PlatformCheckWarn, PlatformCheckFail, PlatformCheckSkip:
+	default:
+		return fmt.Errorf("invalid platform check %q: must be %q, %q or %q", c.Scan.PlatformCheck, PlatformCheckWarn, PlatformCheckFail, PlatformCheckSkip)
+	}
+
+	if err := c.validateNamespaces(); err != nil {
+		return err
+	}
+
+	switch c.Tailoring.Overwrite {
+	case "", OverwriteAlways, OverwriteFail, OverwriteKeep:
+	default:
+		return fmt.Errorf("invalid overwrite policy %q: must be %q, %q or %q", c.Tailoring.Overwrite, OverwriteAlways, OverwriteFail, OverwriteKeep)
+	}
+	switch c.Tailoring.Document {
+	case "", TailoringDocumentJSON, TailoringDocumentYAML:
+	default:
+		return fmt.Errorf("invalid tailoring document format %q: must be %q or %q", c.Tailoring.Document, TailoringDocumentJSON, TailoringDocumentYAML)
+	}
+
+	// separate XCCDF and OVAL files are staged in the workspace by
+	// setupContent, and the XCCDF file is then validated as the datastream.
+	if c.Content.XCCDF != "" || c.Content.OVAL != "" {
+		if err := c.validateXCCDFContent(); err != nil {
+			return err
+		}
+	}
+
+	// a remote datastream is downloaded to the workspace by setupContent,
+	// and then validated as a local file.
+	if isRemoteContent(c.Files.Datastream) {
+		if _, _, err := validateRemoteContent(c.Files.Datastream, c.Content.DatastreamChecksum); err != nil {
+			return fmt.Errorf("invalid remote datastream: %w", err)
+		}
+	}
+
+	if c.Scan.Root != "" {
+		if err := c.resolveRoot(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateHosts(); err != nil {
+		return err
+	}
+
+	if err := c.validateImage(); err != nil {
+		return err
+	}
+	if err := c.validateARFPipe(); err != nil {
+		return err
+	}
+
+	if err := c.validatePrivileges(); err != nil {
+		return err
+	}
+
+	if err := c.validateEnv(); err != nil {
+		return err
+	}
+	if err := c.validateExtraArgs(); err != nil {
+		return err
+	}
+	if err := c.validateEvidenceURL(); err != nil {
+		return err
+	}
+
+	if err := c.validateDatastreams(); err != nil {
+		return err
+	}
+
+	if err := c.validateARFRetries(); err != nil {
+		return err
+	}
+	if err := c.validateScanRetries(); err != nil {
+		return err
+	}
+
+	if err := c.validateARFBufferSize(); err != nil {
+		return err
+	}
+
+	if c.Results.Waivers != "" {
+		cleanPath, err := SanitizePath(c.Results.Waivers)
+		if err != nil {
+			return err
+		}
+		c.Results.Waivers = cleanPath
+		if _, err := c.Waivers(); err != nil {
+			return err
+		}
+	}
+
+	if c.Results.ARFXSLT != "" {
+		cleanPath, err := SanitizePath(c.Results.ARFXSLT)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(cleanPath); err != nil {
+			return fmt.Errorf("invalid ARF stylesheet: %w", err)
+		}
+		c.Results.ARFXSLT = cleanPath
+	}
+
+	if c.Results.ResourceGroups != "" {
+		cleanPath, err := SanitizePath(c.Results.ResourceGroups)
+		if err != nil {
+			return err
+		}
+		c.Results.ResourceGroups = cleanPath
+		if _, err := c.ResourceGroups(); err != nil {
+			return err
+		}
+	}
+
+	if c.Results.TargetNames != "" {
+		cleanPath, err := SanitizePath(c.Results.TargetNames)
+		if err != nil {
+			return err
+		}
+		c.Results.TargetNames = cleanPath
+		if _, err := c.TargetNames(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseResultMapping(c.Results.ResultMapping); err != nil {
+		return err
+	}
+	if _, err := parseCheckMapping(c.Results.CheckMapping); err != nil {
+		return err
+	}
+
+	if c.Results.MaxFailures < 0 {
+		return fmt.Errorf("invalid max failures %d: must not be negative", c.Results.MaxFailures)
+	}
+	if c.Results.MinObservations < 0 {
+		return fmt.Errorf("invalid min observations %d: must not be negative", c.Results.MinObservations)
+	}
+	switch c.Results.EmptyResults {
+	case "", EmptyResultsWarn, EmptyResultsFail, EmptyResultsSkip:
+	default:
+		return fmt.Errorf("invalid empty results handling %q: must be %q, %q or %q", c.Results.EmptyResults, EmptyResultsWarn, EmptyResultsFail, EmptyResultsSkip)
+	}
+	switch c.Results.TimestampSource {
+	case "", TimestampProcessing, TimestampScan:
+	default:
+		return fmt.Errorf("invalid timestamp source %q: must be %q or %q", c.Results.TimestampSource, TimestampProcessing, TimestampScan)
+	}
+	switch c.Results.TimeZone {
+	case "", TimeZoneUTC, TimeZoneLocal:
+	default:
+		return fmt.Errorf("invalid time zone %q: must be %q or %q", c.Results.TimeZone, TimeZoneUTC, TimeZoneLocal)
+	}
+
+	if c.Results.FailSeverity != "" && !slices.Contains(severityLevels, c.Results.FailSeverity) {
+		return fmt.Errorf("invalid fail severity %q: must be one of %v", c.Results.FailSeverity, severityLevels)
+	}
+
+	if err := defineFilesPaths(c); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateDatastream checks the datastream of the Config, once its content is
+// set up. The datastream matching the system is used when none is set.
+func (c *Config) validateDatastream() error {
 	cleanDsPath, err := SanitizePath(c.Files.Datastream)
 	if err != nil {
 		return err
@@ -86,24 +1153,39 @@ func (c *Config) validate() error {
 	if cleanDsPath == "." {
 		matchingDsFile, err := findMatchingDatastream()
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %w", pluginerr.ErrDatastreamInvalid, err)
 		}
 		c.Files.Datastream = matchingDsFile
 	}
 
 	_, err = validatePath(c.Files.Datastream, false)
 	if err != nil {
-		return fmt.Errorf("invalid datastream path: %s: %w", c.Files.Datastream, err)
+		return fmt.Errorf("%w: path %s: %w", pluginerr.ErrDatastreamInvalid, c.Files.Datastream, err)
 	}
 
 	isXML, err := IsXMLFile(c.Files.Datastream)
 	if err != nil || !isXML {
-		return fmt.Errorf("invalid datastream file: %s: %w", c.Files.Datastream, err)
+		return fmt.Errorf("%w: file %s is not valid XML: %w", pluginerr.ErrDatastreamInvalid, c.Files.Datastream, err)
 	}
 
-	if err := defineFilesPaths(c); err != nil {
+	return c.validateDatastreamSignature()
+}
+
+// resolveRoot validates the alternate root directory and makes it absolute,
+// since oscap resolves it independently of the plugin working directory.
+func (c *Config) resolveRoot() error {
+	cleanRoot, err := SanitizePath(c.Scan.Root)
+	if err != nil {
 		return err
 	}
+	if _, err := validatePath(cleanRoot, true); err != nil {
+		return fmt.Errorf("invalid root %s: %w", c.Scan.Root, err)
+	}
+	absRoot, err := filepath.Abs(cleanRoot)
+	if err != nil {
+		return fmt.Errorf("invalid root %s: %w", c.Scan.Root, err)
+	}
+	c.Scan.Root = absRoot
 	return nil
 }
 
@@ -202,9 +1284,10 @@ func ensureWorkspace(cfg *Config) (map[string]string, error) {
 	directories := map[string]string{
 		"workspace":      workspace,
 		"pluginDir":      filepath.Join(workspace, PluginDir),
-		"policyDir":      filepath.Join(workspace, PluginDir, PolicyDir),
-		"resultsDir":     filepath.Join(workspace, PluginDir, ResultsDir),
-		"remediationDir": filepath.Join(workspace, PluginDir, RemediationDir),
+		"policyDir":      cfg.LayoutDir(workspace, PolicyDir),
+		"resultsDir":     cfg.LayoutDir(workspace, ResultsDir),
+		"remediationDir": cfg.LayoutDir(workspace, RemediationDir),
+		"contentDir":     cfg.LayoutDir(workspace, ContentDir),
 	}
 
 	for key, dir := range directories {
@@ -225,26 +1308,75 @@ func defineFilesPaths(cfg *Config) error {
 	cfg.Files.Policy = filepath.Join(directories["policyDir"], cfg.Files.Policy)
 	cfg.Files.Results = filepath.Join(directories["resultsDir"], cfg.Files.Results)
 	cfg.Files.ARF = filepath.Join(directories["resultsDir"], cfg.Files.ARF)
+	if cfg.Results.AssessmentResults != "" {
+		cfg.Results.AssessmentResults = filepath.Join(directories["resultsDir"], cfg.Results.AssessmentResults)
+	}
+	if cfg.Results.EvidenceBundle != "" {
+		cfg.Results.EvidenceBundle = filepath.Join(directories["resultsDir"], cfg.Results.EvidenceBundle)
+	}
+	if cfg.Results.HTMLReport != "" {
+		cfg.Results.HTMLReport = filepath.Join(directories["resultsDir"], cfg.Results.HTMLReport)
+	}
+	if cfg.Results.SystemCharacteristics != "" {
+		cfg.Results.SystemCharacteristics = filepath.Join(directories["resultsDir"], cfg.Results.SystemCharacteristics)
+	}
 
 	return nil
 }
 
 // setConfigStruct populates struct fields with matching tags to values
-// in a given config map.
+// in a given config map. Fields tagged as "optional" keep their zero value
+// when the option is absent.
 func setConfigStruct(val reflect.Value, config map[string]string) error {
 	t := val.Type()
 	for i := 0; i < val.NumField(); i++ {
 		fieldType := t.Field(i)
-		key := fieldType.Tag.Get("config")
+		key, tagOpts, _ := strings.Cut(fieldType.Tag.Get("config"), ",")
 		value, ok := config[key]
-		// if datastream is not set in manifest file, plugin will try to determine
-		// and validate the datastream path later based on system information.
-		if !ok && key != "datastream" {
-			return fmt.Errorf("missing configuration value for option %q (field: %s)", key, fieldType.Name)
+		if !ok {
+			if tagOpts == "optional" {
+				continue
+			}
+			// if datastream is not set in manifest file, plugin will try to determine
+			// and validate the datastream path later based on system information.
+			if key != "datastream" {
+				return fmt.Errorf("missing configuration value for option %q (field: %s)", key, fieldType.Name)
+			}
+		}
+
+		if err := setConfigValue(val.Field(i), value); err != nil {
+			return fmt.Errorf("invalid value for option %q (field: %s): %w", key, fieldType.Name, err)
 		}
+	}
+	return nil
+}
 
-		fieldVal := val.Field(i)
+// setConfigValue converts a value from the config map to the kind of the
+// given field. Empty values leave non-string fields unset.
+func setConfigValue(fieldVal reflect.Value, value string) error {
+	switch fieldVal.Kind() {
+	case reflect.String:
 		fieldVal.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fieldVal.SetBool(parsed)
+	case reflect.Int:
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		fieldVal.SetInt(int64(parsed))
+	default:
+		return fmt.Errorf("unsupported field kind %s", fieldVal.Kind())
 	}
 	return nil
 }
@@ -295,6 +1427,25 @@ func getDistroIdsAndVersions() ([]string, []string, error) {
 	return nil, nil, fmt.Errorf("could not determine distribution and version based on %s", SystemInfoFile)
 }
 
+// SystemCPE returns the CPE name of the system mounted at root, or of the live
+// system when root is empty, from the CPE_NAME field of its SystemInfoFile.
+// It returns an empty string when the field is not set.
+func SystemCPE(root string) (string, error) {
+	file, err := os.Open(filepath.Join(root, SystemInfoFile))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "CPE_NAME="); ok {
+			return strings.Trim(value, `"'`), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
 func findMatchingDatastream() (string, error) {
 	distroIds, distroVersions, err := getDistroIdsAndVersions()
 	if err != nil {
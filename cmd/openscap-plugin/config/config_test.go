@@ -3,12 +3,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
 // TestSanitizeInput tests the SanitizeInput function with various valid and invalid inputs.
@@ -355,6 +360,422 @@ func TestConfig_LoadSettings(t *testing.T) {
 			},
 			expectError: "missing configuration value for option \"profile\" (field: Profile)",
 		},
+		{
+			name: "Invalid/ARFParser",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"arfparser":  "sax",
+			},
+			expectError: "invalid ARF parser \"sax\": must be \"tree\" or \"stream\"",
+		},
+		{
+			name: "Invalid/PlatformCheck",
+			inputSettings: map[string]string{
+				"workspace":     tempDir,
+				"datastream":    tempDataStream,
+				"results":       "results.xml",
+				"arf":           "arf.xml",
+				"policy":        "policy.yaml",
+				"profile":       "test",
+				"platformcheck": "abort",
+			},
+			expectError: "invalid platform check \"abort\": must be \"warn\", \"fail\" or \"skip\"",
+		},
+		{
+			name: "Invalid/BaseProfile",
+			inputSettings: map[string]string{
+				"workspace":   tempDir,
+				"datastream":  tempDataStream,
+				"results":     "results.xml",
+				"arf":         "arf.xml",
+				"policy":      "policy.yaml",
+				"profile":     "test",
+				"baseprofile": "../cis",
+			},
+			expectError: "invalid base profile: input contains unexpected characters: ../cis",
+		},
+		{
+			name: "Invalid/SelectedControls",
+			inputSettings: map[string]string{
+				"workspace":        tempDir,
+				"datastream":       tempDataStream,
+				"results":          "results.xml",
+				"arf":              "arf.xml",
+				"policy":           "policy.yaml",
+				"profile":          "test",
+				"selectedcontrols": "ac-6,cm-6; rm -rf",
+			},
+			expectError: "invalid selected control \"cm-6; rm -rf\": must be a control id, optionally prefixed with a framework and ':'",
+		},
+		{
+			name: "Invalid/ARFXSLT",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"arfxslt":    filepath.Join(tempDir, "absent.xsl"),
+			},
+			expectError: "invalid ARF stylesheet: stat " + filepath.Join(tempDir, "absent.xsl") + ": no such file or directory",
+		},
+		{
+			name: "Invalid/Findings",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"findings":   "true",
+			},
+			expectError: "findings requires assessmentresults",
+		},
+		{
+			name: "Invalid/ARFPipe",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        tempDataStream,
+				"policy":     "policy.yaml",
+				"profile":    "test",
+			},
+			expectError: "invalid arf " + tempDataStream + ": an absolute path must be a named pipe",
+		},
+		{
+			name: "Invalid/ARFPipeStdout",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "-",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+			},
+			expectError: "invalid arf \"-\": the standard output of the plugin is not forwarded by complyctl, use a named pipe",
+		},
+		{
+			name: "Invalid/SubjectType",
+			inputSettings: map[string]string{
+				"workspace":   tempDir,
+				"datastream":  tempDataStream,
+				"results":     "results.xml",
+				"arf":         "arf.xml",
+				"policy":      "policy.yaml",
+				"profile":     "test",
+				"subjecttype": "inventory item",
+			},
+			expectError: "invalid subject type \"inventory item\": must be \"inventory-item\" or \"resource\"",
+		},
+		{
+			name: "Invalid/Overwrite",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"overwrite":  "never",
+			},
+			expectError: "invalid overwrite policy \"never\": must be \"overwrite\", \"fail\" or \"keep\"",
+		},
+		{
+			name: "Invalid/TailoringDocument",
+			inputSettings: map[string]string{
+				"workspace":         tempDir,
+				"datastream":        tempDataStream,
+				"results":           "results.xml",
+				"arf":               "arf.xml",
+				"policy":            "policy.yaml",
+				"profile":           "test",
+				"tailoringdocument": "toml",
+			},
+			expectError: "invalid tailoring document format \"toml\": must be \"json\" or \"yaml\"",
+		},
+		{
+			name: "Invalid/EmptyProfile",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "",
+			},
+			expectError: "no profile set: it must be selected by complyctl or set in the plugin configuration",
+		},
+		{
+			name: "Invalid/FailSeverity",
+			inputSettings: map[string]string{
+				"workspace":    tempDir,
+				"datastream":   tempDataStream,
+				"results":      "results.xml",
+				"arf":          "arf.xml",
+				"policy":       "policy.yaml",
+				"profile":      "test",
+				"failseverity": "critical",
+			},
+			expectError: "invalid fail severity \"critical\": must be one of [info low medium high]",
+		},
+		{
+			name: "Invalid/ResultMapping",
+			inputSettings: map[string]string{
+				"workspace":     tempDir,
+				"datastream":    tempDataStream,
+				"results":       "results.xml",
+				"arf":           "arf.xml",
+				"policy":        "policy.yaml",
+				"profile":       "test",
+				"resultmapping": "unknown=failed",
+			},
+			expectError: "invalid result mapping \"unknown=failed\": unknown result \"failed\", must be one of [pass fail error warning]",
+		},
+		{
+			name: "Invalid/ARFTemplate",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf-${profile}/../${hostname}.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+			},
+			expectError: "invalid arf template \"arf-${profile}/../${hostname}.xml\": input contains unexpected characters: arf-test/../localhost.xml",
+		},
+		{
+			name: "Invalid/Host",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"hosts":      "root@rhel10:22,rhel9;reboot",
+			},
+			expectError: "invalid host \"rhel9;reboot\": must be [user@]host[:port]",
+		},
+		{
+			name: "Invalid/HostsWithEvidenceBundle",
+			inputSettings: map[string]string{
+				"workspace":      tempDir,
+				"datastream":     tempDataStream,
+				"results":        "results.xml",
+				"arf":            "arf.xml",
+				"policy":         "policy.yaml",
+				"profile":        "test",
+				"hosts":          "rhel10,rhel9",
+				"evidencebundle": "evidence.tar.gz",
+			},
+			expectError: "hosts cannot be combined with evidencebundle",
+		},
+		{
+			name: "Invalid/Image",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"image":      "ubi10 --rm",
+			},
+			expectError: "invalid image \"ubi10 --rm\": must be an image reference or id",
+		},
+		{
+			name: "Invalid/ImageWithHosts",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"image":      "registry.access.redhat.com/ubi10/ubi:latest",
+				"hosts":      "rhel10",
+			},
+			expectError: "image cannot be combined with hosts",
+		},
+		{
+			name: "Invalid/Privileges",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"privileges": "admin",
+			},
+			expectError: "invalid privileges \"admin\": must be \"root\" or \"unprivileged\"",
+		},
+		{
+			name: "Invalid/ScanUserRoot",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"scanuser":   "root",
+			},
+			expectError: "invalid scan user \"root\": must not be root",
+		},
+		{
+			name: "Invalid/ScanUserWithHosts",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"scanuser":   "nobody",
+				"hosts":      "rhel10",
+			},
+			expectError: "hosts cannot be combined with scanuser",
+		},
+		{
+			name: "Invalid/Env",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"env":        "OSCAP_PROBE_MEMORY_USAGE_RATIO=0.5,LD_PRELOAD=/tmp/probe.so",
+			},
+			expectError: "invalid environment variable \"LD_PRELOAD\": must be an OSCAP_ or SEXP_ variable",
+		},
+		{
+			name: "Invalid/EnvWithHosts",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"env":        "OSCAP_PROBE_MEMORY_USAGE_RATIO=0.5",
+				"hosts":      "rhel10",
+			},
+			expectError: "env cannot be combined with hosts",
+		},
+		{
+			name: "Invalid/Concurrency",
+			inputSettings: map[string]string{
+				"workspace":   tempDir,
+				"datastream":  tempDataStream,
+				"results":     "results.xml",
+				"arf":         "arf.xml",
+				"policy":      "policy.yaml",
+				"profile":     "test",
+				"concurrency": "-1",
+			},
+			expectError: "invalid concurrency -1: must not be negative",
+		},
+		{
+			name: "Invalid/MaxFailures",
+			inputSettings: map[string]string{
+				"workspace":   tempDir,
+				"datastream":  tempDataStream,
+				"results":     "results.xml",
+				"arf":         "arf.xml",
+				"policy":      "policy.yaml",
+				"profile":     "test",
+				"maxfailures": "-1",
+			},
+			expectError: "invalid max failures -1: must not be negative",
+		},
+		{
+			name: "Invalid/MinObservations",
+			inputSettings: map[string]string{
+				"workspace":       tempDir,
+				"datastream":      tempDataStream,
+				"results":         "results.xml",
+				"arf":             "arf.xml",
+				"policy":          "policy.yaml",
+				"profile":         "test",
+				"minobservations": "-1",
+			},
+			expectError: "invalid min observations -1: must not be negative",
+		},
+		{
+			name: "Invalid/EmptyResults",
+			inputSettings: map[string]string{
+				"workspace":    tempDir,
+				"datastream":   tempDataStream,
+				"results":      "results.xml",
+				"arf":          "arf.xml",
+				"policy":       "policy.yaml",
+				"profile":      "test",
+				"emptyresults": "error",
+			},
+			expectError: "invalid empty results handling \"error\": must be \"warn\", \"fail\" or \"skip\"",
+		},
+		{
+			name: "Invalid/TimestampSource",
+			inputSettings: map[string]string{
+				"workspace":       tempDir,
+				"datastream":      tempDataStream,
+				"results":         "results.xml",
+				"arf":             "arf.xml",
+				"policy":          "policy.yaml",
+				"profile":         "test",
+				"timestampsource": "now",
+			},
+			expectError: "invalid timestamp source \"now\": must be \"processing\" or \"scan\"",
+		},
+		{
+			name: "Invalid/TimeZone",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"timezone":   "Europe/Paris",
+			},
+			expectError: "invalid time zone \"Europe/Paris\": must be \"utc\" or \"local\"",
+		},
+		{
+			name: "Invalid/ARFBufferSize",
+			inputSettings: map[string]string{
+				"workspace":     tempDir,
+				"datastream":    tempDataStream,
+				"results":       "results.xml",
+				"arf":           "arf.xml",
+				"policy":        "policy.yaml",
+				"profile":       "test",
+				"arfbuffersize": "64",
+			},
+			expectError: "invalid ARF buffer size 64: must be between 512 and 67108864",
+		},
+		{
+			name: "Invalid/RootIsFile",
+			inputSettings: map[string]string{
+				"workspace":  tempDir,
+				"datastream": tempDataStream,
+				"results":    "results.xml",
+				"arf":        "arf.xml",
+				"policy":     "policy.yaml",
+				"profile":    "test",
+				"root":       tempDataStream,
+			},
+			expectError: fmt.Sprintf("invalid root %s: expected a directory, but found a file at path: %s", tempDataStream, tempDataStream),
+		},
 	}
 
 	for _, tt := range tests {
@@ -371,3 +792,281 @@ func TestConfig_LoadSettings(t *testing.T) {
 		})
 	}
 }
+
+// TestSetConfigValue tests the conversion of config map values to the supported field kinds.
+func TestSetConfigValue(t *testing.T) {
+	var fields struct {
+		Text   string
+		Flag   bool
+		Number int
+		Ratio  float64
+	}
+	val := reflect.ValueOf(&fields).Elem()
+
+	require.NoError(t, setConfigValue(val.Field(0), "value"))
+	require.NoError(t, setConfigValue(val.Field(1), "true"))
+	require.NoError(t, setConfigValue(val.Field(2), "5"))
+	require.Equal(t, "value", fields.Text)
+	require.True(t, fields.Flag)
+	require.Equal(t, 5, fields.Number)
+
+	// Empty values leave non-string fields unset
+	require.NoError(t, setConfigValue(val.Field(1), ""))
+	require.NoError(t, setConfigValue(val.Field(2), ""))
+	require.True(t, fields.Flag)
+	require.Equal(t, 5, fields.Number)
+
+	require.Error(t, setConfigValue(val.Field(1), "yes please"))
+	require.Error(t, setConfigValue(val.Field(2), "five"))
+	require.EqualError(t, setConfigValue(val.Field(3), "0.5"), "unsupported field kind float64")
+}
+
+func TestMeetsSeverity(t *testing.T) {
+	tests := []struct {
+		severity  string
+		threshold string
+		want      bool
+	}{
+		{severity: "low", threshold: "", want: true},
+		{severity: "low", threshold: "medium", want: false},
+		{severity: "medium", threshold: "medium", want: true},
+		{severity: "high", threshold: "medium", want: true},
+		{severity: "unknown", threshold: "high", want: true},
+		{severity: "", threshold: "high", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.severity+"/"+tt.threshold, func(t *testing.T) {
+			require.Equal(t, tt.want, MeetsSeverity(tt.severity, tt.threshold))
+		})
+	}
+}
+
+func TestConfig_LoadSettings_DatastreamInvalid(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := map[string]string{
+		"workspace":  tempDir,
+		"datastream": filepath.Join(tempDir, "absent.xml"),
+		"results":    "results.xml",
+		"arf":        "arf.xml",
+		"policy":     "policy.yaml",
+		"profile":    "test",
+	}
+	err := NewConfig().LoadSettings(settings)
+	require.ErrorIs(t, err, pluginerr.ErrDatastreamInvalid)
+}
+
+func TestSelectedRuleIDs(t *testing.T) {
+	cfg := NewConfig()
+	require.Nil(t, cfg.SelectedRuleIDs())
+
+	cfg.Tailoring.SelectedRules = "package_aide_installed, aide_build_database,"
+	require.Equal(t, []string{"package_aide_installed", "aide_build_database"}, cfg.SelectedRuleIDs())
+}
+
+func TestScanHosts(t *testing.T) {
+	cfg := NewConfig()
+	require.Nil(t, cfg.ScanHosts())
+
+	cfg.Scan.Hosts = "root@rhel10:2222, 192.168.122.10,[::1]:22,"
+	require.Equal(t, []string{"root@rhel10:2222", "192.168.122.10", "[::1]:22"}, cfg.ScanHosts())
+	for _, host := range cfg.ScanHosts() {
+		require.Regexp(t, hostPattern, host)
+	}
+	// hosts and users starting like ssh options are rejected
+	for _, host := range []string{"-oProxyCommand=reboot", "-rhel10", "-oProxyCommand=reboot@rhel10", ".rhel10"} {
+		require.NotRegexp(t, hostPattern, host)
+	}
+}
+
+func TestScanEnv(t *testing.T) {
+	cfg := NewConfig()
+	require.Nil(t, cfg.ScanEnv())
+	require.NoError(t, cfg.validateEnv())
+
+	cfg.Scan.Env = "OSCAP_PROBE_IGNORE_PATHS=/proc:/sys, SEXP_VALIDATE_DISABLE=1,"
+	require.Equal(t, []string{"OSCAP_PROBE_IGNORE_PATHS=/proc:/sys", "SEXP_VALIDATE_DISABLE=1"}, cfg.ScanEnv())
+	require.NoError(t, cfg.validateEnv())
+
+	cfg.Scan.Env = "OSCAP_FULL_VALIDATION"
+	require.EqualError(t, cfg.validateEnv(), "invalid environment variable \"OSCAP_FULL_VALIDATION\": must be NAME=value")
+}
+
+func TestForDatastream(t *testing.T) {
+	cfg := NewConfig()
+	require.Nil(t, cfg.AdditionalDatastreams())
+
+	cfg.Files.Datastream = "/content/ssg-rhel10-ds.xml"
+	cfg.Files.Policy = "/workspace/openscap/policy/tailoring_policy.xml"
+	cfg.Files.Results = "/workspace/openscap/results/results.xml"
+	cfg.Files.ARF = "/workspace/openscap/results/arf.xml"
+	cfg.Content.Datastreams = "/content/app-ds.xml, "
+	require.Equal(t, []string{"/content/app-ds.xml"}, cfg.AdditionalDatastreams())
+
+	dsConfig := cfg.ForDatastream("/content/app-ds.xml")
+	require.Equal(t, "/content/app-ds.xml", dsConfig.Files.Datastream)
+	require.Equal(t, "/workspace/openscap/policy/tailoring_policy-app-ds.xml", dsConfig.Files.Policy)
+	require.Equal(t, "/workspace/openscap/results/results-app-ds.xml", dsConfig.Files.Results)
+	require.Equal(t, "/workspace/openscap/results/arf-app-ds.xml", dsConfig.Files.ARF)
+	require.Empty(t, dsConfig.AdditionalDatastreams())
+	// the configuration itself is left untouched
+	require.Equal(t, "/content/ssg-rhel10-ds.xml", cfg.Files.Datastream)
+}
+
+func TestForRule(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Files.Policy = "/workspace/openscap/policy/tailoring_policy.xml"
+	cfg.Files.Results = "/workspace/openscap/results/results.xml"
+	cfg.Files.ARF = "/workspace/openscap/results/arf.xml"
+	cfg.Tailoring.SelectedRules = "package_aide_installed,aide_build_database"
+	cfg.Scan.Hosts = "rhel10"
+
+	ruleConfig, err := cfg.ForRule("aide_build_database")
+	require.NoError(t, err)
+	require.Equal(t, []string{"aide_build_database"}, ruleConfig.SelectedRuleIDs())
+	require.Equal(t, "/workspace/openscap/policy/tailoring_policy-aide_build_database.xml", ruleConfig.Files.Policy)
+	require.Equal(t, "/workspace/openscap/results/results-aide_build_database.xml", ruleConfig.Files.Results)
+	require.Equal(t, "/workspace/openscap/results/arf-aide_build_database.xml", ruleConfig.Files.ARF)
+	require.Empty(t, ruleConfig.ScanHosts())
+
+	_, err = cfg.ForRule("../aide")
+	require.ErrorContains(t, err, "invalid rule id")
+}
+
+func TestValidateDatastreams(t *testing.T) {
+	tempDir := t.TempDir()
+	appDs := filepath.Join(tempDir, "app-ds.xml")
+	require.NoError(t, os.WriteFile(appDs, []byte("<ds/>"), 0600))
+	otherDir := filepath.Join(tempDir, "other")
+	require.NoError(t, os.Mkdir(otherDir, 0750))
+	otherAppDs := filepath.Join(otherDir, "app-ds.xml")
+	require.NoError(t, os.WriteFile(otherAppDs, []byte("<ds/>"), 0600))
+	invalidDs := filepath.Join(tempDir, "invalid-ds.xml")
+	require.NoError(t, os.WriteFile(invalidDs, []byte("<ds>"), 0600))
+
+	tests := []struct {
+		name        string
+		datastreams string
+		hosts       string
+		expectError string
+	}{
+		{name: "Valid", datastreams: appDs},
+		{name: "Missing", datastreams: filepath.Join(tempDir, "missing.xml"), expectError: "invalid datastream"},
+		{name: "NotXML", datastreams: invalidDs, expectError: "is not valid XML"},
+		{name: "SameName", datastreams: appDs + "," + otherAppDs, expectError: "have the same file name"},
+		{name: "Hosts", datastreams: appDs, hosts: "rhel10", expectError: "datastreams cannot be combined with hosts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.Content.Datastreams = tt.datastreams
+			cfg.Scan.Hosts = tt.hosts
+			err := cfg.validateDatastreams()
+			if tt.expectError != "" {
+				require.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSystemCPE(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc"), 0750))
+	osRelease := filepath.Join(root, SystemInfoFile)
+
+	require.NoError(t, os.WriteFile(osRelease, []byte("ID=\"rhel\"\nCPE_NAME=\"cpe:/o:redhat:enterprise_linux:10::baseos\"\n"), 0600))
+	systemCPE, err := SystemCPE(root)
+	require.NoError(t, err)
+	require.Equal(t, "cpe:/o:redhat:enterprise_linux:10::baseos", systemCPE)
+
+	require.NoError(t, os.WriteFile(osRelease, []byte("ID=ubuntu\n"), 0600))
+	systemCPE, err = SystemCPE(root)
+	require.NoError(t, err)
+	require.Empty(t, systemCPE)
+
+	_, err = SystemCPE(t.TempDir())
+	require.Error(t, err)
+}
+
+func TestValidateARFRetries(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateARFRetries())
+	require.Equal(t, time.Second, cfg.ARFRetryDelay())
+
+	cfg.Results.ARFRetries = 3
+	cfg.Results.ARFRetryDelay = "250ms"
+	require.NoError(t, cfg.validateARFRetries())
+	require.Equal(t, 250*time.Millisecond, cfg.ARFRetryDelay())
+
+	cfg.Results.ARFRetries = 11
+	require.EqualError(t, cfg.validateARFRetries(), "invalid ARF retries 11: must be between 0 and 10")
+	cfg.Results.ARFRetries = 3
+	cfg.Results.ARFRetryDelay = "1m"
+	require.EqualError(t, cfg.validateARFRetries(), "invalid ARF retry delay 1m0s: must be positive and at most 10s")
+	cfg.Results.ARFRetryDelay = "soon"
+	require.ErrorContains(t, cfg.validateARFRetries(), "invalid ARF retry delay")
+}
+
+func TestValidateARFBufferSize(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateARFBufferSize())
+	require.Equal(t, 4096, cfg.ARFBufferSize())
+
+	cfg.Results.ARFBufferSize = 1 << 20
+	require.NoError(t, cfg.validateARFBufferSize())
+	require.Equal(t, 1<<20, cfg.ARFBufferSize())
+
+	cfg.Results.ARFBufferSize = -1
+	require.EqualError(t, cfg.validateARFBufferSize(), "invalid ARF buffer size -1: must be between 512 and 67108864")
+	cfg.Results.ARFBufferSize = 128 << 20
+	require.EqualError(t, cfg.validateARFBufferSize(), "invalid ARF buffer size 134217728: must be between 512 and 67108864")
+
+	cfg.Results.ARFBufferSize = 0
+	require.Equal(t, 1<<30, cfg.ARFMaxSize())
+	cfg.Results.ARFMaxSize = -1
+	require.EqualError(t, cfg.validateARFBufferSize(), "invalid ARF max size -1: must not be negative")
+}
+
+func TestResultMapping(t *testing.T) {
+	cfg := NewConfig()
+	require.Nil(t, cfg.ResultMapping())
+
+	cfg.Results.ResultMapping = "unknown=fail, notapplicable = pass,"
+	require.Equal(t, map[string]string{"unknown": "fail", "notapplicable": "pass"}, cfg.ResultMapping())
+
+	_, err := parseResultMapping("unknown")
+	require.EqualError(t, err, "invalid result mapping \"unknown\": must be <xccdf result>=<result>")
+	_, err = parseResultMapping("skipped=pass")
+	require.ErrorContains(t, err, "unknown xccdf result \"skipped\"")
+}
+
+func TestCheckMapping(t *testing.T) {
+	cfg := NewConfig()
+	require.Nil(t, cfg.CheckMapping())
+
+	cfg.Results.CheckMapping = "package_aide_installed = aide_installed, sshd_disable_root_login=ssh_root_login,"
+	require.Equal(t, map[string]string{
+		"package_aide_installed":  "aide_installed",
+		"sshd_disable_root_login": "ssh_root_login",
+	}, cfg.CheckMapping())
+
+	_, err := parseCheckMapping("package_aide_installed")
+	require.EqualError(t, err, "invalid check mapping \"package_aide_installed\": must be <oval check>=<check id>")
+	_, err = parseCheckMapping("package_aide_installed=aide_installed,package_aide_installed=aide")
+	require.EqualError(t, err, "invalid check mapping \"package_aide_installed=aide\": check package_aide_installed is mapped several times")
+}
+
+func TestSubjectType(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateSubjectType())
+	require.Equal(t, "inventory-item", cfg.SubjectType())
+
+	cfg.Results.SubjectType = "resource"
+	require.NoError(t, cfg.validateSubjectType())
+	require.Equal(t, "resource", cfg.SubjectType())
+
+	// complyctl rejects the results of other OSCAL subject types
+	cfg.Results.SubjectType = "component"
+	require.Error(t, cfg.validateSubjectType())
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	xccdfURI        string = "http://checklists.nist.gov/xccdf/1.2"
+	ovalCheckSystem string = "http://oval.mitre.org/XMLSchema/oval-definitions-5"
+)
+
+// validateXCCDFContent validates the separate XCCDF and OVAL files of the
+// Config, which are used in place of a datastream once staged by setupContent.
+func (c *Config) validateXCCDFContent() error {
+	if c.Content.XCCDF == "" || c.Content.OVAL == "" {
+		return errors.New("the xccdf and oval options must be set together")
+	}
+	if c.Files.Datastream != "" {
+		return errors.New("the datastream option cannot be used with the xccdf and oval options")
+	}
+
+	contentFiles := []*string{&c.Content.XCCDF, &c.Content.OVAL}
+	for _, contentFile := range contentFiles {
+		cleanPath, err := SanitizePath(*contentFile)
+		if err != nil {
+			return err
+		}
+		if _, err := validatePath(cleanPath, false); err != nil {
+			return fmt.Errorf("invalid content path: %s: %w", cleanPath, err)
+		}
+		isXML, err := IsXMLFile(cleanPath)
+		if err != nil {
+			return fmt.Errorf("invalid content file: %s: %w", cleanPath, err)
+		}
+		if !isXML {
+			return fmt.Errorf("invalid content file: %s is not an XML file", cleanPath)
+		}
+		*contentFile = cleanPath
+	}
+	return nil
+}
+
+// setupContent sets up the content of a validated Config in the workspace:
+// a remote datastream is downloaded, or the separate XCCDF and OVAL files are
+// staged and the staged XCCDF file is then used in place of a datastream.
+func (c *Config) setupContent() error {
+	remote := isRemoteContent(c.Files.Datastream)
+	if !remote && c.Content.XCCDF == "" {
+		return nil
+	}
+	directories, err := ensureWorkspace(c)
+	if err != nil {
+		return err
+	}
+	if remote {
+		localDsPath, err := fetchRemoteContent(c.Files.Datastream, c.Content.DatastreamChecksum, directories["contentDir"])
+		if err != nil {
+			return fmt.Errorf("failed to fetch remote datastream: %w", err)
+		}
+		c.Files.Datastream = localDsPath
+		return nil
+	}
+	stagedXCCDF, err := stageXCCDFContent(c.Content.XCCDF, c.Content.OVAL, directories["contentDir"])
+	if err != nil {
+		return err
+	}
+	c.Files.Datastream = stagedXCCDF
+	return nil
+}
+
+// ovalHrefs returns the distinct OVAL files referenced by the rule checks
+// of an XCCDF benchmark.
+func ovalHrefs(xccdfPath string) ([]string, error) {
+	file, err := os.Open(filepath.Clean(xccdfPath))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type checkContentRef struct {
+		Href string `xml:"href,attr"`
+	}
+	type check struct {
+		System      string            `xml:"system,attr"`
+		ContentRefs []checkContentRef `xml:"check-content-ref"`
+	}
+
+	var hrefs []string
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding XCCDF file %s: %w", xccdfPath, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != xccdfURI || start.Name.Local != "check" {
+			continue
+		}
+		var ruleCheck check
+		if err := decoder.DecodeElement(&ruleCheck, &start); err != nil {
+			return nil, fmt.Errorf("error decoding check in XCCDF file %s: %w", xccdfPath, err)
+		}
+		if ruleCheck.System != ovalCheckSystem {
+			continue
+		}
+		for _, ref := range ruleCheck.ContentRefs {
+			if ref.Href != "" && !slices.Contains(hrefs, ref.Href) {
+				hrefs = append(hrefs, ref.Href)
+			}
+		}
+	}
+	return hrefs, nil
+}
+
+// stageXCCDFContent links an XCCDF benchmark and its OVAL definitions in
+// contentDir, so the OVAL file is found by oscap under the name referenced
+// by the benchmark. It returns the path of the staged XCCDF file.
+func stageXCCDFContent(xccdfPath, ovalPath, contentDir string) (string, error) {
+	hrefs, err := ovalHrefs(xccdfPath)
+	if err != nil {
+		return "", err
+	}
+	switch len(hrefs) {
+	case 0:
+		return "", fmt.Errorf("XCCDF file %s does not reference any OVAL file", xccdfPath)
+	case 1:
+	default:
+		return "", fmt.Errorf("XCCDF file %s references multiple OVAL files %v, use a datastream instead", xccdfPath, hrefs)
+	}
+
+	ovalName, err := SanitizeInput(filepath.Base(hrefs[0]))
+	if err != nil {
+		return "", fmt.Errorf("invalid OVAL file name referenced by %s: %w", xccdfPath, err)
+	}
+	xccdfName, err := SanitizeInput(filepath.Base(xccdfPath))
+	if err != nil {
+		return "", fmt.Errorf("invalid XCCDF file name %s: %w", xccdfPath, err)
+	}
+
+	links := map[string]string{
+		filepath.Join(contentDir, xccdfName): xccdfPath,
+		filepath.Join(contentDir, ovalName):  ovalPath,
+	}
+	for link, target := range links {
+		absTarget, err := filepath.Abs(target)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", target, err)
+		}
+		if err := os.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to replace staged content %s: %w", link, err)
+		}
+		if err := os.Symlink(absTarget, link); err != nil {
+			return "", fmt.Errorf("failed to stage content %s: %w", target, err)
+		}
+	}
+	hclog.Default().Debug("Staged XCCDF and OVAL content", "xccdf", xccdfPath, "oval", ovalPath, "dir", contentDir)
+	return filepath.Join(contentDir, xccdfName), nil
+}
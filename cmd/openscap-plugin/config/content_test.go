@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testXCCDFTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf-1.2:Benchmark xmlns:xccdf-1.2="http://checklists.nist.gov/xccdf/1.2" id="xccdf_org.ssgproject.content_benchmark_TEST">
+  <xccdf-1.2:Rule id="xccdf_org.ssgproject.content_rule_first" selected="true">
+    <xccdf-1.2:check system="http://oval.mitre.org/XMLSchema/oval-definitions-5">
+      <xccdf-1.2:check-content-ref href="ssg-test-oval.xml" name="oval:ssg-first:def:1"/>
+    </xccdf-1.2:check>
+  </xccdf-1.2:Rule>
+  <xccdf-1.2:Rule id="xccdf_org.ssgproject.content_rule_second" selected="true">
+    <xccdf-1.2:check system="http://oval.mitre.org/XMLSchema/oval-definitions-5">
+      <xccdf-1.2:check-content-ref href="%s" name="oval:ssg-second:def:1"/>
+    </xccdf-1.2:check>
+    <xccdf-1.2:check system="http://scap.nist.gov/schema/ocil/2">
+      <xccdf-1.2:check-content-ref href="ssg-test-ocil.xml" name="ocil:ssg-second:questionnaire:1"/>
+    </xccdf-1.2:check>
+  </xccdf-1.2:Rule>
+</xccdf-1.2:Benchmark>
+`
+
+// writeTestContent writes an XCCDF file whose second rule references the
+// given OVAL file, and an OVAL file, returning their paths.
+func writeTestContent(t *testing.T, secondHref string) (string, string) {
+	contentDir := t.TempDir()
+	xccdfPath := filepath.Join(contentDir, "ssg-test-xccdf.xml")
+	ovalPath := filepath.Join(contentDir, "oval-definitions.xml")
+	xccdfContent := []byte(fmt.Sprintf(testXCCDFTemplate, secondHref))
+	require.NoError(t, os.WriteFile(xccdfPath, xccdfContent, 0600))
+	require.NoError(t, os.WriteFile(ovalPath, []byte("<oval_definitions/>"), 0600))
+	return xccdfPath, ovalPath
+}
+
+func TestOvalHrefs(t *testing.T) {
+	xccdfPath, _ := writeTestContent(t, "ssg-test-oval.xml")
+	hrefs, err := ovalHrefs(xccdfPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ssg-test-oval.xml"}, hrefs)
+
+	xccdfPath, _ = writeTestContent(t, "ssg-other-oval.xml")
+	hrefs, err = ovalHrefs(xccdfPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ssg-test-oval.xml", "ssg-other-oval.xml"}, hrefs)
+}
+
+func TestStageXCCDFContent(t *testing.T) {
+	xccdfPath, ovalPath := writeTestContent(t, "ssg-test-oval.xml")
+	stagingDir := t.TempDir()
+
+	stagedXCCDF, err := stageXCCDFContent(xccdfPath, ovalPath, stagingDir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(stagingDir, "ssg-test-xccdf.xml"), stagedXCCDF)
+	stagedOval, err := os.ReadFile(filepath.Join(stagingDir, "ssg-test-oval.xml"))
+	require.NoError(t, err)
+	require.Equal(t, "<oval_definitions/>", string(stagedOval))
+
+	// Staging again replaces the existing links
+	_, err = stageXCCDFContent(xccdfPath, ovalPath, stagingDir)
+	require.NoError(t, err)
+
+	xccdfPath, ovalPath = writeTestContent(t, "ssg-other-oval.xml")
+	_, err = stageXCCDFContent(xccdfPath, ovalPath, stagingDir)
+	require.ErrorContains(t, err, "references multiple OVAL files")
+}
+
+func TestValidateXCCDFContent(t *testing.T) {
+	xccdfPath, ovalPath := writeTestContent(t, "ssg-test-oval.xml")
+
+	cfg := NewConfig()
+	cfg.Files.Workspace = t.TempDir()
+	cfg.Content.XCCDF = xccdfPath
+	require.EqualError(t, cfg.validateXCCDFContent(), "the xccdf and oval options must be set together")
+
+	cfg.Content.OVAL = ovalPath
+	cfg.Files.Datastream = "ssg-test-ds.xml"
+	require.EqualError(t, cfg.validateXCCDFContent(), "the datastream option cannot be used with the xccdf and oval options")
+
+	cfg.Files.Datastream = ""
+	invalidOval := filepath.Join(t.TempDir(), "oval.xml")
+	require.NoError(t, os.WriteFile(invalidOval, []byte("<oval_definitions"), 0600))
+	cfg.Content.OVAL = invalidOval
+	require.ErrorContains(t, cfg.validateXCCDFContent(), "invalid content file: "+invalidOval+": invalid XML file")
+
+	// the content is only staged by setupContent
+	cfg.Content.OVAL = ovalPath
+	require.NoError(t, cfg.validateXCCDFContent())
+	require.NoDirExists(t, filepath.Join(cfg.Files.Workspace, PluginDir))
+	require.NoError(t, cfg.setupContent())
+	require.Equal(t, filepath.Join(cfg.Files.Workspace, PluginDir, ContentDir, "ssg-test-xccdf.xml"), cfg.Files.Datastream)
+}
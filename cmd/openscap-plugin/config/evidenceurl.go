@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandEvidenceHref returns the href of the ARF evidence. The template may be
+// a base URL the ARF file name is appended to, or reference the ARF file name
+// as ${filename}. An empty template results in a file:// link to the ARF.
+func expandEvidenceHref(template, arfPath string) (string, error) {
+	if template == "" {
+		return fmt.Sprintf("file://%s", arfPath), nil
+	}
+	filename := url.PathEscape(filepath.Base(arfPath))
+	var href string
+	if strings.Contains(template, "${") {
+		var unknown []string
+		href = os.Expand(template, func(name string) string {
+			if name != "filename" {
+				unknown = append(unknown, name)
+			}
+			return filename
+		})
+		if len(unknown) > 0 {
+			return "", fmt.Errorf("evidence url template %q references unknown variables: %s", template, strings.Join(unknown, ", "))
+		}
+	} else {
+		href = strings.TrimSuffix(template, "/") + "/" + filename
+	}
+	if parsed, err := url.Parse(href); err != nil || parsed.Scheme == "" {
+		return "", fmt.Errorf("evidence url %q is not an absolute URL", href)
+	}
+	return href, nil
+}
+
+// validateEvidenceURL checks the evidence URL template expands to an absolute
+// URL, so a misconfigured template fails when the plugin is configured rather
+// than when the results are collected.
+func (c *Config) validateEvidenceURL() error {
+	_, err := expandEvidenceHref(c.Results.EvidenceURL, "arf.xml")
+	return err
+}
+
+// EvidenceHref returns the href of the evidence of the observations read from
+// the ARF file at arfPath.
+func (c *Config) EvidenceHref(arfPath string) (string, error) {
+	return expandEvidenceHref(c.Results.EvidenceURL, arfPath)
+}
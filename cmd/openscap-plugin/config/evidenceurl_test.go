@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEvidenceHref(t *testing.T) {
+	arfPath := "/workspace/openscap/results/arf.xml"
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{name: "Valid/Default", template: "", want: "file:///workspace/openscap/results/arf.xml"},
+		{name: "Valid/BaseURL", template: "https://reports.example.com/rhel10/", want: "https://reports.example.com/rhel10/arf.xml"},
+		{name: "Valid/Template", template: "s3://evidence/${filename}?version=1", want: "s3://evidence/arf.xml?version=1"},
+		{
+			name:     "Invalid/UnknownVariable",
+			template: "https://reports.example.com/${target}/${filename}",
+			wantErr:  "evidence url template \"https://reports.example.com/${target}/${filename}\" references unknown variables: target",
+		},
+		{
+			name:     "Invalid/Relative",
+			template: "reports/${filename}",
+			wantErr:  "evidence url \"reports/arf.xml\" is not an absolute URL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEvidenceHref(tt.template, arfPath)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateEvidenceURL(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateEvidenceURL())
+
+	cfg.Results.EvidenceURL = "https://reports.example.com/${target}/${filename}"
+	require.EqualError(t, cfg.validateEvidenceURL(), "evidence url template \"https://reports.example.com/${target}/${filename}\" references unknown variables: target")
+}
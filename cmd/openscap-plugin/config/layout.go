@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// layoutDirs returns the configured directory templates of the workspace
+// layout by artifact directory: PolicyDir, ResultsDir, RemediationDir or
+// ContentDir.
+func (c *Config) layoutDirs() map[string]*string {
+	return map[string]*string{
+		PolicyDir:      &c.Layout.PolicyDir,
+		ResultsDir:     &c.Layout.ResultsDir,
+		RemediationDir: &c.Layout.RemediationsDir,
+		ContentDir:     &c.Layout.ContentDir,
+	}
+}
+
+// expandLayoutTemplate replaces the ${profile} placeholder of a directory
+// template of the workspace layout.
+func expandLayoutTemplate(template, profile string) (string, error) {
+	var unknown []string
+	dir := os.Expand(template, func(name string) string {
+		if name == "profile" {
+			return profile
+		}
+		unknown = append(unknown, name)
+		return ""
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("layout template %q references unknown placeholders: %s", template, strings.Join(unknown, ", "))
+	}
+	return dir, nil
+}
+
+// validateLayout expands and sanitizes the directory templates of the
+// workspace layout.
+func (c *Config) validateLayout() error {
+	for _, dir := range c.layoutDirs() {
+		if *dir == "" {
+			continue
+		}
+		expanded, err := expandLayoutTemplate(*dir, c.Parameters.Profile)
+		if err != nil {
+			return err
+		}
+		cleanPath, err := SanitizePath(expanded)
+		if err != nil {
+			return fmt.Errorf("invalid layout directory %q: %w", *dir, err)
+		}
+		*dir = cleanPath
+	}
+	return nil
+}
+
+// LayoutDir returns the directory of the artifacts of the given artifact
+// directory, PolicyDir, ResultsDir, RemediationDir or ContentDir, in the
+// workspace. It defaults to the artifact directory in the PluginDir of the
+// workspace, and configured relative directories are relative to the
+// workspace.
+func (c *Config) LayoutDir(workspace, artifactDir string) string {
+	dir := filepath.Join(PluginDir, artifactDir)
+	if configured, ok := c.layoutDirs()[artifactDir]; ok && *configured != "" {
+		dir = *configured
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(workspace, dir)
+}
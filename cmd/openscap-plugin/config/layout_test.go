@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := NewConfig()
+	cfg.Parameters.Profile = "cis"
+	require.NoError(t, cfg.validateLayout())
+	require.Equal(t, filepath.Join(workspace, "openscap", "policy"), cfg.LayoutDir(workspace, PolicyDir))
+	require.Equal(t, filepath.Join(workspace, "openscap", "remediations"), cfg.LayoutDir(workspace, RemediationDir))
+
+	reports := filepath.Join(t.TempDir(), "reports")
+	cfg.Layout.ResultsDir = reports + "/${profile}"
+	cfg.Layout.RemediationsDir = "fixes/./${profile}"
+	require.NoError(t, cfg.validateLayout())
+	require.Equal(t, filepath.Join(reports, "cis"), cfg.LayoutDir(workspace, ResultsDir))
+	require.Equal(t, filepath.Join(workspace, "fixes", "cis"), cfg.LayoutDir(workspace, RemediationDir))
+	require.Equal(t, filepath.Join(workspace, "openscap", "content"), cfg.LayoutDir(workspace, ContentDir))
+
+	cfg.Layout.PolicyDir = "policy/${hostname}"
+	require.EqualError(t, cfg.validateLayout(), "layout template \"policy/${hostname}\" references unknown placeholders: hostname")
+}
+
+func TestDefineFilesPathsLayout(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := NewConfig()
+	cfg.Files.Workspace = workspace
+	cfg.Files.Policy = "policy.xml"
+	cfg.Files.Results = "results.xml"
+	cfg.Files.ARF = "arf.xml"
+	cfg.Layout.PolicyDir = "tailoring"
+	cfg.Layout.ResultsDir = "reports"
+	require.NoError(t, defineFilesPaths(cfg))
+	require.Equal(t, filepath.Join(workspace, "tailoring", "policy.xml"), cfg.Files.Policy)
+	require.Equal(t, filepath.Join(workspace, "reports", "arf.xml"), cfg.Files.ARF)
+	require.DirExists(t, filepath.Join(workspace, "openscap", "remediations"))
+}
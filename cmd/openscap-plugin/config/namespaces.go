@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultNamespacePrefix is the prefix of the XCCDF 1.2 namespace in the
+// generated tailoring files.
+const DefaultNamespacePrefix = "xccdf-1.2"
+
+// namespacePrefixPattern matches an XML namespace prefix. Prefixes starting
+// with xml are reserved.
+var namespacePrefixPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9._-]*$`)
+
+// namespaceURIPattern matches a namespace URI that can be written in an
+// attribute and listed in the namespaces option.
+var namespaceURIPattern = regexp.MustCompile(`^[^\s"'<>&,]+$`)
+
+// NamespacePrefix returns the prefix of the XCCDF 1.2 namespace in the
+// generated tailoring files.
+func (c *Config) NamespacePrefix() string {
+	if c.Tailoring.NamespacePrefix == "" {
+		return DefaultNamespacePrefix
+	}
+	return c.Tailoring.NamespacePrefix
+}
+
+// Namespaces returns the additional namespaces declared in the generated
+// tailoring files by prefix, or nil when none is set.
+func (c *Config) Namespaces() map[string]string {
+	namespaces, _ := parseNamespaces(c.Tailoring.Namespaces)
+	return namespaces
+}
+
+func validateNamespacePrefix(prefix string) error {
+	if !namespacePrefixPattern.MatchString(prefix) || strings.HasPrefix(strings.ToLower(prefix), "xml") {
+		return fmt.Errorf("invalid namespace prefix %q: must be an XML name not starting with xml", prefix)
+	}
+	return nil
+}
+
+func parseNamespaces(value string) (map[string]string, error) {
+	var namespaces map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		prefix, uri, ok := strings.Cut(pair, "=")
+		prefix, uri = strings.TrimSpace(prefix), strings.TrimSpace(uri)
+		if !ok || prefix == "" || uri == "" {
+			return nil, fmt.Errorf("invalid namespace %q: must be <prefix>=<uri>", pair)
+		}
+		if err := validateNamespacePrefix(prefix); err != nil {
+			return nil, err
+		}
+		if !namespaceURIPattern.MatchString(uri) {
+			return nil, fmt.Errorf("invalid namespace %q: unexpected characters in uri %q", pair, uri)
+		}
+		if namespaces == nil {
+			namespaces = make(map[string]string)
+		}
+		namespaces[prefix] = uri
+	}
+	return namespaces, nil
+}
+
+// validateNamespaces checks the prefix of the XCCDF namespace and the
+// additional namespaces of the generated tailoring files.
+func (c *Config) validateNamespaces() error {
+	if c.Tailoring.NamespacePrefix != "" {
+		if err := validateNamespacePrefix(c.Tailoring.NamespacePrefix); err != nil {
+			return err
+		}
+	}
+	namespaces, err := parseNamespaces(c.Tailoring.Namespaces)
+	if err != nil {
+		return err
+	}
+	if _, ok := namespaces[c.NamespacePrefix()]; ok {
+		return fmt.Errorf("invalid namespace %q: the prefix is used by the XCCDF namespace", c.NamespacePrefix())
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateNamespaces())
+	require.Equal(t, "xccdf-1.2", cfg.NamespacePrefix())
+	require.Nil(t, cfg.Namespaces())
+
+	cfg.Tailoring.NamespacePrefix = "xccdf"
+	cfg.Tailoring.Namespaces = " xsi = http://www.w3.org/2001/XMLSchema-instance, dc=http://purl.org/dc/elements/1.1/,"
+	require.NoError(t, cfg.validateNamespaces())
+	require.Equal(t, "xccdf", cfg.NamespacePrefix())
+	require.Equal(t, map[string]string{
+		"xsi": "http://www.w3.org/2001/XMLSchema-instance",
+		"dc":  "http://purl.org/dc/elements/1.1/",
+	}, cfg.Namespaces())
+
+	cfg.Tailoring.Namespaces = "xccdf=http://checklists.nist.gov/xccdf/1.2"
+	require.EqualError(t, cfg.validateNamespaces(), "invalid namespace \"xccdf\": the prefix is used by the XCCDF namespace")
+
+	cfg.Tailoring.Namespaces = "xsi"
+	require.EqualError(t, cfg.validateNamespaces(), "invalid namespace \"xsi\": must be <prefix>=<uri>")
+
+	cfg.Tailoring.Namespaces = "dc=\"http://purl.org/dc/elements/1.1/\""
+	require.ErrorContains(t, cfg.validateNamespaces(), "unexpected characters in uri")
+
+	cfg.Tailoring.Namespaces = ""
+	cfg.Tailoring.NamespacePrefix = "xmlns"
+	require.EqualError(t, cfg.validateNamespaces(), "invalid namespace prefix \"xmlns\": must be an XML name not starting with xml")
+	cfg.Tailoring.NamespacePrefix = "1xccdf"
+	require.Error(t, cfg.validateNamespaces())
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// oscapArgs are the options of oscap xccdf eval allowed in extraargs, with
+// the values each option accepts, or nil for options without a value. Only
+// options changing what oscap reports are allowed: the files, profile and
+// target of the evaluation are set by the plugin, and options changing the
+// system, such as --remediate, or writing files at arbitrary paths, such as
+// --report, are not. Neither are --oval-results, which writes files in the
+// working directory of oscap, nor --fetch-remote-resources, which downloads
+// content that is not verified. New options must be reviewed with this in mind.
+var oscapArgs = map[string][]string{
+	// --thin-results omits the details of the checks from the results,
+	// including the system characteristics.
+	"--thin-results": nil,
+	// --without-syschar omits the system characteristics from the OVAL
+	// results.
+	"--without-syschar": nil,
+	// --check-engine-results saves the results of check engines other than
+	// OVAL, such as SCE.
+	"--check-engine-results": nil,
+	// --enforce-signature fails the evaluation of unsigned datastreams.
+	"--enforce-signature": nil,
+	// --verbose sets the level of the oscap logs written in its output.
+	"--verbose": {"DEVEL", "INFO", "WARNING", "ERROR"},
+}
+
+// ScanExtraArgs returns the additional oscap options of the scan, or nil when
+// none is set.
+func (c *Config) ScanExtraArgs() []string {
+	var args []string
+	for _, arg := range strings.Split(c.Scan.ExtraArgs, ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// validateExtraArgs checks the additional oscap options are allowed options
+// with valid values, each given once.
+func (c *Config) validateExtraArgs() error {
+	args := c.ScanExtraArgs()
+	if len(args) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(args))
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		values, ok := oscapArgs[name]
+		if !ok {
+			return fmt.Errorf("invalid oscap argument %q: must be one of %s", name, strings.Join(slices.Sorted(maps.Keys(oscapArgs)), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("invalid oscap argument %q: set several times", name)
+		}
+		seen[name] = true
+		switch {
+		case values == nil && hasValue:
+			return fmt.Errorf("invalid oscap argument %q: %s takes no value", arg, name)
+		case values != nil && !slices.Contains(values, value):
+			return fmt.Errorf("invalid oscap argument %q: must be %s=<value> with one of %s", arg, name, strings.Join(values, ", "))
+		}
+	}
+	// oscap-ssh only accepts the options of the files it copies
+	if len(c.ScanHosts()) > 0 {
+		return errors.New("extraargs cannot be combined with hosts")
+	}
+	return nil
+}
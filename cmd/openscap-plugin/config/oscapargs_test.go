@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraArgs(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateExtraArgs())
+	require.Nil(t, cfg.ScanExtraArgs())
+
+	cfg.Scan.ExtraArgs = " --thin-results, --verbose=WARNING,"
+	require.NoError(t, cfg.validateExtraArgs())
+	require.Equal(t, []string{"--thin-results", "--verbose=WARNING"}, cfg.ScanExtraArgs())
+
+	cfg.Scan.ExtraArgs = "--remediate"
+	require.ErrorContains(t, cfg.validateExtraArgs(), "invalid oscap argument \"--remediate\": must be one of --check-engine-results,")
+
+	cfg.Scan.ExtraArgs = "--report=/etc/passwd"
+	require.ErrorContains(t, cfg.validateExtraArgs(), "invalid oscap argument \"--report\"")
+
+	cfg.Scan.ExtraArgs = "--thin-results=yes"
+	require.EqualError(t, cfg.validateExtraArgs(), "invalid oscap argument \"--thin-results=yes\": --thin-results takes no value")
+
+	cfg.Scan.ExtraArgs = "--verbose"
+	require.EqualError(t, cfg.validateExtraArgs(), "invalid oscap argument \"--verbose\": must be --verbose=<value> with one of DEVEL, INFO, WARNING, ERROR")
+
+	cfg.Scan.ExtraArgs = "--oval-results"
+	require.ErrorContains(t, cfg.validateExtraArgs(), "invalid oscap argument \"--oval-results\"")
+
+	cfg.Scan.ExtraArgs = "--fetch-remote-resources"
+	require.ErrorContains(t, cfg.validateExtraArgs(), "invalid oscap argument \"--fetch-remote-resources\"")
+
+	cfg.Scan.ExtraArgs = "--thin-results,--thin-results"
+	require.EqualError(t, cfg.validateExtraArgs(), "invalid oscap argument \"--thin-results\": set several times")
+
+	cfg.Scan.ExtraArgs = "--thin-results"
+	cfg.Scan.Hosts = "host1"
+	require.EqualError(t, cfg.validateExtraArgs(), "extraargs cannot be combined with hosts")
+}
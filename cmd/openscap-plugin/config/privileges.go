@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// lookupUser returns the user with the given name or, failing that, id.
+func lookupUser(nameOrID string) (*user.User, error) {
+	usr, err := user.Lookup(nameOrID)
+	if err == nil {
+		return usr, nil
+	}
+	if _, convErr := strconv.ParseUint(nameOrID, 10, 32); convErr != nil {
+		return nil, err
+	}
+	return user.LookupId(nameOrID)
+}
+
+// ScanCredential returns the user and groups oscap runs as, or nil when no
+// scan user is set and oscap runs as the plugin.
+func (c *Config) ScanCredential() (*syscall.Credential, error) {
+	if c.Scan.User == "" {
+		return nil, nil
+	}
+	usr, err := lookupUser(c.Scan.User)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan user %q: %w", c.Scan.User, err)
+	}
+	uid, err := strconv.ParseUint(usr.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan user %q: unexpected uid %q", c.Scan.User, usr.Uid)
+	}
+	gid, err := strconv.ParseUint(usr.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan user %q: unexpected gid %q", c.Scan.User, usr.Gid)
+	}
+	groupIDs, err := usr.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan user %q: %w", c.Scan.User, err)
+	}
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	for _, groupID := range groupIDs {
+		if group, err := strconv.ParseUint(groupID, 10, 32); err == nil {
+			credential.Groups = append(credential.Groups, uint32(group))
+		}
+	}
+	return credential, nil
+}
+
+// validatePrivileges checks the privileges needed by the profile and the
+// scan user.
+func (c *Config) validatePrivileges() error {
+	switch c.Scan.Privileges {
+	case "", PrivilegesRoot, PrivilegesUnprivileged:
+	default:
+		return fmt.Errorf("invalid privileges %q: must be %q or %q", c.Scan.Privileges, PrivilegesRoot, PrivilegesUnprivileged)
+	}
+	if c.Scan.User == "" {
+		return nil
+	}
+	credential, err := c.ScanCredential()
+	if err != nil {
+		return err
+	}
+	switch {
+	case credential.Uid == 0:
+		return fmt.Errorf("invalid scan user %q: must not be root", c.Scan.User)
+	case c.Scan.Privileges == PrivilegesRoot:
+		return errors.New("scanuser cannot be combined with privileges root")
+	}
+	return nil
+}
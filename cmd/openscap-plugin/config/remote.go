@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	// remoteFetchTimeout bounds the time spent downloading remote content.
+	remoteFetchTimeout = 10 * time.Minute
+	// maxRemoteContentSize bounds the size of downloaded remote content, well
+	// above the size of the largest SCAP datastreams.
+	maxRemoteContentSize = 1 << 30
+)
+
+// isRemoteContent reports whether the given content location is an HTTP(S) URL.
+func isRemoteContent(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// FileChecksum returns the hex encoded SHA256 checksum of a file.
+func FileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filepath.Clean(filePath))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to compute checksum of %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// normalizeChecksum validates a configured SHA256 checksum, accepting an optional
+// "sha256:" prefix.
+func normalizeChecksum(checksum string) (string, error) {
+	checksum = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	decoded, err := hex.DecodeString(checksum)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid sha256 checksum %q", checksum)
+	}
+	return checksum, nil
+}
+
+// validateRemoteContent checks the URL and the expected checksum of remote
+// content, without downloading it. It returns the normalized checksum and the
+// name of the local copy.
+func validateRemoteContent(rawURL, expectedChecksum string) (string, string, error) {
+	if expectedChecksum == "" {
+		return "", "", fmt.Errorf("a checksum is required to use remote content from %s", rawURL)
+	}
+	checksum, err := normalizeChecksum(expectedChecksum)
+	if err != nil {
+		return "", "", err
+	}
+
+	contentURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid content URL %s: %w", rawURL, err)
+	}
+	fileName, err := SanitizeInput(path.Base(contentURL.Path))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid file name in content URL %s: %w", rawURL, err)
+	}
+	return checksum, fileName, nil
+}
+
+// fetchRemoteContent downloads the content at rawURL into cacheDir and verifies it
+// against the expected checksum. Previously downloaded content matching the checksum
+// is reused. It returns the path of the local copy.
+func fetchRemoteContent(rawURL, expectedChecksum, cacheDir string) (string, error) {
+	checksum, fileName, err := validateRemoteContent(rawURL, expectedChecksum)
+	if err != nil {
+		return "", err
+	}
+	localPath := filepath.Join(cacheDir, fileName)
+
+	cachedChecksum, err := FileChecksum(localPath)
+	switch {
+	case err == nil && cachedChecksum == checksum:
+		hclog.Default().Debug("Using cached remote content", "url", rawURL, "path", localPath)
+		return localPath, nil
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return "", fmt.Errorf("failed to verify cached content %s: %w", localPath, err)
+	}
+
+	hclog.Default().Info("Downloading remote content", "url", rawURL, "path", localPath)
+	if err := downloadFile(rawURL, localPath, checksum, maxRemoteContentSize); err != nil {
+		return "", err
+	}
+	return localPath, nil
+}
+
+// downloadFile writes the content of a URL to localPath only if it matches
+// the expected checksum and is at most maxSize bytes.
+func downloadFile(contentURL, localPath, checksum string, maxSize int64) error {
+	client := &http.Client{Timeout: remoteFetchTimeout}
+	resp, err := client.Get(contentURL)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", contentURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: unexpected status %s", contentURL, resp.Status)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(localPath), ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hash := sha256.New()
+	// one more byte than the maximum is read to detect larger content
+	written, err := io.Copy(io.MultiWriter(tmpFile, hash), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to download %s: %w", contentURL, err)
+	}
+	if written > maxSize {
+		tmpFile.Close()
+		return fmt.Errorf("failed to download %s: content exceeds the maximum size of %d bytes", contentURL, maxSize)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpFile.Name(), err)
+	}
+
+	gotChecksum := hex.EncodeToString(hash.Sum(nil))
+	if gotChecksum != checksum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", contentURL, checksum, gotChecksum)
+	}
+	return os.Rename(tmpFile.Name(), localPath)
+}
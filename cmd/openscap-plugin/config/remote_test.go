@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const remoteContent = `<root></root>`
+
+func remoteContentChecksum() string {
+	sum := sha256.Sum256([]byte(remoteContent))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIsRemoteContent(t *testing.T) {
+	require.True(t, isRemoteContent("https://example.com/ssg-rhel9-ds.xml"))
+	require.True(t, isRemoteContent("http://example.com/ssg-rhel9-ds.xml"))
+	require.False(t, isRemoteContent("/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml"))
+	require.False(t, isRemoteContent("ftp://example.com/ssg-rhel9-ds.xml"))
+}
+
+func TestNormalizeChecksum(t *testing.T) {
+	checksum := remoteContentChecksum()
+
+	got, err := normalizeChecksum("sha256:" + checksum)
+	require.NoError(t, err)
+	require.Equal(t, checksum, got)
+
+	_, err = normalizeChecksum("abc")
+	require.EqualError(t, err, "invalid sha256 checksum \"abc\"")
+}
+
+func TestFetchRemoteContent(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/ssg-test-ds.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(remoteContent))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	contentURL := server.URL + "/ssg-test-ds.xml"
+
+	localPath, err := fetchRemoteContent(contentURL, remoteContentChecksum(), cacheDir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(cacheDir, "ssg-test-ds.xml"), localPath)
+	content, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	require.Equal(t, remoteContent, string(content))
+	require.Equal(t, 1, requests)
+
+	// Cached content matching the checksum is not downloaded again
+	_, err = fetchRemoteContent(contentURL, remoteContentChecksum(), cacheDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+
+	wrongChecksum := hex.EncodeToString(make([]byte, sha256.Size))
+	_, err = fetchRemoteContent(contentURL, wrongChecksum, t.TempDir())
+	require.ErrorContains(t, err, "checksum mismatch")
+
+	_, err = fetchRemoteContent(contentURL, "", cacheDir)
+	require.ErrorContains(t, err, "a checksum is required")
+
+	_, err = fetchRemoteContent(server.URL+"/absent.xml", remoteContentChecksum(), cacheDir)
+	require.ErrorContains(t, err, "unexpected status 404 Not Found")
+}
+
+func TestValidateRemoteContent(t *testing.T) {
+	checksum, fileName, err := validateRemoteContent("https://example.com/content/ssg-test-ds.xml", "sha256:"+remoteContentChecksum())
+	require.NoError(t, err)
+	require.Equal(t, remoteContentChecksum(), checksum)
+	require.Equal(t, "ssg-test-ds.xml", fileName)
+
+	_, _, err = validateRemoteContent("https://example.com/content/ssg-test-ds.xml", "")
+	require.ErrorContains(t, err, "a checksum is required")
+}
+
+func TestDownloadFileMaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(remoteContent))
+	}))
+	defer server.Close()
+
+	localPath := filepath.Join(t.TempDir(), "ssg-test-ds.xml")
+	err := downloadFile(server.URL, localPath, remoteContentChecksum(), int64(len(remoteContent)-1))
+	require.ErrorContains(t, err, "content exceeds the maximum size of 12 bytes")
+	require.NoFileExists(t, localPath)
+
+	require.NoError(t, downloadFile(server.URL, localPath, remoteContentChecksum(), int64(len(remoteContent))))
+	require.FileExists(t, localPath)
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ResourceGroup maps an evaluated target, such as a node of a cluster, to the
+// resources its results apply to, such as all the nodes of the cluster.
+type ResourceGroup struct {
+	// Target is the target of the rule results, as reported in the ARF.
+	Target string `json:"target"`
+	// Resources are the resource ids of the subjects of the observations.
+	Resources []string `json:"resources"`
+}
+
+// resourceGroupsFile is the content of a resource groups file.
+type resourceGroupsFile struct {
+	Groups []ResourceGroup `json:"groups"`
+}
+
+// ReadResourceGroups reads the resource groups file at path and returns the
+// resource ids by target.
+func ReadResourceGroups(path string) (map[string][]string, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource groups: %w", err)
+	}
+	var file resourceGroupsFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode resource groups %s: %w", path, err)
+	}
+	groups := make(map[string][]string, len(file.Groups))
+	for _, group := range file.Groups {
+		if group.Target == "" {
+			return nil, fmt.Errorf("invalid resource group in %s: missing target", path)
+		}
+		if _, ok := groups[group.Target]; ok {
+			return nil, fmt.Errorf("target %s has several resource groups", group.Target)
+		}
+		if len(group.Resources) == 0 {
+			return nil, fmt.Errorf("resource group of target %s has no resources", group.Target)
+		}
+		seen := make(map[string]struct{}, len(group.Resources))
+		for _, resource := range group.Resources {
+			if resource == "" {
+				return nil, fmt.Errorf("resource group of target %s has an empty resource id", group.Target)
+			}
+			if _, ok := seen[resource]; ok {
+				return nil, fmt.Errorf("resource group of target %s has resource %s several times", group.Target, resource)
+			}
+			seen[resource] = struct{}{}
+		}
+		groups[group.Target] = group.Resources
+	}
+	return groups, nil
+}
+
+// ResourceGroups returns the resource ids by target of the resource groups
+// file set in the configuration, or nil when there is none.
+func (c *Config) ResourceGroups() (map[string][]string, error) {
+	if c.Results.ResourceGroups == "" {
+		return nil, nil
+	}
+	return ReadResourceGroups(c.Results.ResourceGroups)
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadResourceGroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        map[string][]string
+		expectError string
+	}{
+		{
+			name: "Valid/Groups",
+			content: `{"groups": [
+				{"target": "db-1", "resources": ["db-1", "db-2"]},
+				{"target": "web", "resources": ["web-a"]}
+			]}`,
+			want: map[string][]string{
+				"db-1": {"db-1", "db-2"},
+				"web":  {"web-a"},
+			},
+		},
+		{
+			name:        "Invalid/MissingTarget",
+			content:     `{"groups": [{"resources": ["db-1"]}]}`,
+			expectError: "missing target",
+		},
+		{
+			name:        "Invalid/DuplicateTarget",
+			content:     `{"groups": [{"target": "db-1", "resources": ["db-1"]}, {"target": "db-1", "resources": ["db-2"]}]}`,
+			expectError: "target db-1 has several resource groups",
+		},
+		{
+			name:        "Invalid/NoResources",
+			content:     `{"groups": [{"target": "db-1", "resources": []}]}`,
+			expectError: "resource group of target db-1 has no resources",
+		},
+		{
+			name:        "Invalid/DuplicateResource",
+			content:     `{"groups": [{"target": "db-1", "resources": ["db-1", "db-1"]}]}`,
+			expectError: "resource group of target db-1 has resource db-1 several times",
+		},
+		{
+			name:        "Invalid/JSON",
+			content:     `{"groups": `,
+			expectError: "failed to decode resource groups",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "groups.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			groups, err := ReadResourceGroups(path)
+			if tt.expectError != "" {
+				require.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, groups)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds of the retries of a failed evaluation, and the delay between the
+// retries when not configured.
+const (
+	maxScanRetries        = 5
+	maxScanRetryDelay     = 10 * time.Minute
+	defaultScanRetryDelay = 5 * time.Second
+)
+
+// ScanRetryDelay returns the delay before each retry of a failed evaluation.
+func (c *Config) ScanRetryDelay() time.Duration {
+	delay, err := time.ParseDuration(c.Scan.ScanRetryDelay)
+	if err != nil {
+		return defaultScanRetryDelay
+	}
+	return delay
+}
+
+// RetryExitCodes returns the exit codes of oscap for which a failed
+// evaluation is retried, or nil when none is set.
+func (c *Config) RetryExitCodes() []int {
+	codes, _ := parseExitCodes(c.Scan.RetryExitCodes)
+	return codes
+}
+
+// RetryPattern returns the regular expression matching the output of oscap
+// for which a failed evaluation is retried, or nil when none is set.
+func (c *Config) RetryPattern() *regexp.Regexp {
+	if c.Scan.RetryPattern == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(c.Scan.RetryPattern)
+	if err != nil {
+		return nil
+	}
+	return pattern
+}
+
+func parseExitCodes(value string) ([]int, error) {
+	var codes []int
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		// exit code 0 is a success and 2 reports failed rules
+		if err != nil || code < 1 || code > 255 || code == 2 {
+			return nil, fmt.Errorf("invalid retry exit code %q: must be an exit code between 1 and 255, other than 2", field)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// validateScanRetries checks the retries of a failed evaluation are bounded
+// and limited to the configured exit codes and output pattern.
+func (c *Config) validateScanRetries() error {
+	if c.Scan.ScanRetries < 0 || c.Scan.ScanRetries > maxScanRetries {
+		return fmt.Errorf("invalid scan retries %d: must be between 0 and %d", c.Scan.ScanRetries, maxScanRetries)
+	}
+	if c.Scan.ScanRetryDelay != "" {
+		delay, err := time.ParseDuration(c.Scan.ScanRetryDelay)
+		if err != nil {
+			return fmt.Errorf("invalid scan retry delay: %w", err)
+		}
+		if delay <= 0 || delay > maxScanRetryDelay {
+			return fmt.Errorf("invalid scan retry delay %s: must be positive and at most %s", delay, maxScanRetryDelay)
+		}
+	}
+	if _, err := parseExitCodes(c.Scan.RetryExitCodes); err != nil {
+		return err
+	}
+	if c.Scan.RetryPattern != "" {
+		if _, err := regexp.Compile(c.Scan.RetryPattern); err != nil {
+			return fmt.Errorf("invalid retry pattern: %w", err)
+		}
+	}
+	// failures are only retried when they are known to be transient
+	if c.Scan.ScanRetries > 0 && c.Scan.RetryExitCodes == "" && c.Scan.RetryPattern == "" {
+		return errors.New("scanretries requires retryexitcodes or retrypattern")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateScanRetries(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateScanRetries())
+	require.Equal(t, 5*time.Second, cfg.ScanRetryDelay())
+	require.Nil(t, cfg.RetryExitCodes())
+	require.Nil(t, cfg.RetryPattern())
+
+	cfg.Scan.ScanRetries = 3
+	require.EqualError(t, cfg.validateScanRetries(), "scanretries requires retryexitcodes or retrypattern")
+
+	cfg.Scan.ScanRetryDelay = "30s"
+	cfg.Scan.RetryExitCodes = "1, 139"
+	cfg.Scan.RetryPattern = `probe_\w+: timeout`
+	require.NoError(t, cfg.validateScanRetries())
+	require.Equal(t, 30*time.Second, cfg.ScanRetryDelay())
+	require.Equal(t, []int{1, 139}, cfg.RetryExitCodes())
+	require.True(t, cfg.RetryPattern().MatchString("E: probe_rpminfo: timeout"))
+
+	cfg.Scan.ScanRetries = 6
+	require.EqualError(t, cfg.validateScanRetries(), "invalid scan retries 6: must be between 0 and 5")
+	cfg.Scan.ScanRetries = 1
+
+	cfg.Scan.ScanRetryDelay = "1h"
+	require.EqualError(t, cfg.validateScanRetries(), "invalid scan retry delay 1h0m0s: must be positive and at most 10m0s")
+	cfg.Scan.ScanRetryDelay = ""
+
+	cfg.Scan.RetryExitCodes = "2"
+	require.EqualError(t, cfg.validateScanRetries(), "invalid retry exit code \"2\": must be an exit code between 1 and 255, other than 2")
+	cfg.Scan.RetryExitCodes = "one"
+	require.ErrorContains(t, cfg.validateScanRetries(), "invalid retry exit code \"one\"")
+	cfg.Scan.RetryExitCodes = ""
+
+	cfg.Scan.RetryPattern = "probe_("
+	require.ErrorContains(t, cfg.validateScanRetries(), "invalid retry pattern")
+}
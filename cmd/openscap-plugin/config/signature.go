@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/hashicorp/go-hclog"
+)
+
+// ErrSignatureInvalid is returned when the signature of the content does not
+// verify with the configured public key.
+var ErrSignatureInvalid = errors.New("invalid signature")
+
+// VerifySignature verifies the detached OpenPGP signature at signaturePath of
+// the file at path with the public keys at keyPath. The signature and the keys
+// can be ASCII armored or binary.
+func VerifySignature(path, signaturePath, keyPath string) error {
+	keyContent, err := os.ReadFile(filepath.Clean(keyPath))
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	var keyRing openpgp.EntityList
+	if isArmored(keyContent) {
+		keyRing, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(keyContent))
+	} else {
+		keyRing, err = openpgp.ReadKeyRing(bytes.NewReader(keyContent))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read public key %s: %w", keyPath, err)
+	}
+
+	signature, err := os.ReadFile(filepath.Clean(signaturePath))
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	content, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	var signer *openpgp.Entity
+	if isArmored(signature) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyRing, content, bytes.NewReader(signature), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyRing, content, bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s does not match signature %s: %w", ErrSignatureInvalid, path, signaturePath, err)
+	}
+	hclog.Default().Debug("Verified content signature", "path", path, "key", fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint))
+	return nil
+}
+
+// isArmored reports whether the OpenPGP content is ASCII armored.
+func isArmored(content []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN PGP")) {
+		return false
+	}
+	_, err := armor.Decode(bytes.NewReader(content))
+	return err == nil
+}
+
+// validateDatastreamSignature verifies the signature of the datastream when a
+// signature is set, so tampered content is never evaluated.
+func (c *Config) validateDatastreamSignature() error {
+	if c.Content.DatastreamSignature == "" && c.Content.DatastreamKey == "" {
+		return nil
+	}
+	if c.Content.DatastreamSignature == "" || c.Content.DatastreamKey == "" {
+		return errors.New("the datastreamsignature and datastreamkey options must be set together")
+	}
+	for _, file := range []*string{&c.Content.DatastreamSignature, &c.Content.DatastreamKey} {
+		cleanPath, err := SanitizePath(*file)
+		if err != nil {
+			return err
+		}
+		*file = cleanPath
+	}
+	return VerifySignature(c.Files.Datastream, c.Content.DatastreamSignature, c.Content.DatastreamKey)
+}
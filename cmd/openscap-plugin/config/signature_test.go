@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSigningKey writes the armored public key of a new signing key to dir
+// and returns the key.
+func writeSigningKey(t *testing.T, dir, name string) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Content Team", "", "content@example.com", nil)
+	require.NoError(t, err)
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), key.Bytes(), 0600))
+	return entity
+}
+
+func TestVerifySignature(t *testing.T) {
+	dir := t.TempDir()
+	datastream := filepath.Join(dir, "ds.xml")
+	require.NoError(t, os.WriteFile(datastream, []byte("<ds/>\n"), 0600))
+	signer := writeSigningKey(t, dir, "key.asc")
+	writeSigningKey(t, dir, "other.asc")
+
+	var armored, binary bytes.Buffer
+	content, err := os.ReadFile(datastream)
+	require.NoError(t, err)
+	require.NoError(t, openpgp.ArmoredDetachSign(&armored, signer, bytes.NewReader(content), nil))
+	require.NoError(t, openpgp.DetachSign(&binary, signer, bytes.NewReader(content), nil))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ds.xml.asc"), armored.Bytes(), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ds.xml.sig"), binary.Bytes(), 0600))
+
+	require.NoError(t, VerifySignature(datastream, filepath.Join(dir, "ds.xml.asc"), filepath.Join(dir, "key.asc")))
+	require.NoError(t, VerifySignature(datastream, filepath.Join(dir, "ds.xml.sig"), filepath.Join(dir, "key.asc")))
+
+	err = VerifySignature(datastream, filepath.Join(dir, "ds.xml.asc"), filepath.Join(dir, "other.asc"))
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+
+	// tampered content
+	require.NoError(t, os.WriteFile(datastream, []byte("<ds tampered=\"true\"/>\n"), 0600))
+	err = VerifySignature(datastream, filepath.Join(dir, "ds.xml.asc"), filepath.Join(dir, "key.asc"))
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+
+	err = VerifySignature(datastream, filepath.Join(dir, "absent.asc"), filepath.Join(dir, "key.asc"))
+	require.ErrorContains(t, err, "failed to read signature")
+	err = VerifySignature(datastream, filepath.Join(dir, "ds.xml.asc"), datastream)
+	require.ErrorContains(t, err, "failed to read public key "+datastream)
+}
+
+func TestValidateDatastreamSignature(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.validateDatastreamSignature())
+	cfg.Content.DatastreamKey = "key.asc"
+	require.EqualError(t, cfg.validateDatastreamSignature(), "the datastreamsignature and datastreamkey options must be set together")
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// targetNamesFile is the content of a target names file, mapping the targets
+// reported in the ARF, such as short hostnames, to their canonical names in
+// the inventory.
+type targetNamesFile struct {
+	Targets map[string]string `json:"targets"`
+}
+
+// ReadTargetNames reads the target names file at path and returns the
+// canonical names by target.
+func ReadTargetNames(path string) (map[string]string, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target names: %w", err)
+	}
+	var file targetNamesFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode target names %s: %w", path, err)
+	}
+	if file.Targets == nil {
+		return nil, fmt.Errorf("invalid target names in %s: missing targets", path)
+	}
+	for target, name := range file.Targets {
+		if target == "" {
+			return nil, fmt.Errorf("invalid target names in %s: empty target", path)
+		}
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("target %s has an empty name", target)
+		}
+	}
+	return file.Targets, nil
+}
+
+// TargetNames returns the canonical names by target of the target names file
+// set in the configuration, or nil when there is none.
+func (c *Config) TargetNames() (map[string]string, error) {
+	if c.Results.TargetNames == "" {
+		return nil, nil
+	}
+	return ReadTargetNames(c.Results.TargetNames)
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        map[string]string
+		expectError string
+	}{
+		{
+			name:    "Valid/Names",
+			content: `{"targets": {"web1": "web1.example.com", "db": "db-primary.example.com"}}`,
+			want: map[string]string{
+				"web1": "web1.example.com",
+				"db":   "db-primary.example.com",
+			},
+		},
+		{
+			name:        "Invalid/MissingTargets",
+			content:     `{"names": {"web1": "web1.example.com"}}`,
+			expectError: "missing targets",
+		},
+		{
+			name:        "Invalid/EmptyTarget",
+			content:     `{"targets": {"": "web1.example.com"}}`,
+			expectError: "empty target",
+		},
+		{
+			name:        "Invalid/EmptyName",
+			content:     `{"targets": {"web1": " "}}`,
+			expectError: "target web1 has an empty name",
+		},
+		{
+			name:        "Invalid/JSON",
+			content:     `{"targets": `,
+			expectError: "failed to decode target names",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "targets.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			names, err := ReadTargetNames(path)
+			if tt.expectError != "" {
+				require.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, names)
+		})
+	}
+}
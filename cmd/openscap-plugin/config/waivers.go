@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// waiverDateFormat is the format of the expiry date of a waiver.
+const waiverDateFormat = "2006-01-02"
+
+// Waiver accepts the failure of a rule, until it expires.
+type Waiver struct {
+	// Rule is the id of the waived rule, as used in the policy.
+	Rule string `json:"rule"`
+	// Expires is the last day, as YYYY-MM-DD, the waiver applies. The
+	// waiver does not expire when it is empty.
+	Expires       string `json:"expires,omitempty"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// Expired reports whether the waiver no longer applies at the given time.
+// A waiver applies until the end of its expiry date, in UTC.
+func (w Waiver) Expired(now time.Time) bool {
+	if w.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse(waiverDateFormat, w.Expires)
+	if err != nil {
+		return true
+	}
+	return !now.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// waiversFile is the content of a waivers file.
+type waiversFile struct {
+	Waivers []Waiver `json:"waivers"`
+}
+
+// ReadWaivers reads the waivers file at path and returns the waivers by rule
+// id.
+func ReadWaivers(path string) (map[string]Waiver, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read waivers: %w", err)
+	}
+	var file waiversFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode waivers %s: %w", path, err)
+	}
+	waivers := make(map[string]Waiver, len(file.Waivers))
+	for _, waiver := range file.Waivers {
+		if _, err := SanitizeInput(waiver.Rule); err != nil {
+			return nil, fmt.Errorf("invalid waiver rule: %w", err)
+		}
+		if _, ok := waivers[waiver.Rule]; ok {
+			return nil, fmt.Errorf("rule %s is waived several times", waiver.Rule)
+		}
+		if waiver.Expires != "" {
+			if _, err := time.Parse(waiverDateFormat, waiver.Expires); err != nil {
+				return nil, fmt.Errorf("invalid expiry date %q of the waiver of rule %s: must be YYYY-MM-DD", waiver.Expires, waiver.Rule)
+			}
+		}
+		waivers[waiver.Rule] = waiver
+	}
+	return waivers, nil
+}
+
+// Waivers returns the waivers of the waivers file set in the configuration,
+// or nil when there is none.
+func (c *Config) Waivers() (map[string]Waiver, error) {
+	if c.Results.Waivers == "" {
+		return nil, nil
+	}
+	return ReadWaivers(c.Results.Waivers)
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWaivers(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        map[string]Waiver
+		expectError string
+	}{
+		{
+			name: "Valid/Waivers",
+			content: `{"waivers": [
+				{"rule": "package_aide_installed", "expires": "2026-12-31", "justification": "AIDE is replaced by a central agent"},
+				{"rule": "configure_crypto_policy"}
+			]}`,
+			want: map[string]Waiver{
+				"package_aide_installed":  {Rule: "package_aide_installed", Expires: "2026-12-31", Justification: "AIDE is replaced by a central agent"},
+				"configure_crypto_policy": {Rule: "configure_crypto_policy"},
+			},
+		},
+		{
+			name:        "Invalid/Rule",
+			content:     `{"waivers": [{"rule": "package aide"}]}`,
+			expectError: "invalid waiver rule: input contains unexpected characters: package aide",
+		},
+		{
+			name:        "Invalid/Duplicate",
+			content:     `{"waivers": [{"rule": "package_aide_installed"}, {"rule": "package_aide_installed"}]}`,
+			expectError: "rule package_aide_installed is waived several times",
+		},
+		{
+			name:        "Invalid/Expires",
+			content:     `{"waivers": [{"rule": "package_aide_installed", "expires": "31/12/2026"}]}`,
+			expectError: "invalid expiry date \"31/12/2026\" of the waiver of rule package_aide_installed: must be YYYY-MM-DD",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "waivers.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			waivers, err := ReadWaivers(path)
+			if tt.expectError != "" {
+				require.EqualError(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, waivers)
+		})
+	}
+}
+
+func TestWaiverExpired(t *testing.T) {
+	waiver := Waiver{Rule: "package_aide_installed", Expires: "2026-12-31"}
+	// the waiver applies until the end of its expiry date
+	require.False(t, waiver.Expired(time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)))
+	require.True(t, waiver.Expired(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.False(t, Waiver{Rule: "package_aide_installed"}.Expired(time.Now()))
+}
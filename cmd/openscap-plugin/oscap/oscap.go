@@ -3,24 +3,66 @@
 package oscap
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
 
-	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
 	"github.com/hashicorp/go-hclog"
 )
 
+// ErrNotInstalled is returned when the oscap command cannot be found.
+var ErrNotInstalled = errors.New("oscap binary not found in PATH")
+
+// LookPath returns the path of the oscap command found in PATH.
+func LookPath() (string, error) {
+	path, err := exec.LookPath("oscap")
+	if err != nil {
+		return "", fmt.Errorf("%w: install the openscap-scanner package: %w", ErrNotInstalled, err)
+	}
+	return path, nil
+}
+
 func executeCommand(command []string) ([]byte, error) {
+	return runCommand(command, nil, nil, nil)
+}
+
+// runCommand executes a command with additional environment variables, as the
+// user of credential when it is not nil and, when progress is not nil,
+// reports the progress parsed from its standard output while it runs.
+func runCommand(command []string, env []string, credential *syscall.Credential, progress ProgressFunc) ([]byte, error) {
 	cmdPath, err := exec.LookPath(command[0])
 	if err != nil {
 		return nil, fmt.Errorf("command not found: %s: %w", command[0], err)
 	}
 
-	hclog.Default().Debug("Executing command", "command", command)
+	hclog.Default().Debug("Executing command", "command", CommandLine(env, command))
 	cmd := exec.Command(cmdPath, command[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if credential != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	}
 
-	output, err := cmd.CombinedOutput()
+	var output []byte
+	if progress == nil {
+		output, err = cmd.CombinedOutput()
+	} else {
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = io.MultiWriter(&stdout, newProgressWriter(progress))
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		output = append(stdout.Bytes(), stderr.Bytes()...)
+	}
 	if err != nil {
 		if err.Error() == "exit status 1" {
 			return output, fmt.Errorf("oscap error during evaluation: %w", err)
@@ -28,13 +70,51 @@ func executeCommand(command []string) ([]byte, error) {
 			hclog.Default().Warn("at least one rule resulted in fail or unknown", "err", err)
 			return output, nil
 		} else {
-			return nil, err
+			return output, err
 		}
 	}
 	return output, nil
 }
 
-func constructScanCommand(openscapFiles map[string]string, profile string) []string {
+// safeShellArg matches the arguments that need no quoting in a command line.
+var safeShellArg = regexp.MustCompile(`^[a-zA-Z0-9@%+=:,./_-]+$`)
+
+// CommandLine returns a command and its additional environment variables as
+// a command line that can be run in a shell. The credentials of URLs are
+// redacted.
+func CommandLine(env, command []string) string {
+	args := make([]string, 0, len(env)+len(command))
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		args = append(args, name+"="+shellQuote(redactArg(value)))
+	}
+	for _, arg := range command {
+		args = append(args, shellQuote(redactArg(arg)))
+	}
+	return strings.Join(args, " ")
+}
+
+// redactArg redacts the password of an argument that is a URL.
+func redactArg(arg string) string {
+	if !strings.Contains(arg, "://") {
+		return arg
+	}
+	parsed, err := url.Parse(arg)
+	if err != nil || parsed.User == nil {
+		return arg
+	}
+	return parsed.Redacted()
+}
+
+// shellQuote quotes an argument for a POSIX shell when needed.
+func shellQuote(arg string) string {
+	if safeShellArg.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func constructScanCommand(openscapFiles map[string]string, profile string, progress bool, extraArgs []string) []string {
 	datastream := openscapFiles["datastream"]
 	tailoringFile := openscapFiles["policy"]
 	resultsFile := openscapFiles["results"]
@@ -44,23 +124,116 @@ func constructScanCommand(openscapFiles map[string]string, profile string) []str
 		"oscap",
 		"xccdf",
 		"eval",
+	}
+	if progress {
+		cmd = append(cmd, "--progress")
+	}
+	// the benchmark is selected when the datastream has several of them
+	if benchmarkID := openscapFiles["benchmark"]; benchmarkID != "" {
+		cmd = append(cmd, "--benchmark-id", benchmarkID)
+	}
+	cmd = append(cmd,
 		"--profile", profile,
 		"--results", resultsFile,
 		"--results-arf", arfFile,
 		"--tailoring-file", tailoringFile,
-		datastream,
-	}
+	)
+	// the additional options are validated by the configuration
+	cmd = append(cmd, extraArgs...)
+	cmd = append(cmd, datastream)
 
 	return cmd
 }
 
-func OscapScan(openscapFiles map[string]string, profile string) ([]byte, error) {
-	command := constructScanCommand(openscapFiles, profile)
+// constructOfflineEnv returns the environment variables switching oscap to the
+// offline evaluation of the filesystem mounted at root, as oscap-chroot does.
+func constructOfflineEnv(root string) []string {
+	if root == "" {
+		return nil
+	}
+	return []string{
+		"OSCAP_PROBE_ROOT=" + root,
+		"OSCAP_EVALUATION_TARGET=chroot://" + root,
+	}
+}
+
+// OscapScan evaluates the system with the given profile, or the filesystem
+// mounted at root when it is not empty. The NAME=value variables of env are
+// added to the environment of oscap, after the ones set for root, and the
+// extraArgs options to its command line. oscap runs as the user of
+// credential when it is not nil, which requires the plugin to run as root.
+// When progress is not nil, it is called each time oscap completes the
+// evaluation of a rule. It returns the oscap output and the command line that
+// was run.
+func OscapScan(openscapFiles map[string]string, profile, root string, env, extraArgs []string, credential *syscall.Credential, progress ProgressFunc) ([]byte, string, error) {
+	command := constructScanCommand(openscapFiles, profile, progress != nil, extraArgs)
+	env = append(constructOfflineEnv(root), env...)
+
+	output, err := runCommand(command, env, credential, progress)
+	return output, CommandLine(env, command), err
+}
+
+// defaultSSHPort is the port oscap-ssh connects to when the host has none.
+const defaultSSHPort = "22"
+
+// splitSSHHost splits a [user@]host[:port] remote host in the destination and
+// the port given to oscap-ssh.
+func splitSSHHost(host string) (string, string) {
+	destination, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// IPv6 addresses are given to ssh without brackets
+		return strings.NewReplacer("[", "", "]", "").Replace(host), defaultSSHPort
+	}
+	return destination, port
+}
+
+func constructSSHScanCommand(openscapFiles map[string]string, profile, host string) []string {
+	destination, port := splitSSHHost(host)
+	cmd := []string{
+		"oscap-ssh",
+		destination,
+		port,
+	}
+	// oscap-ssh copies the files to and from the remote host and runs
+	// the same evaluation as the local scan
+	return append(cmd, constructScanCommand(openscapFiles, profile, false, nil)[1:]...)
+}
+
+// OscapSSHScan evaluates a remote [user@]host[:port] host over SSH with the
+// given profile. The local files are copied to the host by oscap-ssh, and
+// the results are copied back to the local results and ARF files. It returns
+// the oscap output and the command line that was run.
+func OscapSSHScan(openscapFiles map[string]string, profile, host string) ([]byte, string, error) {
+	command := constructSSHScanCommand(openscapFiles, profile, host)
+
+	output, err := runCommand(command, nil, nil, nil)
+	return output, CommandLine(nil, command), err
+}
+
+func constructPodmanScanCommand(openscapFiles map[string]string, profile, image string, progress bool, extraArgs []string) []string {
+	cmd := []string{
+		"oscap-podman",
+		image,
+	}
+	// oscap-podman mounts the image and runs the same evaluation as the
+	// local scan on its filesystem
+	return append(cmd, constructScanCommand(openscapFiles, profile, progress, extraArgs)[1:]...)
+}
 
-	return executeCommand(command)
+// OscapPodmanScan evaluates a container image with the given profile. The
+// image is mounted by oscap-podman, which needs to run as root. The NAME=value
+// variables of env are added to the environment of oscap and the extraArgs
+// options to its command line. When progress is not nil, it is called each
+// time oscap completes the evaluation of a rule. It returns the oscap output
+// and the command line that was run.
+func OscapPodmanScan(openscapFiles map[string]string, profile, image string, env, extraArgs []string, progress ProgressFunc) ([]byte, string, error) {
+	command := constructPodmanScanCommand(openscapFiles, profile, image, progress != nil, extraArgs)
+
+	output, err := runCommand(command, env, nil, progress)
+	return output, CommandLine(env, command), err
 }
 
-func constructGenerateFixCommand(fixType, output, profile, tailoringFile, datastream string) []string {
+func constructGenerateFixCommand(fixType, output, profile, tailoringFile, datastream, benchmarkID string) []string {
 
 	cmd := []string{
 		"oscap",
@@ -69,29 +242,70 @@ func constructGenerateFixCommand(fixType, output, profile, tailoringFile, datast
 		"fix",
 		"--fix-type", fixType,
 		"--output", output,
+	}
+	if benchmarkID != "" {
+		cmd = append(cmd, "--benchmark-id", benchmarkID)
+	}
+	cmd = append(cmd,
 		"--profile", profile,
 		"--tailoring-file", tailoringFile,
 		datastream,
-	}
+	)
 	return cmd
 }
 
-func OscapGenerateFix(pluginDir, profile, policyFile, datastream string) error {
+func constructGenerateReportCommand(output, arfFile string) []string {
+	return []string{
+		"oscap",
+		"xccdf",
+		"generate",
+		"report",
+		"--output", output,
+		arfFile,
+	}
+}
+
+// OscapGenerateReport generates the HTML report of the results in an ARF file.
+func OscapGenerateReport(arfFile, output string) error {
+	_, err := executeCommand(constructGenerateReportCommand(output, arfFile))
+	if err != nil {
+		return fmt.Errorf("failed to generate HTML report: %w", err)
+	}
+	return nil
+}
+
+// GeneratedFile is a file generated by oscap.
+type GeneratedFile struct {
+	Path string
+	// CommandLine is the command line that generated the file.
+	CommandLine string
+}
+
+// OscapGenerateFix generates remediation files for all fix types supported by
+// the given oscap version in remediationDir from the benchmark of the
+// datastream, or its only benchmark when benchmarkID is empty, and returns the
+// generated files by fix type.
+func OscapGenerateFix(version Version, remediationDir, profile, policyFile, datastream, benchmarkID string) (map[string]GeneratedFile, error) {
 	fixTypes := map[string]string{
 		"bash":      "remediation-script.sh",
 		"ansible":   "remediation-playbook.yml",
 		"blueprint": "remediation-blueprint.toml",
 	}
 
+	generatedFiles := make(map[string]GeneratedFile)
 	for fixType, outputFile := range fixTypes {
-		outputPath := filepath.Join(pluginDir, config.RemediationDir, outputFile)
+		if !version.SupportsFixType(fixType) {
+			hclog.Default().Warn("Fix type is not supported by oscap, skipping", "fixType", fixType, "version", version.String())
+			continue
+		}
+		outputPath := filepath.Join(remediationDir, outputFile)
 		hclog.Default().Debug("Generating remedation file %s", outputPath)
-		command := constructGenerateFixCommand(fixType, outputPath, profile, policyFile, datastream)
+		command := constructGenerateFixCommand(fixType, outputPath, profile, policyFile, datastream, benchmarkID)
 		_, err := executeCommand(command)
 		if err != nil {
-			return err
+			return generatedFiles, err
 		}
-
+		generatedFiles[fixType] = GeneratedFile{Path: outputPath, CommandLine: CommandLine(nil, command)}
 	}
-	return nil
+	return generatedFiles, nil
 }
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package oscap
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// Progress is the evaluation progress of a scan, reported each time oscap
+// completes the evaluation of a rule.
+type Progress struct {
+	// Completed is the number of rules evaluated so far.
+	Completed int
+	RuleID    string
+	Result    string
+	// Duration is the time taken to evaluate the rule, measured from the
+	// evaluation of the previous rule, or from the start of the scan for
+	// the first rule.
+	Duration time.Duration
+}
+
+// ProgressFunc is called with the progress of a scan. A nil ProgressFunc
+// disables progress reporting.
+type ProgressFunc func(Progress)
+
+// progressWriter parses the output of "oscap xccdf eval --progress", where
+// each evaluated rule is reported in a "<rule id>:<result>" line, and calls
+// the progress function for every rule.
+type progressWriter struct {
+	progress  ProgressFunc
+	completed int
+	pending   []byte
+	now       func() time.Time
+	// last is the time the previous rule was reported.
+	last time.Time
+}
+
+func newProgressWriter(progress ProgressFunc) *progressWriter {
+	return &progressWriter{progress: progress, now: time.Now, last: time.Now()}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.parseLine(string(w.pending[:i]))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *progressWriter) parseLine(line string) {
+	line = strings.TrimSpace(line)
+	ruleID, result, ok := strings.Cut(line, ":")
+	// other output, such as warnings, is not a "<rule id>:<result>" pair
+	if !ok || ruleID == "" || result == "" || strings.ContainsAny(line, " \t") {
+		return
+	}
+	w.completed++
+	now := w.now()
+	duration := now.Sub(w.last)
+	w.last = now
+	w.progress(Progress{Completed: w.completed, RuleID: ruleID, Result: result, Duration: duration})
+}
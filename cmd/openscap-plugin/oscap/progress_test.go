@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package oscap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressWriter(t *testing.T) {
+	var reported []Progress
+	writer := newProgressWriter(func(progress Progress) {
+		reported = append(reported, progress)
+	})
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	writer.last = start
+	writer.now = func() time.Time {
+		clock = clock.Add(2 * time.Second)
+		return clock
+	}
+
+	// lines may be split across writes
+	chunks := []string{
+		"xccdf_org.ssgproject.content_rule_package_aide_installed:pass\nxccdf_org.ssg",
+		"project.content_rule_aide_build_database:fail\n",
+		"WARNING: Datastream component has an invalid checksum\n",
+		"xccdf_org.ssgproject.content_rule_configure_crypto_policy:notapplicable",
+	}
+	for _, chunk := range chunks {
+		n, err := writer.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+
+	require.Equal(t, []Progress{
+		{Completed: 1, RuleID: "xccdf_org.ssgproject.content_rule_package_aide_installed", Result: "pass", Duration: 2 * time.Second},
+		{Completed: 2, RuleID: "xccdf_org.ssgproject.content_rule_aide_build_database", Result: "fail", Duration: 2 * time.Second},
+	}, reported)
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package oscap
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// ErrSchemaValidation is returned when a file does not conform to its schema.
+var ErrSchemaValidation = errors.New("schema validation failed")
+
+// SchemaViolation is a violation of the schema reported by oscap.
+type SchemaViolation struct {
+	// Line is the line of the violation in the file.
+	Line    int
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("line %d: %s", v.Line, v.Message)
+}
+
+// schemaViolationLine matches the violations reported by oscap, such as
+// "File '/tmp/tailoring.xml' line 12: Element '...': This element is not expected.".
+var schemaViolationLine = regexp.MustCompile(`^File '.*' line (\d+): (.+)$`)
+
+// parseSchemaViolations returns the violations reported in the output of
+// oscap validate.
+func parseSchemaViolations(output string) []SchemaViolation {
+	var violations []SchemaViolation
+	for _, line := range strings.Split(output, "\n") {
+		if match := schemaViolationLine.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			lineNumber, _ := strconv.Atoi(match[1])
+			violations = append(violations, SchemaViolation{Line: lineNumber, Message: match[2]})
+		}
+	}
+	return violations
+}
+
+func constructValidateCommand(file string) []string {
+	return []string{
+		"oscap",
+		"xccdf",
+		"validate",
+		file,
+	}
+}
+
+// OscapValidate validates an XCCDF file, such as a tailoring file, against
+// the XCCDF schema bundled with oscap. A file that does not conform to the
+// schema is reported with an error wrapping ErrSchemaValidation and listing
+// the violations.
+func OscapValidate(file string) error {
+	command := constructValidateCommand(file)
+	cmdPath, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("command not found: %s: %w", command[0], err)
+	}
+	hclog.Default().Debug("Executing command", "command", CommandLine(nil, command))
+	// oscap exits with 2 when the file is invalid, which runCommand treats
+	// as a success for the evaluations
+	output, err := exec.Command(cmdPath, command[1:]...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	violations := parseSchemaViolations(string(output))
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 || len(violations) == 0 {
+		return fmt.Errorf("failed to validate %s: %w: %s", file, err, strings.TrimSpace(string(output)))
+	}
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	return fmt.Errorf("%w: %s: %s", ErrSchemaValidation, file, strings.Join(messages, "; "))
+}
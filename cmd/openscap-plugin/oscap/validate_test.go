@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package oscap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const validateOutput = `File '/tmp/tailoring.xml' line 5: Element '{http://checklists.nist.gov/xccdf/1.2}select': The attribute 'selected' is required but missing.
+File '/tmp/tailoring.xml' line 9: Element '{http://checklists.nist.gov/xccdf/1.2}title': This element is not expected.
+`
+
+func TestParseSchemaViolations(t *testing.T) {
+	require.Equal(t, []SchemaViolation{
+		{Line: 5, Message: "Element '{http://checklists.nist.gov/xccdf/1.2}select': The attribute 'selected' is required but missing."},
+		{Line: 9, Message: "Element '{http://checklists.nist.gov/xccdf/1.2}title': This element is not expected."},
+	}, parseSchemaViolations(validateOutput))
+	require.Empty(t, parseSchemaViolations("OpenSCAP Error: Unable to open file\n"))
+}
+
+func TestOscapValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+		invalid bool
+	}{
+		{name: "Valid", script: "exit 0"},
+		{
+			name:    "Invalid",
+			script:  "echo \"" + strings.TrimSpace(validateOutput) + "\"\nexit 2",
+			wantErr: "schema validation failed: tailoring.xml: line 5: Element '{http://checklists.nist.gov/xccdf/1.2}select': The attribute 'selected' is required but missing.; line 9:",
+			invalid: true,
+		},
+		{name: "Error", script: "echo 'OpenSCAP Error: Unable to open file' >&2\nexit 1", wantErr: "failed to validate tailoring.xml: exit status 1: OpenSCAP Error: Unable to open file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(binDir, "oscap"), []byte("#!/bin/sh\n"+tt.script+"\n"), 0700))
+			t.Setenv("PATH", binDir)
+
+			err := OscapValidate("tailoring.xml")
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Equal(t, tt.invalid, errors.Is(err, ErrSchemaValidation))
+		})
+	}
+}
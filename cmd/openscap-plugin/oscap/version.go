@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package oscap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// versionRegex captures the version reported by "oscap --version".
+var versionRegex = regexp.MustCompile(`\(oscap\)\s+(\d+)\.(\d+)\.(\d+)`)
+
+// Version is a version of the oscap command.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+var (
+	// MinimumVersion is the oldest oscap version supported by the plugin.
+	MinimumVersion = Version{Major: 1, Minor: 3, Patch: 0}
+	// blueprintFixVersion is the first oscap version able to generate
+	// Image Builder blueprint remediations.
+	blueprintFixVersion = Version{Major: 1, Minor: 3, Patch: 5}
+)
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is equal to or newer than other.
+func (v Version) AtLeast(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+// SupportsFixType reports whether this oscap version can generate
+// remediations of the given fix type.
+func (v Version) SupportsFixType(fixType string) bool {
+	if fixType == "blueprint" {
+		return v.AtLeast(blueprintFixVersion)
+	}
+	return true
+}
+
+// ParseVersion extracts the oscap version from the output of "oscap --version".
+func ParseVersion(output string) (Version, error) {
+	matches := versionRegex.FindStringSubmatch(output)
+	if matches == nil {
+		return Version{}, fmt.Errorf("unable to find the oscap version in %q", output)
+	}
+	var parts [3]int
+	for i, match := range matches[1:] {
+		part, err := strconv.Atoi(match)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid oscap version %q: %w", matches[0], err)
+		}
+		parts[i] = part
+	}
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2]}, nil
+}
+
+// DetectVersion returns the version of the installed oscap command.
+func DetectVersion() (Version, error) {
+	output, err := executeCommand([]string{"oscap", "--version"})
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to detect oscap version: %w", err)
+	}
+	return ParseVersion(string(output))
+}
+
+// CheckVersion detects the installed oscap version and verifies it is at
+// least the minimum version.
+func CheckVersion(minimum Version) (Version, error) {
+	version, err := DetectVersion()
+	if err != nil {
+		return Version{}, err
+	}
+	if !version.AtLeast(minimum) {
+		return version, fmt.Errorf("requires oscap >= %s, found %s", minimum, version)
+	}
+	return version, nil
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package oscap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const versionOutput = `OpenSCAP command line tool (oscap) 1.3.10
+Copyright 2009--2023 Red Hat Inc., Durham, North Carolina.
+
+==== Supported specifications ====
+SCAP Version: 1.3
+XCCDF Version: 1.2
+`
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion(versionOutput)
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 1, Minor: 3, Patch: 10}, version)
+	require.Equal(t, "1.3.10", version.String())
+
+	_, err = ParseVersion("command not found")
+	require.EqualError(t, err, "unable to find the oscap version in \"command not found\"")
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		name    string
+		version Version
+		other   Version
+		want    bool
+	}{
+		{name: "Equal", version: Version{1, 3, 0}, other: Version{1, 3, 0}, want: true},
+		{name: "NewerPatch", version: Version{1, 3, 10}, other: Version{1, 3, 5}, want: true},
+		{name: "OlderMinor", version: Version{1, 2, 17}, other: Version{1, 3, 0}, want: false},
+		{name: "NewerMajor", version: Version{2, 0, 0}, other: Version{1, 3, 5}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.version.AtLeast(tt.other))
+		})
+	}
+}
+
+func TestVersionSupportsFixType(t *testing.T) {
+	require.True(t, Version{1, 3, 5}.SupportsFixType("blueprint"))
+	require.False(t, Version{1, 3, 4}.SupportsFixType("blueprint"))
+	require.True(t, Version{1, 3, 0}.SupportsFixType("ansible"))
+}
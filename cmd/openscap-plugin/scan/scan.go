@@ -3,14 +3,34 @@
 package scan
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/artifacts"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/oscap"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
+// ErrPlatformMismatch is returned when the system is not a platform of the
+// profile and the platform check is set to fail.
+var ErrPlatformMismatch = errors.New("system is not a platform of the profile")
+
 func validateOpenSCAPFiles(cfg *config.Config) (map[string]string, error) {
 	if _, err := os.Stat(cfg.Files.Policy); err != nil {
 		return nil, err
@@ -26,24 +46,395 @@ func validateOpenSCAPFiles(cfg *config.Config) (map[string]string, error) {
 		"policy":     cfg.Files.Policy,
 		"results":    cfg.Files.Results,
 		"arf":        cfg.Files.ARF,
+		"benchmark":  cfg.Content.BenchmarkID,
 	}, nil
 }
 
-func ScanSystem(cfg *config.Config, profile string) ([]byte, error) {
+// verifyTailoring checks that the tailoring file is the one recorded in the
+// artifacts manifest by the generate command, unless the check is disabled.
+// Tailoring files generated before the manifest existed are not verified.
+func verifyTailoring(cfg *config.Config) error {
+	if cfg.Scan.SkipTailoringCheck {
+		return nil
+	}
+	manifest, err := artifacts.ReadManifest(artifacts.ManifestPath(cfg.Files.Workspace))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		hclog.Default().Warn("No artifacts manifest in the workspace, the tailoring file is not verified")
+		return nil
+	case err != nil:
+		return err
+	}
+	recorded, err := manifest.Verify(cfg.Files.Policy)
+	if err != nil {
+		return fmt.Errorf("unexpected tailoring file, run the generate command again or set skiptailoringcheck: %w", err)
+	}
+	if !recorded {
+		hclog.Default().Warn("Tailoring file not recorded in the artifacts manifest, it is not verified", "policy", cfg.Files.Policy)
+	}
+	return nil
+}
+
+// ErrPrivileges is returned when the scan would not run with the privileges
+// needed by the profile.
+var ErrPrivileges = errors.New("unexpected scan privileges")
+
+// geteuid returns the effective user id of the plugin, replaced in tests.
+var geteuid = os.Geteuid
+
+// checkPrivileges checks the privileges the scan runs with against the
+// privileges needed by the profile, and returns the credential of the scan
+// user oscap runs as, or nil when oscap runs as the plugin. A plugin running
+// as root drops its privileges to the scan user, while a plugin running as
+// another user can only run oscap as itself.
+func checkPrivileges(cfg *config.Config) (*syscall.Credential, error) {
+	euid := geteuid()
+	credential, err := cfg.ScanCredential()
+	if err != nil {
+		return nil, err
+	}
+	if credential != nil && euid != 0 {
+		if credential.Uid != uint32(euid) {
+			return nil, fmt.Errorf("%w: running oscap as scan user %s requires the plugin to run as root", ErrPrivileges, cfg.Scan.User)
+		}
+		credential = nil
+	}
+	switch {
+	case cfg.Scan.Privileges == config.PrivilegesRoot && euid != 0:
+		return nil, fmt.Errorf("%w: the profile needs root privileges but the plugin runs as user %d", ErrPrivileges, euid)
+	case cfg.Scan.Privileges == config.PrivilegesUnprivileged && euid == 0 && credential == nil:
+		return nil, fmt.Errorf("%w: the profile does not need root privileges, set scanuser to drop them", ErrPrivileges)
+	case cfg.Scan.Privileges == "" && euid == 0 && credential == nil:
+		hclog.Default().Warn("The scan runs as root, set privileges to unprivileged and scanuser when the profile does not need root privileges")
+	}
+	if credential != nil {
+		hclog.Default().Info("Dropping the privileges of the scan", "user", cfg.Scan.User, "uid", credential.Uid)
+	}
+	return credential, nil
+}
+
+// stageScanUserFiles stages the files oscap reads and writes as the scan user
+// in a private directory owned by the user, as the directories of the
+// workspace are only accessible to the plugin: a copy of the tailoring file,
+// so the tailoring evaluated by later scans cannot be changed by the user,
+// and the results and ARF files. The paths of openscapFiles are changed to
+// the staged files. The returned function copies the results and ARF files
+// written by oscap to their configured paths, owned by the plugin, and
+// removes the directory.
+func stageScanUserFiles(cfg *config.Config, openscapFiles map[string]string, credential *syscall.Credential) (func() error, error) {
+	dir, err := os.MkdirTemp("", "complytime-scan-")
+	if err != nil {
+		return nil, err
+	}
+	uid, gid := int(credential.Uid), int(credential.Gid)
+	stagedPolicy := filepath.Join(dir, filepath.Base(cfg.Files.Policy))
+	err = os.Chown(dir, uid, gid)
+	if err == nil {
+		err = copyFile(cfg.Files.Policy, stagedPolicy)
+	}
+	if err == nil {
+		err = os.Chown(stagedPolicy, uid, gid)
+	}
+	if err != nil {
+		return nil, errors.Join(err, os.RemoveAll(dir))
+	}
+	openscapFiles["policy"] = stagedPolicy
+	openscapFiles["results"] = filepath.Join(dir, "results.xml")
+	openscapFiles["arf"] = filepath.Join(dir, "arf.xml")
+
+	return func() error {
+		var errs []error
+		for staged, path := range map[string]string{openscapFiles["results"]: cfg.Files.Results, openscapFiles["arf"]: cfg.Files.ARF} {
+			// oscap may have failed before writing the file
+			if err := copyFile(staged, path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+		errs = append(errs, os.RemoveAll(dir))
+		return errors.Join(errs...)
+	}, nil
+}
+
+// copyFile copies the content of the file at src to a file at dst, only
+// accessible to the plugin.
+func copyFile(src, dst string) error {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}
+
+// platformMatches reports whether a system CPE name is an instance of a
+// platform CPE name, such as cpe:/o:redhat:enterprise_linux:10::baseos of
+// cpe:/o:redhat:enterprise_linux:10. Components missing or empty in the
+// platform match any value, and a platform version matches its minor
+// versions.
+func platformMatches(systemCPE, platform string) bool {
+	systemParts := strings.Split(systemCPE, ":")
+	for i, platformPart := range strings.Split(platform, ":") {
+		if platformPart == "" {
+			continue
+		}
+		if i >= len(systemParts) {
+			return false
+		}
+		systemPart := systemParts[i]
+		if !strings.EqualFold(systemPart, platformPart) && !strings.HasPrefix(systemPart, platformPart+".") {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPlatform compares the platform of the evaluated system with the
+// platforms the profile applies to, so a profile evaluated on another system
+// is reported before a scan where most rules are not applicable. The check is
+// skipped when either platform cannot be determined.
+func checkPlatform(cfg *config.Config, profile string) error {
+	// the platform of an image is not the one of the system
+	if cfg.Scan.PlatformCheck == config.PlatformCheckSkip || cfg.Scan.Image != "" {
+		return nil
+	}
+	systemCPE, err := config.SystemCPE(cfg.Scan.Root)
+	if err != nil || systemCPE == "" {
+		hclog.Default().Warn("Could not detect the platform of the system, the platforms of the profile are not checked", "err", err)
+		return nil
+	}
+	platforms, err := xccdf.GetDsPlatforms(profile, cfg.Files.Datastream)
+	if err != nil {
+		hclog.Default().Warn("Could not read the platforms of the profile, they are not checked", "err", err)
+		return nil
+	}
+	if len(platforms) == 0 {
+		return nil
+	}
+	for _, platform := range platforms {
+		if platformMatches(systemCPE, platform) {
+			hclog.Default().Debug("The system is a platform of the profile", "system", systemCPE, "platform", platform)
+			return nil
+		}
+	}
+	if cfg.Scan.PlatformCheck == config.PlatformCheckFail {
+		return fmt.Errorf("%w: %s is not one of %s in %s", ErrPlatformMismatch, systemCPE, strings.Join(platforms, ", "), cfg.Files.Datastream)
+	}
+	hclog.Default().Warn("The system is not a platform of the profile, most rules may not be applicable", "system", systemCPE,
+		"platforms", platforms, "datastream", cfg.Files.Datastream)
+	return nil
+}
+
+// ScanSystem evaluates the system with the tailoring profile generated for the
+// given profile. The filesystem mounted at the configured root, or the
+// configured container image, is evaluated instead of the live system when
+// set. The privileges of the plugin are checked against the ones needed by
+// the profile and dropped to the scan user when set. The optional progress function
+// is called each time a rule is evaluated. It returns the oscap output and
+// the command line that was run.
+func ScanSystem(cfg *config.Config, profile string, progress oscap.ProgressFunc) ([]byte, string, error) {
 	openscapFiles, err := validateOpenSCAPFiles(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("invalid openscap files: %w", err)
+		return nil, "", fmt.Errorf("invalid openscap files: %w", err)
+	}
+	if err := verifyTailoring(cfg); err != nil {
+		return nil, "", err
+	}
+	// the tailoring profile applies to the platforms of the profile it extends
+	if err := checkPlatform(cfg, cfg.BaseProfile()); err != nil {
+		return nil, "", err
+	}
+
+	if err := xccdf.VerifyTailoringProfile(cfg.Files.Policy, profile); err != nil {
+		return nil, "", err
+	}
+	credential, err := checkPrivileges(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	var finish func() error
+	if credential != nil {
+		finish, err = stageScanUserFiles(cfg, openscapFiles, credential)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to stage the scan files of the scan user: %w", err)
+		}
 	}
 
 	tailoringProfile := fmt.Sprintf("%s_%s", profile, xccdf.XCCDFTailoringSuffix)
-	// In the future, we can add an integrity check to confirm if the expected tailoring profile
-	// id exists in the tailoring file. It is not a common case but a guardrail to prevent manual
-	// manipulation of the tailoring file would be good.
 
-	output, err := oscap.OscapScan(openscapFiles, tailoringProfile)
+	var output []byte
+	var commandLine string
+	for attempt := 1; ; attempt++ {
+		if cfg.Scan.Image != "" {
+			output, commandLine, err = oscap.OscapPodmanScan(openscapFiles, tailoringProfile, cfg.Scan.Image, cfg.ScanEnv(), cfg.ScanExtraArgs(), progress)
+		} else {
+			output, commandLine, err = oscap.OscapScan(openscapFiles, tailoringProfile, cfg.Scan.Root, cfg.ScanEnv(), cfg.ScanExtraArgs(), credential, progress)
+		}
+		if err == nil || attempt > cfg.Scan.ScanRetries || !retryable(cfg, output, err) {
+			break
+		}
+		hclog.Default().Warn("Scan failed, retrying", "retry", attempt, "retries", cfg.Scan.ScanRetries,
+			"delay", cfg.ScanRetryDelay(), "err", err)
+		time.Sleep(cfg.ScanRetryDelay())
+	}
+	if finish != nil {
+		if finishErr := finish(); finishErr != nil {
+			if err == nil {
+				return output, commandLine, fmt.Errorf("failed to collect the scan files of the scan user: %w", finishErr)
+			}
+			hclog.Default().Warn("Failed to collect the scan files of the scan user", "err", finishErr)
+		}
+	}
+	if err != nil {
+		return output, commandLine, fmt.Errorf("%w: %w", pluginerr.ErrScanFailed, err)
+	}
+	if pipe := cfg.ARFPipe(); pipe != "" {
+		if err := writeARFPipe(cfg.Files.ARF, pipe); err != nil {
+			return output, commandLine, err
+		}
+	}
+
+	return output, commandLine, nil
+}
+
+// writeARFPipe writes the ARF file of a scan to the named pipe, for the
+// process reading the pipe. The pipe is opened without blocking, so the scan
+// fails when no process reads the pipe rather than waiting for one.
+func writeARFPipe(arf, pipePath string) error {
+	pipe, err := os.OpenFile(pipePath, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return fmt.Errorf("failed to write ARF to pipe %s: no process reads the pipe", pipePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write ARF to pipe: %w", err)
+	}
+	defer pipe.Close()
+
+	arfFile, err := os.Open(filepath.Clean(arf))
+	if err != nil {
+		return err
+	}
+	defer arfFile.Close()
+	hclog.Default().Debug("Writing ARF to pipe", "arf", arf, "pipe", pipePath)
+	if _, err := io.Copy(pipe, arfFile); err != nil {
+		return fmt.Errorf("failed to write ARF to pipe %s: %w", pipePath, err)
+	}
+	return pipe.Close()
+}
+
+// retryable reports whether a failed evaluation is transient and can be
+// retried, because oscap exited with one of the configured exit codes or its
+// output matches the configured pattern. Other failures, such as a missing
+// profile, are deterministic.
+func retryable(cfg *config.Config, output []byte, err error) bool {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && slices.Contains(cfg.RetryExitCodes(), exitErr.ExitCode()) {
+		return true
+	}
+	pattern := cfg.RetryPattern()
+	return pattern != nil && pattern.Match(output)
+}
+
+// DatastreamScan is the outcome of the evaluation of a datastream.
+type DatastreamScan struct {
+	Datastream string
+	// ARF is the path of the ARF file of the datastream.
+	ARF string
+	// CommandLine is the command line that evaluated the datastream.
+	CommandLine string
+}
+
+// ScanDatastreams evaluates the system with the datastream and each of the
+// additional datastreams, one after the other, with the tailoring profile
+// generated for the given profile in the tailoring file of each datastream.
+// The first failed evaluation stops the scan.
+func ScanDatastreams(cfg *config.Config, profile string, progress oscap.ProgressFunc) ([]DatastreamScan, error) {
+	dsConfigs := []*config.Config{cfg}
+	for _, datastream := range cfg.AdditionalDatastreams() {
+		dsConfigs = append(dsConfigs, cfg.ForDatastream(datastream))
+	}
+
+	var dsScans []DatastreamScan
+	for _, dsConfig := range dsConfigs {
+		_, commandLine, err := ScanSystem(dsConfig, profile, progress)
+		if err != nil {
+			return nil, fmt.Errorf("datastream %s: %w", dsConfig.Files.Datastream, err)
+		}
+		dsScans = append(dsScans, DatastreamScan{
+			Datastream:  dsConfig.Files.Datastream,
+			ARF:         dsConfig.Files.ARF,
+			CommandLine: commandLine,
+		})
+	}
+	return dsScans, nil
+}
+
+// unsafeFileChars matches the characters of a host replaced in the names of
+// its result files.
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// hostFile returns the path of the result file of a remote host, named after
+// the local result file and the host.
+func hostFile(path, host string) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), unsafeFileChars.ReplaceAllString(host, "_"), ext)
+}
+
+// HostScan is the outcome of the evaluation of a remote host.
+type HostScan struct {
+	Host string
+	// ARF is the path of the ARF file of the host, only valid when Err is nil.
+	ARF string
+	// CommandLine is the command line that evaluated the host.
+	CommandLine string
+	Err         error
+}
+
+// ScanHosts evaluates the configured remote hosts over SSH with the tailoring
+// profile generated for the given profile, at most the configured concurrency
+// at a time. Each host has its own result files, named after the configured
+// files and the host. A failed host does not stop the evaluation of the other
+// hosts, its error is returned in its HostScan instead.
+func ScanHosts(cfg *config.Config, profile string) ([]HostScan, error) {
+	openscapFiles, err := validateOpenSCAPFiles(cfg)
 	if err != nil {
-		return output, fmt.Errorf("failed during scan: %w", err)
+		return nil, fmt.Errorf("invalid openscap files: %w", err)
 	}
+	if err := verifyTailoring(cfg); err != nil {
+		return nil, err
+	}
+	if err := xccdf.VerifyTailoringProfile(cfg.Files.Policy, profile); err != nil {
+		return nil, err
+	}
+	tailoringProfile := fmt.Sprintf("%s_%s", profile, xccdf.XCCDFTailoringSuffix)
 
-	return output, nil
+	hosts := cfg.ScanHosts()
+	hostScans := make([]HostScan, len(hosts))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(cfg.Scan.Concurrency, 1))
+	for i, host := range hosts {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			hostFiles := maps.Clone(openscapFiles)
+			hostFiles["results"] = hostFile(openscapFiles["results"], host)
+			hostFiles["arf"] = hostFile(openscapFiles["arf"], host)
+			hostScans[i] = HostScan{Host: host, ARF: hostFiles["arf"]}
+			_, commandLine, err := oscap.OscapSSHScan(hostFiles, tailoringProfile, host)
+			hostScans[i].CommandLine = commandLine
+			if err != nil {
+				hostScans[i].Err = fmt.Errorf("%w on host %s: %w", pluginerr.ErrScanFailed, host, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return hostScans, nil
 }
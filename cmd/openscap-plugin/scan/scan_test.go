@@ -3,11 +3,20 @@
 package scan
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
 
+	"github.com/complytime/complyctl/cmd/openscap-plugin/artifacts"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
 func setupTestFiles() error {
@@ -21,9 +30,16 @@ func setupTestFiles() error {
 	if err := os.WriteFile("testdata/invalid.xml", []byte(`<root>`), 0600); err != nil {
 		return err
 	}
+	if err := os.WriteFile("testdata/tailoring.xml", []byte(testTailoring), 0600); err != nil {
+		return err
+	}
 	return nil
 }
 
+// testTailoring is a tailoring file with the tailoring profile of the "test"
+// profile.
+const testTailoring = `<Tailoring><Profile id="xccdf_complytime.openscapplugin_profile_test_complytime"/></Tailoring>`
+
 func teardownTestFiles() {
 	os.RemoveAll("testdata")
 }
@@ -75,5 +91,519 @@ func TestValidateOpenSCAPFiles(t *testing.T) {
 	}
 }
 
+func TestVerifyTailoring(t *testing.T) {
+	workspace := t.TempDir()
+	pluginDir := filepath.Join(workspace, config.PluginDir)
+	if err := os.MkdirAll(pluginDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	cfg := new(config.Config)
+	cfg.Files.Workspace = workspace
+	cfg.Files.Policy = filepath.Join(pluginDir, "tailoring_policy.xml")
+	if err := os.WriteFile(cfg.Files.Policy, []byte(testTailoring), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// tailoring files are not verified without a manifest
+	if err := verifyTailoring(cfg); err != nil {
+		t.Errorf("verifyTailoring() without manifest error = %v", err)
+	}
+
+	tailoring, err := artifacts.NewArtifact(cfg.Files.Policy, artifacts.TypeTailoring, "xccdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := artifacts.Manifest{Artifacts: []artifacts.Artifact{tailoring}}
+	if err := artifacts.WriteManifest(artifacts.ManifestPath(workspace), manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTailoring(cfg); err != nil {
+		t.Errorf("verifyTailoring() error = %v", err)
+	}
+
+	if err := os.WriteFile(cfg.Files.Policy, []byte(`<Tailoring edited="true"/>`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTailoring(cfg); !errors.Is(err, artifacts.ErrArtifactModified) {
+		t.Errorf("verifyTailoring() error = %v, want %v", err, artifacts.ErrArtifactModified)
+	}
+	cfg.Scan.SkipTailoringCheck = true
+	if err := verifyTailoring(cfg); err != nil {
+		t.Errorf("verifyTailoring() with skiptailoringcheck error = %v", err)
+	}
+}
+
+func TestPlatformMatches(t *testing.T) {
+	tests := []struct {
+		systemCPE string
+		platform  string
+		want      bool
+	}{
+		{"cpe:/o:redhat:enterprise_linux:10::baseos", "cpe:/o:redhat:enterprise_linux:10", true},
+		{"cpe:/o:redhat:enterprise_linux:10.0", "cpe:/o:redhat:enterprise_linux:10", true},
+		{"cpe:/o:redhat:enterprise_linux:9::baseos", "cpe:/o:redhat:enterprise_linux:10", false},
+		{"cpe:/o:redhat:enterprise_linux:100", "cpe:/o:redhat:enterprise_linux:10", false},
+		{"cpe:/o:fedoraproject:fedora:41", "cpe:/o:redhat:enterprise_linux", false},
+		{"cpe:/o:redhat", "cpe:/o:redhat:enterprise_linux", false},
+		{"cpe:/o:redhat:enterprise_linux:9", "cpe:/o:redhat:enterprise_linux", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.systemCPE+" "+tt.platform, func(t *testing.T) {
+			if got := platformMatches(tt.systemCPE, tt.platform); got != tt.want {
+				t.Errorf("platformMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPlatform(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	cfg := new(config.Config)
+	cfg.Files.Datastream = filepath.Join("..", "..", "..", "internal", "complytime", "testdata", "openscap", "ssg-rhel-ds.xml")
+	cfg.Scan.Root = root
+	cfg.Scan.PlatformCheck = config.PlatformCheckFail
+
+	tests := []struct {
+		name      string
+		osRelease string
+		wantErr   error
+	}{
+		{name: "Match", osRelease: "ID=\"rhel\"\nCPE_NAME=\"cpe:/o:redhat:enterprise_linux:10::baseos\"\n"},
+		{name: "Mismatch", osRelease: "ID=ubuntu\nCPE_NAME=\"cpe:/o:canonical:ubuntu_linux:24.04\"\n", wantErr: ErrPlatformMismatch},
+		// the check is skipped when the platform of the system is unknown
+		{name: "Unknown", osRelease: "ID=ubuntu\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(root, "etc", "os-release"), []byte(tt.osRelease), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := checkPlatform(cfg, "test_profile"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkPlatform() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg.Scan.PlatformCheck = config.PlatformCheckWarn
+	if err := checkPlatform(cfg, "test_profile"); err != nil {
+		t.Errorf("checkPlatform() with warn error = %v", err)
+	}
+}
+
+// fakeOscapSSH is an oscap-ssh replacement writing the host to the ARF file,
+// or failing for the host named "down".
+const fakeOscapSSH = `#!/bin/sh
+[ "$1" = "down" ] && exit 1
+echo "$1:$2" > "${10}"
+`
+
+func TestScanHosts(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "oscap-ssh"), []byte(fakeOscapSSH), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	resultsDir := t.TempDir()
+	cfg := new(config.Config)
+	cfg.Files.Datastream = "testdata/valid.xml"
+	cfg.Files.Policy = "testdata/tailoring.xml"
+	cfg.Files.Results = filepath.Join(resultsDir, "results.xml")
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	cfg.Scan.Hosts = "root@rhel10:2222,down,rhel9"
+	cfg.Scan.Concurrency = 2
+
+	hostScans, err := ScanHosts(cfg, "test")
+	if err != nil {
+		t.Fatalf("ScanHosts() error = %v", err)
+	}
+	wantARFs := map[string]string{
+		"root@rhel10:2222": filepath.Join(resultsDir, "arf-root_rhel10_2222.xml"),
+		"down":             filepath.Join(resultsDir, "arf-down.xml"),
+		"rhel9":            filepath.Join(resultsDir, "arf-rhel9.xml"),
+	}
+	wantContent := map[string]string{
+		"root@rhel10:2222": "root@rhel10:2222\n",
+		"rhel9":            "rhel9:22\n",
+	}
+	if len(hostScans) != len(wantARFs) {
+		t.Fatalf("ScanHosts() = %v, want %d hosts", hostScans, len(wantARFs))
+	}
+	for _, hostScan := range hostScans {
+		if !strings.HasPrefix(hostScan.CommandLine, "oscap-ssh ") {
+			t.Errorf("ScanHosts() command line of %s = %s", hostScan.Host, hostScan.CommandLine)
+		}
+		if hostScan.ARF != wantARFs[hostScan.Host] {
+			t.Errorf("ScanHosts() ARF of %s = %s, want %s", hostScan.Host, hostScan.ARF, wantARFs[hostScan.Host])
+		}
+		if hostScan.Host == "down" {
+			if !errors.Is(hostScan.Err, pluginerr.ErrScanFailed) {
+				t.Errorf("ScanHosts() error of %s = %v, want %v", hostScan.Host, hostScan.Err, pluginerr.ErrScanFailed)
+			}
+			continue
+		}
+		if hostScan.Err != nil {
+			t.Errorf("ScanHosts() error of %s = %v", hostScan.Host, hostScan.Err)
+			continue
+		}
+		content, err := os.ReadFile(hostScan.ARF)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != wantContent[hostScan.Host] {
+			t.Errorf("ScanHosts() ARF content of %s = %q, want %q", hostScan.Host, content, wantContent[hostScan.Host])
+		}
+	}
+}
+
+// fakeOscap is an oscap replacement writing the datastream to the ARF file, or
+// failing for the datastream named "down-ds.xml".
+const fakeOscap = `#!/bin/sh
+case "${11}" in */down-ds.xml) exit 1 ;; esac
+echo "${11}" > "$8"
+`
+
+func TestScanDatastreams(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "oscap"), []byte(fakeOscap), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	policyDir := t.TempDir()
+	resultsDir := t.TempDir()
+	cfg := new(config.Config)
+	cfg.Files.Datastream = "testdata/valid.xml"
+	cfg.Files.Policy = filepath.Join(policyDir, "policy.xml")
+	cfg.Files.Results = filepath.Join(resultsDir, "results.xml")
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	cfg.Content.Datastreams = "testdata/app-ds.xml"
+	for _, policyFile := range []string{"policy.xml", "policy-app-ds.xml", "policy-down-ds.xml"} {
+		if err := os.WriteFile(filepath.Join(policyDir, policyFile), []byte(testTailoring), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dsScans, err := ScanDatastreams(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("ScanDatastreams() error = %v", err)
+	}
+	wantARFs := map[string]string{
+		"testdata/valid.xml":  filepath.Join(resultsDir, "arf.xml"),
+		"testdata/app-ds.xml": filepath.Join(resultsDir, "arf-app-ds.xml"),
+	}
+	if len(dsScans) != len(wantARFs) {
+		t.Fatalf("ScanDatastreams() = %v, want %d datastreams", dsScans, len(wantARFs))
+	}
+	for _, dsScan := range dsScans {
+		if dsScan.ARF != wantARFs[dsScan.Datastream] {
+			t.Errorf("ScanDatastreams() ARF of %s = %s, want %s", dsScan.Datastream, dsScan.ARF, wantARFs[dsScan.Datastream])
+		}
+		if !strings.HasSuffix(dsScan.CommandLine, " "+dsScan.Datastream) {
+			t.Errorf("ScanDatastreams() command line of %s = %s", dsScan.Datastream, dsScan.CommandLine)
+		}
+		content, err := os.ReadFile(dsScan.ARF)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != dsScan.Datastream+"\n" {
+			t.Errorf("ScanDatastreams() ARF content of %s = %q", dsScan.Datastream, content)
+		}
+	}
+
+	cfg.Content.Datastreams = "testdata/app-ds.xml,testdata/down-ds.xml"
+	if _, err := ScanDatastreams(cfg, "test", nil); !errors.Is(err, pluginerr.ErrScanFailed) {
+		t.Errorf("ScanDatastreams() error = %v, want %v", err, pluginerr.ErrScanFailed)
+	}
+
+	// the tailoring files were generated for the test profile
+	cfg.Content.Datastreams = ""
+	if _, err := ScanDatastreams(cfg, "cis", nil); !errors.Is(err, xccdf.ErrTailoringProfileMismatch) {
+		t.Errorf("ScanDatastreams() with another profile error = %v, want %v", err, xccdf.ErrTailoringProfileMismatch)
+	}
+}
+
+func TestCheckPrivileges(t *testing.T) {
+	tests := []struct {
+		name           string
+		euid           int
+		privileges     string
+		user           string
+		wantErr        bool
+		wantCredential bool
+	}{
+		{name: "Default/Root", euid: 0},
+		{name: "Default/User", euid: 1000},
+		{name: "Root/Root", euid: 0, privileges: config.PrivilegesRoot},
+		{name: "Root/User", euid: 1000, privileges: config.PrivilegesRoot, wantErr: true},
+		{name: "Unprivileged/Root", euid: 0, privileges: config.PrivilegesUnprivileged, wantErr: true},
+		{name: "Unprivileged/User", euid: 1000, privileges: config.PrivilegesUnprivileged},
+		{name: "Unprivileged/RootDropped", euid: 0, privileges: config.PrivilegesUnprivileged, user: "nobody", wantCredential: true},
+		{name: "ScanUser/Root", euid: 0, user: "nobody", wantCredential: true},
+		{name: "ScanUser/OtherUser", euid: 1000, user: "nobody", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geteuid = func() int { return tt.euid }
+			t.Cleanup(func() { geteuid = os.Geteuid })
+			cfg := new(config.Config)
+			cfg.Scan.Privileges = tt.privileges
+			cfg.Scan.User = tt.user
+			if tt.user != "" {
+				if _, err := cfg.ScanCredential(); err != nil {
+					t.Skipf("no %s user: %v", tt.user, err)
+				}
+			}
+
+			credential, err := checkPrivileges(cfg)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("checkPrivileges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrPrivileges) {
+				t.Errorf("checkPrivileges() error = %v, want %v", err, ErrPrivileges)
+			}
+			if tt.wantCredential != (credential != nil) {
+				t.Errorf("checkPrivileges() credential = %v, wantCredential %v", credential, tt.wantCredential)
+			}
+		})
+	}
+}
+
+func TestStageScanUserFiles(t *testing.T) {
+	resultsDir := t.TempDir()
+	cfg := new(config.Config)
+	cfg.Files.Policy = "testdata/tailoring.xml"
+	cfg.Files.Results = filepath.Join(resultsDir, "results.xml")
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	openscapFiles := map[string]string{
+		"policy":  cfg.Files.Policy,
+		"results": cfg.Files.Results,
+		"arf":     cfg.Files.ARF,
+	}
+	credential := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+
+	finish, err := stageScanUserFiles(cfg, openscapFiles, credential)
+	if err != nil {
+		t.Fatalf("stageScanUserFiles() error = %v", err)
+	}
+	// the scan user evaluates a copy of the tailoring file
+	dir := filepath.Dir(openscapFiles["policy"])
+	if dir == "testdata" {
+		t.Fatalf("stageScanUserFiles() policy = %s, want a staged copy", openscapFiles["policy"])
+	}
+	want, err := os.ReadFile(cfg.Files.Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(openscapFiles["policy"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("stageScanUserFiles() staged policy differs from %s", cfg.Files.Policy)
+	}
+
+	// oscap failed before writing the results
+	if err := os.WriteFile(openscapFiles["arf"], []byte("<arf/>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := finish(); err != nil {
+		t.Fatalf("finish() error = %v", err)
+	}
+	content, err := os.ReadFile(cfg.Files.ARF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "<arf/>" {
+		t.Errorf("finish() ARF content = %q", content)
+	}
+	if _, err := os.Stat(cfg.Files.Results); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("finish() results error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("finish() staging directory error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestWriteARFPipe(t *testing.T) {
+	tempDir := t.TempDir()
+	arf := filepath.Join(tempDir, "arf.xml")
+	if err := os.WriteFile(arf, []byte("<arf/>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pipePath := filepath.Join(tempDir, "arf.pipe")
+	if err := syscall.Mkfifo(pipePath, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// without a reader, writing fails rather than blocking
+	wantErr := "failed to write ARF to pipe " + pipePath + ": no process reads the pipe"
+	if err := writeARFPipe(arf, pipePath); err == nil || err.Error() != wantErr {
+		t.Fatalf("writeARFPipe() error = %v, want %s", err, wantErr)
+	}
+
+	reader, err := os.OpenFile(pipePath, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	received := make(chan []byte)
+	go func() {
+		content, _ := io.ReadAll(reader)
+		received <- content
+	}()
+	if err := writeARFPipe(arf, pipePath); err != nil {
+		t.Fatalf("writeARFPipe() error = %v", err)
+	}
+	if content := <-received; string(content) != "<arf/>" {
+		t.Errorf("writeARFPipe() pipe content = %q", content)
+	}
+}
+
 // ScanSystem function is not tested because it is high-level functions using other functions
 // already tested above or in other packages.
+
+// fakeOscapPodman is an oscap-podman replacement writing the image to the ARF
+// file.
+const fakeOscapPodman = `#!/bin/sh
+echo "$1" > "$9"
+`
+
+func TestScanSystemImage(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "oscap-podman"), []byte(fakeOscapPodman), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	resultsDir := t.TempDir()
+	cfg := new(config.Config)
+	cfg.Files.Datastream = "testdata/valid.xml"
+	cfg.Files.Policy = "testdata/tailoring.xml"
+	cfg.Files.Results = filepath.Join(resultsDir, "results.xml")
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	cfg.Scan.Image = "registry.access.redhat.com/ubi10/ubi:latest"
+	// the platform of the system is not checked against the image profile
+	cfg.Scan.PlatformCheck = config.PlatformCheckFail
+
+	_, commandLine, err := ScanSystem(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("ScanSystem() error = %v", err)
+	}
+	if !strings.HasPrefix(commandLine, "oscap-podman "+cfg.Scan.Image+" xccdf eval ") {
+		t.Errorf("ScanSystem() command line = %s", commandLine)
+	}
+	content, err := os.ReadFile(cfg.Files.ARF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != cfg.Scan.Image+"\n" {
+		t.Errorf("ScanSystem() ARF content = %q", content)
+	}
+}
+
+// fakeOscapEnv is an oscap replacement writing an oscap environment variable
+// to the ARF file.
+const fakeOscapEnv = `#!/bin/sh
+echo "$OSCAP_PROBE_MEMORY_USAGE_RATIO" > "$8"
+`
+
+func TestScanSystemEnv(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "oscap"), []byte(fakeOscapEnv), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	resultsDir := t.TempDir()
+	cfg := new(config.Config)
+	cfg.Files.Datastream = "testdata/valid.xml"
+	cfg.Files.Policy = "testdata/tailoring.xml"
+	cfg.Files.Results = filepath.Join(resultsDir, "results.xml")
+	cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	cfg.Scan.PlatformCheck = config.PlatformCheckSkip
+	cfg.Scan.Env = "OSCAP_PROBE_MEMORY_USAGE_RATIO=0.5"
+
+	_, commandLine, err := ScanSystem(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("ScanSystem() error = %v", err)
+	}
+	if !strings.HasPrefix(commandLine, "OSCAP_PROBE_MEMORY_USAGE_RATIO=0.5 oscap xccdf eval ") {
+		t.Errorf("ScanSystem() command line = %s", commandLine)
+	}
+	content, err := os.ReadFile(cfg.Files.ARF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "0.5\n" {
+		t.Errorf("ScanSystem() ARF content = %q, want the oscap environment variable", content)
+	}
+}
+
+func TestScanSystemRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		exitCodes    string
+		pattern      string
+		wantErr      bool
+		wantAttempts string
+	}{
+		{name: "ExitCode", retries: 2, exitCodes: "1", wantAttempts: "3"},
+		{name: "Pattern", retries: 2, pattern: "probe_icache", wantAttempts: "3"},
+		{name: "NotRetryable", retries: 2, exitCodes: "139", pattern: "missing profile", wantErr: true, wantAttempts: "1"},
+		{name: "Exhausted", retries: 1, exitCodes: "1", wantErr: true, wantAttempts: "2"},
+		{name: "NoRetries", wantErr: true, wantAttempts: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			attemptsFile := filepath.Join(binDir, "attempts")
+			// oscap fails twice with a transient probe error
+			script := fmt.Sprintf(`#!/bin/sh
+attempts=0
+[ -f %[1]s ] && read attempts < %[1]s
+attempts=$((attempts + 1))
+echo $attempts > %[1]s
+if [ $attempts -lt 3 ]; then
+	echo "E: oscap: probe_icache: transient error"
+	exit 1
+fi
+`, attemptsFile)
+			if err := os.WriteFile(filepath.Join(binDir, "oscap"), []byte(script), 0700); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", binDir)
+
+			resultsDir := t.TempDir()
+			cfg := new(config.Config)
+			cfg.Files.Datastream = "testdata/valid.xml"
+			cfg.Files.Policy = "testdata/tailoring.xml"
+			cfg.Files.Results = filepath.Join(resultsDir, "results.xml")
+			cfg.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+			cfg.Scan.PlatformCheck = config.PlatformCheckSkip
+			cfg.Scan.ScanRetries = tt.retries
+			cfg.Scan.ScanRetryDelay = "1ms"
+			cfg.Scan.RetryExitCodes = tt.exitCodes
+			cfg.Scan.RetryPattern = tt.pattern
+
+			_, _, err := ScanSystem(cfg, "test", nil)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("ScanSystem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, pluginerr.ErrScanFailed) {
+				t.Errorf("ScanSystem() error = %v, want %v", err, pluginerr.ErrScanFailed)
+			}
+			attempts, err := os.ReadFile(attemptsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(attempts)); got != tt.wantAttempts {
+				t.Errorf("ScanSystem() attempts = %s, want %s", got, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// newARFReader returns a reader of the rule results of an opened ARF file,
+// buffered with the configured size or, when enabled, reading the file mapped
+// in memory. The returned function releases the mapping and must be called
+// once the file is read. A file that cannot be mapped is read through a
+// buffer. The reader can be rewound, so the ARF can be read several times.
+func (s PluginServer) newARFReader(file *os.File) (io.ReadSeeker, func() error) {
+	if s.Config.Results.ARFMmap {
+		data, err := mmapFile(file)
+		if err == nil {
+			return bytes.NewReader(data), func() error { return munmap(data) }
+		}
+		hclog.Default().Warn("Failed to map ARF file in memory, reading it through a buffer", "arf", file.Name(), "err", err)
+	}
+	return &bufferedFile{Reader: bufio.NewReaderSize(file, s.Config.ARFBufferSize()), file: file}, func() error { return nil }
+}
+
+// bufferedFile reads a file through a buffer, which is discarded when the
+// file is sought.
+type bufferedFile struct {
+	*bufio.Reader
+	file *os.File
+}
+
+func (b *bufferedFile) Seek(offset int64, whence int) (int64, error) {
+	// the offset of the file is ahead of the reader by the buffered bytes
+	if whence == io.SeekCurrent {
+		offset -= int64(b.Buffered())
+	}
+	n, err := b.file.Seek(offset, whence)
+	b.Reset(b.file)
+	return n, err
+}
+
+// mmapFile maps the whole content of a file in memory, read-only.
+func mmapFile(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, nil
+	}
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("file %s of %d bytes is too large to be mapped", file.Name(), size)
+	}
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap releases a mapping made by mmapFile.
+func munmap(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/defenseunicorns/go-oscal/src/pkg/uuid"
+	"github.com/defenseunicorns/go-oscal/src/pkg/versioning"
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/oscal-compass/oscal-sdk-go/extensions"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+const (
+	// assessmentResultsTitle is the default title of the assessment results.
+	assessmentResultsTitle = "OpenSCAP Assessment Results"
+	// assessmentResultsVersion is the version of the assessment results document.
+	assessmentResultsVersion = "1.0.0"
+	// assessmentPlanFile is the assessment plan written by complyctl in the workspace.
+	assessmentPlanFile = "assessment-plan.json"
+)
+
+// toAssessmentResults converts the results of a scan started at the given time
+// to an OSCAL assessment results document with a single result.
+func (s PluginServer) toAssessmentResults(pvpResult policy.PVPResult, start time.Time) oscalTypes.AssessmentResults {
+	// subjects are reported once per resource id, as inventory items when
+	// they have the default type
+	subjectUUIDs := make(map[string]string)
+	var inventoryItems []oscalTypes.InventoryItem
+	observations := make([]oscalTypes.Observation, 0, len(pvpResult.ObservationsByCheck))
+	for _, observationByCheck := range pvpResult.ObservationsByCheck {
+		var subjects []oscalTypes.SubjectReference
+		for _, subject := range observationByCheck.Subjects {
+			subjectUUID, ok := subjectUUIDs[subject.ResourceID]
+			if !ok {
+				subjectUUID = uuid.NewUUID()
+				subjectUUIDs[subject.ResourceID] = subjectUUID
+				// subjects of other types are defined by the consumers
+				// of the results
+				if subject.Type == config.DefaultSubjectType {
+					inventoryItems = append(inventoryItems, oscalTypes.InventoryItem{
+						UUID:        subjectUUID,
+						Description: subject.Title,
+						Props:       &[]oscalTypes.Property{oscalProp("resource-id", subject.ResourceID)},
+					})
+				}
+			}
+			props := []oscalTypes.Property{
+				oscalProp("resource-id", subject.ResourceID),
+				oscalProp("result", subject.Result.String()),
+				oscalProp("evaluated-on", subject.EvaluatedOn.Format(time.RFC3339)),
+				oscalProp("reason", subject.Reason),
+			}
+			for _, prop := range subject.Props {
+				props = append(props, oscalProp(prop.Name, prop.Value))
+			}
+			subjects = append(subjects, oscalTypes.SubjectReference{
+				SubjectUuid: subjectUUID,
+				Title:       subject.Title,
+				Type:        subject.Type,
+				Props:       &props,
+			})
+		}
+
+		var evidences []oscalTypes.RelevantEvidence
+		for _, link := range observationByCheck.RelevantEvidences {
+			evidences = append(evidences, oscalTypes.RelevantEvidence{
+				Href:        link.Href,
+				Description: link.Description,
+			})
+		}
+
+		observation := oscalTypes.Observation{
+			UUID:        uuid.NewUUID(),
+			Title:       observationByCheck.Title,
+			Description: observationByCheck.Description,
+			Methods:     observationByCheck.Methods,
+			Collected:   observationByCheck.Collected,
+			Props:       &[]oscalTypes.Property{oscalProp(extensions.AssessmentCheckIdProp, observationByCheck.CheckID)},
+		}
+		if observation.Description == "" {
+			observation.Description = fmt.Sprintf("Evaluation of rule %s", observationByCheck.Title)
+		}
+		if len(subjects) > 0 {
+			observation.Subjects = &subjects
+		}
+		if len(evidences) > 0 {
+			observation.RelevantEvidence = &evidences
+		}
+		observations = append(observations, observation)
+	}
+
+	end := time.Now()
+	result := oscalTypes.Result{
+		UUID:        uuid.NewUUID(),
+		Title:       fmt.Sprintf("OpenSCAP scan of profile %s", s.Config.Parameters.Profile),
+		Description: fmt.Sprintf("Results of the evaluation of the %s profile by oscap", s.Config.Parameters.Profile),
+		Start:       start,
+		End:         &end,
+		ReviewedControls: oscalTypes.ReviewedControls{
+			ControlSelections: []oscalTypes.AssessedControls{{IncludeAll: &oscalTypes.IncludeAll{}}},
+		},
+		Observations: &observations,
+	}
+	if len(inventoryItems) > 0 {
+		result.LocalDefinitions = &oscalTypes.LocalDefinitions{InventoryItems: &inventoryItems}
+	}
+	if len(pvpResult.Links) > 0 {
+		links := make([]oscalTypes.Link, 0, len(pvpResult.Links))
+		for _, link := range pvpResult.Links {
+			links = append(links, oscalTypes.Link{Href: link.Href, Text: link.Description})
+		}
+		result.Links = &links
+	}
+
+	title := s.Config.Results.AssessmentTitle
+	if title == "" {
+		title = assessmentResultsTitle
+	}
+	oscalVersion := s.Config.Results.OSCALVersion
+	if oscalVersion == "" {
+		oscalVersion = versioning.GetLatestSupportedVersion()
+	}
+	return oscalTypes.AssessmentResults{
+		UUID: uuid.NewUUID(),
+		ImportAp: oscalTypes.ImportAp{
+			Href: fmt.Sprintf("file://%s", filepath.Join(s.Config.Files.Workspace, assessmentPlanFile)),
+		},
+		Metadata: oscalTypes.Metadata{
+			Title:        title,
+			Version:      assessmentResultsVersion,
+			OscalVersion: oscalVersion,
+			LastModified: end,
+		},
+		Results: []oscalTypes.Result{result},
+	}
+}
+
+// oscalProp returns an OSCAL property in the same namespace as the properties
+// set by complyctl when it reports the results.
+func oscalProp(name, value string) oscalTypes.Property {
+	return oscalTypes.Property{
+		Name:  name,
+		Value: value,
+		Ns:    extensions.TrestleNameSpace,
+	}
+}
+
+// writeAssessmentResults writes the assessment results as JSON to the path set
+// in the configuration and returns a link to it.
+func (s PluginServer) writeAssessmentResults(assessmentResults oscalTypes.AssessmentResults) (policy.Link, error) {
+	path := s.Config.Results.AssessmentResults
+	oscalModels := oscalTypes.OscalModels{
+		AssessmentResults: &assessmentResults,
+	}
+	data, err := json.MarshalIndent(oscalModels, "", "  ")
+	if err != nil {
+		return policy.Link{}, fmt.Errorf("failed to encode assessment results: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return policy.Link{}, fmt.Errorf("failed to write assessment results: %w", err)
+	}
+	return policy.Link{
+		Href:        fmt.Sprintf("file://%s", path),
+		Description: "ASSESSMENT_RESULTS",
+	}, nil
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
+	"github.com/oscal-compass/oscal-sdk-go/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAssessmentResults(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+	s := newTestServer("arf.xml")
+	s.Config.Files.Workspace = "/workspace"
+	s.Config.Parameters.Profile = "cis"
+	s.Config.Results.AssessmentTitle = "Nightly scan"
+	s.Config.Results.AssessmentResults = filepath.Join(t.TempDir(), "assessment-results.json")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+
+	link, err := s.writeAssessmentResults(s.toAssessmentResults(pvpResults, time.Now()))
+	require.NoError(t, err)
+	require.Equal(t, "file://"+s.Config.Results.AssessmentResults, link.Href)
+
+	data, err := os.ReadFile(s.Config.Results.AssessmentResults)
+	require.NoError(t, err)
+	var oscalModels oscalTypes.OscalModels
+	require.NoError(t, json.Unmarshal(data, &oscalModels))
+	require.NoError(t, validation.NewSchemaValidator().Validate(oscalModels))
+
+	assessmentResults := oscalModels.AssessmentResults
+	require.NotNil(t, assessmentResults)
+	require.Equal(t, "Nightly scan", assessmentResults.Metadata.Title)
+	require.Equal(t, "1.1.3", assessmentResults.Metadata.OscalVersion)
+	require.Equal(t, "file:///workspace/assessment-plan.json", assessmentResults.ImportAp.Href)
+	require.Len(t, assessmentResults.Results, 1)
+
+	result := assessmentResults.Results[0]
+	require.Len(t, *result.Observations, 3)
+	// all observations are about the same host
+	require.Len(t, *result.LocalDefinitions.InventoryItems, 1)
+	inventoryItem := (*result.LocalDefinitions.InventoryItems)[0]
+	for _, observation := range *result.Observations {
+		require.Len(t, *observation.Subjects, 1)
+		require.Equal(t, inventoryItem.UUID, (*observation.Subjects)[0].SubjectUuid)
+	}
+}
+
+func TestToAssessmentResultsSubjectType(t *testing.T) {
+	s := newTestServer("arf.xml")
+	s.Config.Results.SubjectType = "resource"
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database"))
+	require.NoError(t, err)
+	for _, observation := range pvpResults.ObservationsByCheck {
+		require.Equal(t, "resource", observation.Subjects[0].Type)
+	}
+
+	assessmentResults := s.toAssessmentResults(pvpResults, time.Now())
+	require.NoError(t, validation.NewSchemaValidator().Validate(oscalTypes.OscalModels{AssessmentResults: &assessmentResults}))
+	result := assessmentResults.Results[0]
+	// resources are defined by the consumers of the results
+	require.Nil(t, result.LocalDefinitions)
+	subjects := *(*result.Observations)[0].Subjects
+	require.Equal(t, "resource", subjects[0].Type)
+	require.Equal(t, subjects[0].SubjectUuid, (*(*result.Observations)[1].Subjects)[0].SubjectUuid)
+}
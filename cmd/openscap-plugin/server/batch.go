@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+)
+
+// ObservationBatchFunc receives a batch of the observations of GetResults.
+// The batch is cleared and reused once it returns, so it must not be kept.
+type ObservationBatchFunc func([]policy.ObservationByCheck) error
+
+// observationBatcher passes the observations of a scan to the batch sink by
+// batches of the configured size, while counting and summarizing them, so
+// that only a batch of observations is held in memory at a time.
+type observationBatcher struct {
+	size         int
+	sink         ObservationBatchFunc
+	durations    map[string]time.Duration
+	durationName string
+	severityName string
+	batch        []policy.ObservationByCheck
+	count        int
+	summary      resultsSummary
+}
+
+// batching returns whether the observations of GetResults are passed to the
+// batch sink rather than returned.
+func (s PluginServer) batching() bool {
+	return s.BatchSink != nil && s.BatchSize > 0
+}
+
+// validateBatching checks the configuration does not need all the
+// observations at once, as batches are passed on before all the observations
+// are read.
+func (s PluginServer) validateBatching() error {
+	switch {
+	case s.Config.Results.Deduplicate:
+		return errors.New("batched observations cannot be deduplicated")
+	case s.Config.Results.AssessmentResults != "":
+		return errors.New("batched observations cannot be written in assessmentresults")
+	case len(s.Config.ScanHosts()) > 0:
+		return errors.New("batched observations cannot be collected from hosts")
+	}
+	return nil
+}
+
+// newObservationBatcher returns a batcher passing observations to the batch
+// sink with the durations of their rules, if recorded.
+func (s PluginServer) newObservationBatcher(durations map[string]time.Duration) *observationBatcher {
+	return &observationBatcher{
+		size:         s.BatchSize,
+		sink:         s.BatchSink,
+		durations:    durations,
+		durationName: s.Config.PropertyName(durationProp),
+		severityName: s.Config.PropertyName(severityProp),
+		batch:        make([]policy.ObservationByCheck, 0, s.BatchSize),
+		summary:      newResultsSummary(s.Config.Results.FailSeverity, s.Config.Results.MaxFailures),
+	}
+}
+
+// add adds an observation to the batch, which is passed to the sink once
+// full.
+func (b *observationBatcher) add(observation policy.ObservationByCheck) error {
+	b.batch = append(b.batch, observation)
+	b.count++
+	if len(b.batch) < b.size {
+		return nil
+	}
+	return b.flush()
+}
+
+// flush passes the observations of the batch to the sink, if any, and clears
+// the batch.
+func (b *observationBatcher) flush() error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	addDurations(b.batch, b.durations, b.durationName)
+	for _, observation := range b.batch {
+		b.summary.add(observation, b.severityName)
+	}
+	err := b.sink(b.batch)
+	clear(b.batch)
+	b.batch = b.batch[:0]
+	if err != nil {
+		return fmt.Errorf("failed to pass a batch of observations: %w", err)
+	}
+	return nil
+}
+
+// summarize returns the summary of all the observations passed to the sink.
+func (b *observationBatcher) summarize() resultsSummary {
+	b.summary.finish()
+	return b.summary
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservationBatcher(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+	s := newTestServer("arf.xml")
+	s.BatchSize = 2
+	// observations are returned without a batch sink
+	require.False(t, s.batching())
+
+	var batches [][]string
+	var durations []string
+	s.BatchSink = func(batch []policy.ObservationByCheck) error {
+		var checkIDs []string
+		for _, observation := range batch {
+			checkIDs = append(checkIDs, observation.CheckID)
+			durations = append(durations, subjectProp(observation.Subjects[0], durationProp))
+		}
+		batches = append(batches, checkIDs)
+		return nil
+	}
+	require.True(t, s.batching())
+	require.NoError(t, s.validateBatching())
+	batcher := s.newObservationBatcher(map[string]time.Duration{
+		"xccdf_org.ssgproject.content_rule_package_aide_installed": 1500 * time.Millisecond,
+	})
+	require.NoError(t, s.walkObservations(oscalPolicy, s.Config.Files.ARF, s.transformed(batcher.add)))
+	// the last batch is passed once flushed
+	require.Len(t, batches, 1)
+	require.NoError(t, batcher.flush())
+	require.Equal(t, [][]string{
+		{"package_aide_installed", "aide_build_database"},
+		{"configure_crypto_policy"},
+	}, batches)
+	require.Equal(t, []string{"1.5s", "", ""}, durations)
+	require.Equal(t, 3, batcher.count)
+	require.Empty(t, batcher.batch)
+
+	summary := batcher.summarize()
+	require.Equal(t, 3, summary.Total)
+	require.Equal(t, 1, summary.Passed)
+	require.Equal(t, 2, summary.Failed)
+	require.True(t, summary.Blocking)
+
+	// batches are passed on before all the observations are read
+	s.Config.Results.Deduplicate = true
+	require.EqualError(t, s.validateBatching(), "batched observations cannot be deduplicated")
+	s.Config.Results.Deduplicate = false
+
+	// an error of the sink stops reading the results
+	s.BatchSink = func([]policy.ObservationByCheck) error {
+		return errors.New("sink closed")
+	}
+	batcher = s.newObservationBatcher(nil)
+	err := s.walkObservations(oscalPolicy, s.Config.Files.ARF, batcher.add)
+	require.EqualError(t, err, "failed to pass a batch of observations: sink closed")
+	require.Equal(t, 2, batcher.count)
+}
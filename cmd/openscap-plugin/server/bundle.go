@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/artifacts"
+)
+
+// writeEvidenceBundle packages the ARF, the results summary, the HTML report
+// and the OVAL system characteristics when enabled, and the tailoring and
+// remediation files recorded by the generate command in the evidence bundle
+// set in the configuration, and returns a link to it.
+func (s PluginServer) writeEvidenceBundle() (policy.Link, error) {
+	manifest := artifacts.Manifest{GeneratedAt: time.Now()}
+	arf, err := artifacts.NewArtifact(s.Config.Files.ARF, artifacts.TypeARF, "arf")
+	if err != nil {
+		return policy.Link{}, err
+	}
+	summary, err := artifacts.NewArtifact(filepath.Join(filepath.Dir(s.Config.Files.ARF), summaryFile), artifacts.TypeSummary, "json")
+	if err != nil {
+		return policy.Link{}, err
+	}
+	manifest.Artifacts = append(manifest.Artifacts, arf, summary)
+	if s.Config.Results.HTMLReport != "" {
+		report, err := artifacts.NewArtifact(s.Config.Results.HTMLReport, artifacts.TypeReport, "html")
+		if err != nil {
+			return policy.Link{}, err
+		}
+		manifest.Artifacts = append(manifest.Artifacts, report)
+	}
+	if s.Config.Results.SystemCharacteristics != "" {
+		// the file is not written when the ARF has no system characteristics
+		sysChar, err := artifacts.NewArtifact(s.Config.Results.SystemCharacteristics, artifacts.TypeSystemCharacteristics, "xml")
+		switch {
+		case err == nil:
+			manifest.Artifacts = append(manifest.Artifacts, sysChar)
+		case !errors.Is(err, fs.ErrNotExist):
+			return policy.Link{}, err
+		}
+	}
+
+	generated, err := artifacts.ReadManifest(artifacts.ManifestPath(s.Config.Files.Workspace))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		hclog.Default().Warn("No artifacts manifest in the workspace, the tailoring and remediation files are not bundled")
+	case err != nil:
+		return policy.Link{}, err
+	}
+	for _, artifact := range generated.Artifacts {
+		// the files are described again in case they changed since they
+		// were generated
+		artifact, err := artifacts.NewArtifact(artifact.Path, artifact.Type, artifact.Format)
+		if err != nil {
+			return policy.Link{}, err
+		}
+		manifest.Artifacts = append(manifest.Artifacts, artifact)
+	}
+
+	if err := artifacts.WriteBundle(s.Config.Results.EvidenceBundle, manifest); err != nil {
+		return policy.Link{}, err
+	}
+	return policy.Link{
+		Href:        fmt.Sprintf("file://%s", s.Config.Results.EvidenceBundle),
+		Description: "EVIDENCE_BUNDLE",
+	}, nil
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/artifacts"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+func TestWriteEvidenceBundle(t *testing.T) {
+	workspace := t.TempDir()
+	resultsDir := filepath.Join(workspace, config.PluginDir, config.ResultsDir)
+	require.NoError(t, os.MkdirAll(resultsDir, 0700))
+	s := New()
+	s.Config.Files.Workspace = workspace
+	s.Config.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	s.Config.Results.EvidenceBundle = filepath.Join(resultsDir, "evidence.tar.gz")
+	require.NoError(t, os.WriteFile(s.Config.Files.ARF, []byte("<arf/>"), 0600))
+	_, err := s.writeSummary(resultsSummary{BlockingFailures: []string{}})
+	require.NoError(t, err)
+
+	// only the scan results are bundled before files are generated
+	link, err := s.writeEvidenceBundle()
+	require.NoError(t, err)
+	require.Equal(t, "file://"+s.Config.Results.EvidenceBundle, link.Href)
+	require.Equal(t, "EVIDENCE_BUNDLE", link.Description)
+
+	remediationPath := filepath.Join(workspace, config.PluginDir, config.RemediationDir, "remediation-script.sh")
+	require.NoError(t, os.MkdirAll(filepath.Dir(remediationPath), 0700))
+	require.NoError(t, os.WriteFile(remediationPath, []byte("echo fix\n"), 0600))
+	require.NoError(t, artifacts.WriteManifest(artifacts.ManifestPath(workspace), artifacts.Manifest{
+		GeneratedAt: time.Now(),
+		Artifacts:   []artifacts.Artifact{{Path: remediationPath, Type: artifacts.TypeRemediation, Format: "bash"}},
+	}))
+	_, err = s.writeEvidenceBundle()
+	require.NoError(t, err)
+
+	// generated files that were removed since cannot be bundled
+	require.NoError(t, os.Remove(remediationPath))
+	_, err = s.writeEvidenceBundle()
+	require.ErrorContains(t, err, "failed to describe artifact "+remediationPath)
+}
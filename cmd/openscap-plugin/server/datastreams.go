@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+// collectDatastreamResults maps the rule results in the ARF of each evaluated
+// datastream to observations, with the datastream set on their subjects so
+// the results of rules found in several datastreams can be told apart.
+func (s PluginServer) collectDatastreamResults(oscalPolicy policy.Policy, dsScans []scan.DatastreamScan) (policy.PVPResult, error) {
+	var observations []policy.ObservationByCheck
+	for _, dsScan := range dsScans {
+		dsObservations, err := s.readObservations(oscalPolicy, dsScan.ARF)
+		if err != nil {
+			return policy.PVPResult{}, fmt.Errorf("datastream %s: %w", dsScan.Datastream, err)
+		}
+		for _, observation := range dsObservations {
+			observations = append(observations, s.withDatastream(observation, dsScan.Datastream))
+		}
+	}
+	return s.toPVPResult(observations), nil
+}
+
+// withDatastream sets the file name of the datastream on the subjects of the
+// observation.
+func (s PluginServer) withDatastream(observation policy.ObservationByCheck, datastream string) policy.ObservationByCheck {
+	datastreamProperty := policy.Property{
+		Name:  s.Config.PropertyName(datastreamProp),
+		Value: filepath.Base(datastream),
+	}
+	for i := range observation.Subjects {
+		observation.Subjects[i].Props = append(observation.Subjects[i].Props, datastreamProperty)
+	}
+	return observation
+}
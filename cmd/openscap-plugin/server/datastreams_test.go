@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+func TestCollectDatastreamResults(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database")
+	s := New()
+	dsScans := []scan.DatastreamScan{
+		{Datastream: "/content/ssg-rhel10-ds.xml", ARF: filepath.Join(testDataDir, "arf.xml")},
+		{Datastream: "/content/app-ds.xml", ARF: filepath.Join(testDataDir, "arf.xml")},
+	}
+	pvpResults, err := s.collectDatastreamResults(oscalPolicy, dsScans)
+	require.NoError(t, err)
+
+	datastreams := make(map[string][]string)
+	for _, observation := range pvpResults.ObservationsByCheck {
+		require.Len(t, observation.Subjects, 1)
+		datastream := subjectProp(observation.Subjects[0], datastreamProp)
+		datastreams[datastream] = append(datastreams[datastream], observation.CheckID)
+	}
+	require.Len(t, datastreams, 2)
+	require.ElementsMatch(t, []string{"package_aide_installed", "aide_build_database"}, datastreams["ssg-rhel10-ds.xml"])
+	require.ElementsMatch(t, []string{"package_aide_installed", "aide_build_database"}, datastreams["app-ds.xml"])
+
+	// an ARF that cannot be read fails the results
+	dsScans = append(dsScans, scan.DatastreamScan{Datastream: "/content/missing-ds.xml", ARF: filepath.Join(testDataDir, "arf-missing.xml")})
+	_, err = s.collectDatastreamResults(oscalPolicy, dsScans)
+	require.ErrorContains(t, err, "datastream /content/missing-ds.xml")
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+// ErrEmptyResults is returned by GetResults when the scan results have fewer
+// observations than configured and empty results are configured to fail.
+var ErrEmptyResults = errors.New("scan results have too few observations")
+
+// checkObservations reports scan results with fewer observations than the
+// configured minimum, which usually means the profile or the policy selects
+// no rule that was evaluated, according to the configured handling.
+func (s PluginServer) checkObservations(observations int) error {
+	if s.Config.Results.EmptyResults == config.EmptyResultsSkip {
+		return nil
+	}
+	minObservations := s.Config.MinObservations()
+	if observations >= minObservations {
+		return nil
+	}
+	if s.Config.Results.EmptyResults == config.EmptyResultsFail {
+		return fmt.Errorf("%w: %d observations, expected at least %d: check the profile %s and the rules of the policy",
+			ErrEmptyResults, observations, minObservations, s.Config.Parameters.Profile)
+	}
+	hclog.Default().Warn("The scan results have too few observations, check the profile and the rules of the policy",
+		"observations", observations, "min", minObservations, "profile", s.Config.Parameters.Profile)
+	return nil
+}
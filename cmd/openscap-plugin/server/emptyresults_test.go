@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+func TestCheckObservations(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+	s := newTestServer("arf.xml")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+
+	// empty results only warn by default
+	require.NoError(t, s.checkObservations(0))
+	require.NoError(t, s.checkObservations(len(pvpResults.ObservationsByCheck)))
+
+	s.Config.Results.EmptyResults = config.EmptyResultsFail
+	err = s.checkObservations(0)
+	require.ErrorIs(t, err, ErrEmptyResults)
+	require.ErrorContains(t, err, "0 observations, expected at least 1")
+	require.NoError(t, s.checkObservations(len(pvpResults.ObservationsByCheck)))
+
+	s.Config.Results.MinObservations = 4
+	require.ErrorContains(t, s.checkObservations(len(pvpResults.ObservationsByCheck)), "3 observations, expected at least 4")
+
+	s.Config.Results.EmptyResults = config.EmptyResultsSkip
+	require.NoError(t, s.checkObservations(0))
+}
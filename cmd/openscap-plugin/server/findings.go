@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defenseunicorns/go-oscal/src/pkg/uuid"
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+)
+
+// riskLevels maps the severity of a rule to the level of the risk of its
+// failure. Rules with another severity, such as unknown, are low risks.
+var riskLevels = map[string]string{
+	"high":   "high",
+	"medium": "moderate",
+	"low":    "low",
+}
+
+// ruleControls returns the controls of the configured framework the rules of
+// the evaluated datastreams are mapped to, by rule id.
+func (s PluginServer) ruleControls() (map[string][]string, error) {
+	datastreams := append([]string{s.Config.Files.Datastream}, s.Config.AdditionalDatastreams()...)
+	ruleControls := make(map[string][]string)
+	for _, datastream := range datastreams {
+		dsRuleControls, err := xccdf.RuleControls(datastream, s.Config.FindingsFramework())
+		if err != nil {
+			return nil, err
+		}
+		for ruleID, controls := range dsRuleControls {
+			if _, ok := ruleControls[ruleID]; !ok {
+				ruleControls[ruleID] = controls
+			}
+		}
+	}
+	return ruleControls, nil
+}
+
+// addFindings adds a risk per failed observation of the result, with the
+// level of the severity of its rule, and a finding per control the failed
+// rules are mapped to, relating the observations and risks of the rules. The
+// observations of the result are the observations of the PVP result, in the
+// same order. Risks of rules not mapped to any control have no finding.
+func (s PluginServer) addFindings(result *oscalTypes.Result, pvpResult policy.PVPResult, ruleControls map[string][]string) {
+	if result.Observations == nil {
+		return
+	}
+	var risks []oscalTypes.Risk
+	var findings []oscalTypes.Finding
+	findingIndexes := make(map[string]int)
+	for i, observationByCheck := range pvpResult.ObservationsByCheck {
+		var severity string
+		var failedResources, reasons []string
+		for _, subject := range observationByCheck.Subjects {
+			if subject.Result != policy.ResultFail {
+				continue
+			}
+			failedResources = append(failedResources, subject.ResourceID)
+			reasons = append(reasons, subject.Reason)
+			for _, prop := range subject.Props {
+				if prop.Name == s.Config.PropertyName(severityProp) {
+					severity = prop.Value
+				}
+			}
+		}
+		if len(failedResources) == 0 {
+			continue
+		}
+
+		ruleID := strings.TrimPrefix(observationByCheck.Title, contentRulePrefix)
+		riskLevel, ok := riskLevels[severity]
+		if !ok {
+			riskLevel = "low"
+		}
+		relatedObservations := []oscalTypes.RelatedObservation{{ObservationUuid: (*result.Observations)[i].UUID}}
+		props := []oscalTypes.Property{oscalProp("risk-level", riskLevel)}
+		if severity != "" {
+			props = append(props, oscalProp(severityProp, severity))
+		}
+		risk := oscalTypes.Risk{
+			UUID:                uuid.NewUUID(),
+			Title:               fmt.Sprintf("Failure of rule %s", ruleID),
+			Description:         fmt.Sprintf("Rule %s failed on %s", ruleID, strings.Join(failedResources, ", ")),
+			Statement:           strings.Join(reasons, "; "),
+			Status:              "open",
+			Props:               &props,
+			RelatedObservations: &relatedObservations,
+		}
+		risks = append(risks, risk)
+
+		for _, control := range ruleControls[ruleID] {
+			j, ok := findingIndexes[control]
+			if !ok {
+				j = len(findings)
+				findingIndexes[control] = j
+				findings = append(findings, oscalTypes.Finding{
+					UUID:        uuid.NewUUID(),
+					Title:       fmt.Sprintf("Control %s not satisfied", control),
+					Description: fmt.Sprintf("Rules mapped to control %s failed", control),
+					Target: oscalTypes.FindingTarget{
+						TargetId: fmt.Sprintf("%s_smt", control),
+						Type:     "statement-id",
+						Status: oscalTypes.ObjectiveStatus{
+							State: "not-satisfied",
+						},
+					},
+					RelatedObservations: &[]oscalTypes.RelatedObservation{},
+					RelatedRisks:        &[]oscalTypes.AssociatedRisk{},
+				})
+			}
+			finding := &findings[j]
+			*finding.RelatedObservations = append(*finding.RelatedObservations, relatedObservations...)
+			*finding.RelatedRisks = append(*finding.RelatedRisks, oscalTypes.AssociatedRisk{RiskUuid: risk.UUID})
+		}
+	}
+	if len(risks) > 0 {
+		result.Risks = &risks
+	}
+	if len(findings) > 0 {
+		result.Findings = &findings
+	}
+}
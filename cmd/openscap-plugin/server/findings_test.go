@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
+	"github.com/oscal-compass/oscal-sdk-go/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFindings(t *testing.T) {
+	s := newTestServer("arf.xml")
+	s.Config.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy"))
+	require.NoError(t, err)
+
+	assessmentResults := s.toAssessmentResults(pvpResults, time.Now())
+	ruleControls, err := s.ruleControls()
+	require.NoError(t, err)
+	result := &assessmentResults.Results[0]
+	s.addFindings(result, pvpResults, ruleControls)
+	require.NoError(t, validation.NewSchemaValidator().Validate(oscalTypes.OscalModels{AssessmentResults: &assessmentResults}))
+
+	// configure_crypto_policy and package_aide_installed failed
+	require.NotNil(t, result.Risks)
+	risks := *result.Risks
+	require.Len(t, risks, 2)
+	require.Equal(t, "Failure of rule configure_crypto_policy", risks[0].Title)
+	require.Equal(t, "open", risks[0].Status)
+	require.Equal(t, "high", (*risks[0].Props)[0].Value)
+	require.Equal(t, "Failure of rule package_aide_installed", risks[1].Title)
+	require.Equal(t, "moderate", (*risks[1].Props)[0].Value)
+	require.Equal(t, "Rule package_aide_installed failed on rhel10", risks[1].Description)
+	require.Equal(t, (*result.Observations)[2].UUID, (*risks[1].RelatedObservations)[0].ObservationUuid)
+
+	require.NotNil(t, result.Findings)
+	findings := make(map[string]oscalTypes.Finding)
+	for _, finding := range *result.Findings {
+		require.Equal(t, "statement-id", finding.Target.Type)
+		require.Equal(t, "not-satisfied", finding.Target.Status.State)
+		findings[finding.Target.TargetId] = finding
+	}
+	require.Contains(t, findings, "sc-13_smt")
+	require.Equal(t, []oscalTypes.AssociatedRisk{{RiskUuid: risks[0].UUID}}, *findings["sc-13_smt"].RelatedRisks)
+	// both failed rules are mapped to cm-6, as is the passing
+	// aide_build_database rule
+	require.Equal(t, []oscalTypes.AssociatedRisk{{RiskUuid: risks[0].UUID}, {RiskUuid: risks[1].UUID}}, *findings["cm-6_smt"].RelatedRisks)
+	require.Equal(t, []oscalTypes.RelatedObservation{
+		{ObservationUuid: (*result.Observations)[1].UUID},
+		{ObservationUuid: (*result.Observations)[2].UUID},
+	}, *findings["cm-6_smt"].RelatedObservations)
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+// collectHostResults maps the rule results in the ARF of each evaluated remote
+// host to observations. The checks of the policy are reported as errors for
+// the hosts that could not be evaluated, so they do not hide the results of
+// the other hosts.
+func (s PluginServer) collectHostResults(oscalPolicy policy.Policy, hostScans []scan.HostScan) policy.PVPResult {
+	var observations []policy.ObservationByCheck
+	for _, hostScan := range hostScans {
+		err := hostScan.Err
+		if err == nil {
+			var hostObservations []policy.ObservationByCheck
+			hostObservations, err = s.readObservations(oscalPolicy, hostScan.ARF)
+			observations = append(observations, hostObservations...)
+		}
+		if err != nil {
+			hclog.Default().Error("Failed to evaluate host", "host", hostScan.Host, "err", err)
+			observations = append(observations, s.hostErrorObservations(oscalPolicy, hostScan.Host, err)...)
+		}
+	}
+	return s.toPVPResult(observations)
+}
+
+// hostErrorObservations returns an observation in error for each check of the
+// policy on a host that could not be evaluated.
+func (s PluginServer) hostErrorObservations(oscalPolicy policy.Policy, host string, err error) []policy.ObservationByCheck {
+	var observations []policy.ObservationByCheck
+	collected := s.collectionTime(time.Time{})
+	for _, rule := range oscalPolicy {
+		for _, check := range rule.Checks {
+			observations = append(observations, policy.ObservationByCheck{
+				Title:     rule.Rule.ID,
+				Methods:   []string{checkMethod(ovalCheckType)},
+				Collected: collected,
+				CheckID:   check.ID,
+				Subjects: []policy.Subject{
+					{
+						Title:       fmt.Sprintf("Host %s", host),
+						Type:        s.Config.SubjectType(),
+						ResourceID:  host,
+						EvaluatedOn: collected,
+						Result:      policy.ResultError,
+						Reason:      fmt.Sprintf("openscap scan failed: %v", err),
+						Props: []policy.Property{
+							{
+								Name:  s.Config.PropertyName(hostnameProp),
+								Value: host,
+							},
+						},
+					},
+				},
+			})
+		}
+	}
+	return observations
+}
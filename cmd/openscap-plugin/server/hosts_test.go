@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+func TestCollectHostResults(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database")
+	s := New()
+	hostScans := []scan.HostScan{
+		{Host: "rhel10", ARF: filepath.Join(testDataDir, "arf.xml")},
+		{Host: "down", ARF: filepath.Join(testDataDir, "arf-down.xml"), Err: errors.New("connection refused")},
+		// an ARF that cannot be read fails the host as well
+		{Host: "missing", ARF: filepath.Join(testDataDir, "arf-missing.xml")},
+	}
+	pvpResults := s.collectHostResults(oscalPolicy, hostScans)
+
+	results := make(map[string]map[string]policy.Result)
+	for _, observation := range pvpResults.ObservationsByCheck {
+		require.Len(t, observation.Subjects, 1)
+		subject := observation.Subjects[0]
+		if results[subject.ResourceID] == nil {
+			results[subject.ResourceID] = make(map[string]policy.Result)
+		}
+		results[subject.ResourceID][observation.CheckID] = subject.Result
+		require.Equal(t, subject.ResourceID, subjectProp(subject, hostnameProp))
+	}
+	require.Equal(t, map[string]map[string]policy.Result{
+		"rhel10": {
+			"package_aide_installed": policy.ResultFail,
+			"aide_build_database":    policy.ResultPass,
+		},
+		"down": {
+			"package_aide_installed": policy.ResultError,
+			"aide_build_database":    policy.ResultError,
+		},
+		"missing": {
+			"package_aide_installed": policy.ResultError,
+			"aide_build_database":    policy.ResultError,
+		},
+	}, results)
+}
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+)
+
+// MergeResults combines the results of several scans, for example of
+// different profiles or datastreams, into a single result. Observations of
+// the same check with the same subject resource id and result are kept once,
+// while observations with distinct results are preserved. The observations
+// are sorted as the results of a single scan, unless documentorder is set in
+// which case they keep the order of the results. Links found in several
+// results are kept once.
+func (s PluginServer) MergeResults(results ...policy.PVPResult) policy.PVPResult {
+	var merged policy.PVPResult
+	seenLinks := make(map[policy.Link]struct{})
+	for _, result := range results {
+		merged.ObservationsByCheck = append(merged.ObservationsByCheck, result.ObservationsByCheck...)
+		for _, link := range result.Links {
+			if _, ok := seenLinks[link]; ok {
+				continue
+			}
+			seenLinks[link] = struct{}{}
+			merged.Links = append(merged.Links, link)
+		}
+	}
+	merged.ObservationsByCheck = deduplicateObservations(merged.ObservationsByCheck)
+	if !s.Config.Results.DocumentOrder {
+		sortObservations(merged.ObservationsByCheck)
+	}
+	return merged
+}
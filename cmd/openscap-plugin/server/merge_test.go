@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeResults(t *testing.T) {
+	s := newTestServer("arf.xml")
+	results, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy"))
+	require.NoError(t, err)
+	results.Links = []policy.Link{{Href: "file:///tmp/summary.json", Description: "RESULTS_SUMMARY"}}
+
+	// identical results are merged into the same observations
+	merged := s.MergeResults(results, results)
+	require.Equal(t, results, merged)
+
+	// package_aide_installed fails in both ARF files but passes for one of
+	// the instances, which is preserved
+	instances := newTestServer("arf-instances.xml")
+	instanceResults, err := instances.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	merged = s.MergeResults(results, instanceResults)
+	var got []string
+	for _, observation := range merged.ObservationsByCheck {
+		got = append(got, observation.CheckID+"="+observation.Subjects[0].Result.String())
+	}
+	require.Equal(t, []string{
+		"aide_build_database=pass",
+		"configure_crypto_policy=fail",
+		"package_aide_installed=fail",
+		"package_aide_installed=pass",
+	}, got)
+	require.Len(t, merged.Links, 1)
+}
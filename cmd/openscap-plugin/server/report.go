@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/oscap"
+)
+
+// writeHTMLReport generates the HTML report of the ARF at the path set in the
+// configuration and returns a link to it.
+func (s PluginServer) writeHTMLReport() (policy.Link, error) {
+	if err := oscap.OscapGenerateReport(s.Config.Files.ARF, s.Config.Results.HTMLReport); err != nil {
+		return policy.Link{}, err
+	}
+	return policy.Link{
+		Href:        fmt.Sprintf("file://%s", s.Config.Results.HTMLReport),
+		Description: "HTML_REPORT",
+	}, nil
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOscap is an oscap replacement writing the output of the generate
+// report command.
+const fakeOscap = `#!/bin/sh
+echo "<html>$6</html>" > "$5"
+`
+
+func TestWriteHTMLReport(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "oscap"), []byte(fakeOscap), 0700))
+	t.Setenv("PATH", binDir)
+
+	resultsDir := t.TempDir()
+	s := New()
+	s.Config.Files.ARF = filepath.Join(resultsDir, "arf.xml")
+	s.Config.Results.HTMLReport = filepath.Join(resultsDir, "report.html")
+
+	link, err := s.writeHTMLReport()
+	require.NoError(t, err)
+	require.Equal(t, "file://"+s.Config.Results.HTMLReport, link.Href)
+	require.Equal(t, "HTML_REPORT", link.Description)
+	content, err := os.ReadFile(s.Config.Results.HTMLReport)
+	require.NoError(t, err)
+	require.Equal(t, "<html>"+s.Config.Files.ARF+"</html>\n", string(content))
+
+	t.Setenv("PATH", t.TempDir())
+	_, err = s.writeHTMLReport()
+	require.ErrorContains(t, err, "failed to generate HTML report: command not found: oscap")
+}
@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+)
+
+// expandResourceGroup replaces the subjects of an observation of a target in
+// a resource group by one subject per resource of the group, so the result of
+// a single evaluation is attributed to all the members of the group. The
+// subjects keep the properties of the evaluated target, such as its hostname.
+func expandResourceGroup(observation policy.ObservationByCheck, resources []string) policy.ObservationByCheck {
+	subjects := make([]policy.Subject, 0, len(observation.Subjects)*len(resources))
+	for _, subject := range observation.Subjects {
+		for _, resource := range resources {
+			member := subject
+			member.Title = fmt.Sprintf("Host %s", resource)
+			member.ResourceID = resource
+			member.Props = slices.Clone(subject.Props)
+			subjects = append(subjects, member)
+		}
+	}
+	observation.Subjects = subjects
+	return observation
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectResultsResourceGroups(t *testing.T) {
+	dir := t.TempDir()
+	groupsPath := filepath.Join(dir, "groups.json")
+	require.NoError(t, os.WriteFile(groupsPath, []byte(`{"groups": [
+		{"target": "rhel10", "resources": ["node-a", "node-b"]}
+	]}`), 0600))
+	waiversPath := filepath.Join(dir, "waivers.json")
+	require.NoError(t, os.WriteFile(waiversPath, []byte(`{"waivers": [{"rule": "package_aide_installed"}]}`), 0600))
+
+	s := newTestServer("arf.xml")
+	s.Config.Results.ResourceGroups = groupsPath
+	s.Config.Results.Waivers = waiversPath
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+
+	subjects := pvpResults.ObservationsByCheck[0].Subjects
+	require.Len(t, subjects, 2)
+	for i, resource := range []string{"node-a", "node-b"} {
+		require.Equal(t, "Host "+resource, subjects[i].Title)
+		require.Equal(t, resource, subjects[i].ResourceID)
+		require.Equal(t, "rhel10", subjectProp(subjects[i], hostnameProp))
+		// the waiver applies to all the members of the group
+		require.Equal(t, policy.ResultWarning, subjects[i].Result)
+		require.Equal(t, "true", subjectProp(subjects[i], waivedProp))
+	}
+
+	// other targets keep a single subject
+	require.NoError(t, os.WriteFile(groupsPath, []byte(`{"groups": [
+		{"target": "rhel9", "resources": ["node-a", "node-b"]}
+	]}`), 0600))
+	pvpResults, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck[0].Subjects, 1)
+	require.Equal(t, "rhel10", pvpResults.ObservationsByCheck[0].Subjects[0].ResourceID)
+}
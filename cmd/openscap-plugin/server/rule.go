@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+// EvaluateRule evaluates a single rule of the policy on the local system and
+// returns its observations, for example to quickly verify a fix while
+// developing content. The rule is evaluated with its own tailoring and result
+// files, named after the configured files and the rule, so the files of the
+// generate and scan commands are left untouched. No remediation file, summary
+// or report is written.
+func (s PluginServer) EvaluateRule(oscalPolicy policy.Policy, ruleID string) (policy.PVPResult, error) {
+	if err := s.Config.ResolveARF(time.Now()); err != nil {
+		return policy.PVPResult{}, err
+	}
+	ruleConfig, err := s.Config.ForRule(ruleID)
+	if err != nil {
+		return policy.PVPResult{}, err
+	}
+	ruleServer := s
+	ruleServer.Config = ruleConfig
+
+	oscalPolicy, err = ruleServer.selectedPolicy(oscalPolicy)
+	if err != nil {
+		return policy.PVPResult{}, err
+	}
+	tailoringXML, skippedRules, err := s.policyToXML(oscalPolicy, ruleConfig)
+	if err != nil {
+		return policy.PVPResult{}, err
+	}
+	if len(skippedRules) > 0 {
+		return policy.PVPResult{}, fmt.Errorf("rule %s not found in datastream %s", ruleID, ruleConfig.Files.Datastream)
+	}
+	// the tailoring of a single rule is recreated at each evaluation
+	if err := os.WriteFile(ruleConfig.Files.Policy, []byte(tailoringXML), 0600); err != nil {
+		return policy.PVPResult{}, err
+	}
+
+	hclog.Default().Info("Evaluating rule", "rule", ruleID, "arf", ruleConfig.Files.ARF)
+	if _, _, err := scan.ScanSystem(ruleConfig, ruleConfig.Parameters.Profile, nil); err != nil {
+		return policy.PVPResult{}, err
+	}
+	return ruleServer.collectResults(oscalPolicy)
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOscapEval is an oscap replacement copying an ARF to the ARF file of the
+// evaluation.
+const fakeOscapEval = `#!/bin/sh
+cp %q "$8"
+`
+
+func TestEvaluateRule(t *testing.T) {
+	arfPath, err := filepath.Abs(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "oscap"), []byte(fmt.Sprintf(fakeOscapEval, arfPath)), 0700))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	workspace := t.TempDir()
+	s := New()
+	s.Config.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	s.Config.Files.Policy = filepath.Join(workspace, "tailoring_policy.xml")
+	s.Config.Files.Results = filepath.Join(workspace, "results.xml")
+	s.Config.Files.ARF = filepath.Join(workspace, "arf.xml")
+	s.Config.Parameters.Profile = "test_profile"
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database")
+
+	pvpResults, err := s.EvaluateRule(oscalPolicy, "package_aide_installed")
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+	require.Equal(t, "package_aide_installed", pvpResults.ObservationsByCheck[0].CheckID)
+	require.Equal(t, policy.ResultFail, pvpResults.ObservationsByCheck[0].Subjects[0].Result)
+	require.FileExists(t, filepath.Join(workspace, "tailoring_policy-package_aide_installed.xml"))
+	require.FileExists(t, filepath.Join(workspace, "arf-package_aide_installed.xml"))
+	require.NoFileExists(t, s.Config.Files.Policy)
+	require.NoFileExists(t, s.Config.Files.ARF)
+
+	_, err = s.EvaluateRule(oscalPolicy, "configure_crypto_policy")
+	require.Error(t, err)
+}
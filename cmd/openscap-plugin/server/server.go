@@ -6,9 +6,12 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,10 +20,12 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
 
+	"github.com/complytime/complyctl/cmd/openscap-plugin/artifacts"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/oscap"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
 	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
 var (
@@ -30,31 +35,212 @@ var (
 	ovalRegex = regexp.MustCompile(`^[^:]*?:[^-]*?-(.*?):.*?$`)
 )
 
-const ovalCheckType = "http://oval.mitre.org/XMLSchema/oval-definitions-5"
+const (
+	ovalCheckType = "http://oval.mitre.org/XMLSchema/oval-definitions-5"
+	// ocilCheckType and sceCheckType are the systems of the questionnaire
+	// and script check engines.
+	ocilCheckType = "http://scap.nist.gov/schema/ocil/2"
+	sceCheckType  = "http://open-scap.org/page/SCE"
+	// progressInterval is the number of evaluated rules between scan
+	// progress logs.
+	progressInterval = 10
+	// tailoringCacheSize is the number of generated tailoring files kept
+	// in memory.
+	tailoringCacheSize = 16
+)
+
+// ObservationTransform post-processes an observation collected from the scan
+// results, for example to add properties to its subjects. The observation is
+// dropped when it returns false.
+type ObservationTransform func(observation policy.ObservationByCheck) (policy.ObservationByCheck, bool)
+
+// checkMethods are the observation methods by check system, telling how the
+// results of the checks are obtained.
+var checkMethods = map[string]string{
+	ovalCheckType: "AUTOMATED",
+	sceCheckType:  "AUTOMATED",
+	ocilCheckType: "INTERVIEW",
+}
+
+// checkMethod returns the observation method of a check system, or UNKNOWN
+// for other check systems.
+func checkMethod(system string) string {
+	if method, ok := checkMethods[system]; ok {
+		return method
+	}
+	return "UNKNOWN"
+}
 
 type PluginServer struct {
 	Config *config.Config
+	// OscapVersion is the version of the oscap command detected on Configure.
+	OscapVersion *oscap.Version
+	// Transform is applied to each observation before the results are
+	// returned. Observations are returned unchanged when it is nil.
+	Transform ObservationTransform
+	// TailoringCache memoizes the tailoring files generated for a policy.
+	// Tailoring files are always generated when it is nil.
+	TailoringCache *xccdf.TailoringCache
+	// BatchSink receives the observations of GetResults by batches of
+	// BatchSize observations, as they are read from the ARF, instead of
+	// returning them, to bound the memory used by large scans. Observations
+	// are always returned when it is nil or BatchSize is not positive.
+	BatchSink ObservationBatchFunc
+	BatchSize int
 }
 
 func New() PluginServer {
 	return PluginServer{
-		Config: config.NewConfig(),
+		Config:         config.NewConfig(),
+		OscapVersion:   &oscap.Version{},
+		TailoringCache: xccdf.NewTailoringCache(tailoringCacheSize),
 	}
 }
 
 func (s PluginServer) Configure(configMap map[string]string) error {
-	return s.Config.LoadSettings(configMap)
+	if err := s.Config.LoadSettings(configMap); err != nil {
+		return err
+	}
+	if s.Config.Scan.SkipOscapCheck {
+		hclog.Default().Debug("Skipping the oscap check, it is checked when generating")
+		return nil
+	}
+	return s.checkOscap()
+}
+
+// checkOscap verifies oscap is installed and detects its version.
+func (s PluginServer) checkOscap() error {
+	path, err := oscap.LookPath()
+	if err != nil {
+		return err
+	}
+	version, err := oscap.CheckVersion(oscap.MinimumVersion)
+	if err != nil {
+		return err
+	}
+	hclog.Default().Debug("Detected oscap version", "path", path, "version", version.String())
+	*s.OscapVersion = version
+	return nil
 }
 
 func (s PluginServer) Generate(policy policy.Policy) error {
-	hclog.Default().Info("Generating a tailoring file")
-	tailoringXML, err := xccdf.PolicyToXML(policy, s.Config)
+	policy, err := s.selectedPolicy(policy)
 	if err != nil {
 		return err
 	}
+	// the oscap version selects the remediation files to generate
+	if s.Config.Scan.SkipOscapCheck && *s.OscapVersion == (oscap.Version{}) {
+		if err := s.checkOscap(); err != nil {
+			return err
+		}
+	}
 
-	policyPath := s.Config.Files.Policy
-	dst, err := os.Create(policyPath)
+	dsConfigs := []*config.Config{s.Config}
+	for _, datastream := range s.Config.AdditionalDatastreams() {
+		dsConfigs = append(dsConfigs, s.Config.ForDatastream(datastream))
+	}
+	for _, dsConfig := range dsConfigs {
+		hclog.Default().Info("Generating a tailoring file", "datastream", dsConfig.Files.Datastream)
+		tailoringXML, skippedRules, err := s.policyToXML(policy, dsConfig)
+		if err != nil {
+			return err
+		}
+		if len(skippedRules) > 0 {
+			hclog.Default().Warn("Rules not found in the datastream are skipped", "rules", skippedRules, "datastream", dsConfig.Files.Datastream)
+		}
+		if s.Config.Tailoring.Validate {
+			if err := validateTailoring(tailoringXML); err != nil {
+				return err
+			}
+		}
+		if err := s.writeTailoring(dsConfig.Files.Policy, tailoringXML); err != nil {
+			return err
+		}
+		// an existing tailoring file kept as configured may have been
+		// generated for another profile
+		if err := xccdf.VerifyTailoringProfile(dsConfig.Files.Policy, dsConfig.Parameters.Profile); err != nil {
+			return err
+		}
+		if s.Config.Tailoring.Document != "" {
+			if err := s.writeTailoringDocument(dsConfig.Files.Policy); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Generate remedation files
+	hclog.Default().Info(("Generating remediation files"))
+	remediationDir := s.Config.LayoutDir(s.Config.Files.Workspace, config.RemediationDir)
+	// the remediation applies the selections and values of the tailoring
+	// profile, as the scan evaluates it
+	tailoringProfile := fmt.Sprintf("%s_%s", s.Config.Parameters.Profile, xccdf.XCCDFTailoringSuffix)
+	remediationFiles, fixErr := oscap.OscapGenerateFix(*s.OscapVersion, remediationDir, tailoringProfile, s.Config.Files.Policy, s.Config.Files.Datastream, s.Config.Content.BenchmarkID)
+	if fixErr != nil {
+		hclog.Default().Error("Failed to generate the remediation files, the tailoring files are kept", "err", fixErr)
+	}
+	// the tailoring and the remediation files generated before a failure
+	// are recorded, so the tailoring can still be verified by the scan
+	if err := s.writeArtifactsManifest(remediationFiles); err != nil {
+		return err
+	}
+	if fixErr != nil {
+		return fmt.Errorf("%w: %w", pluginerr.ErrRemediationFailed, fixErr)
+	}
+	return nil
+}
+
+// policyToXML generates the tailoring file of the policy, or returns the one
+// already generated for the same inputs when cached.
+func (s PluginServer) policyToXML(oscalPolicy policy.Policy, cfg *config.Config) (string, []string, error) {
+	if s.TailoringCache == nil {
+		return xccdf.PolicyToXML(oscalPolicy, cfg)
+	}
+	return s.TailoringCache.PolicyToXML(oscalPolicy, cfg)
+}
+
+// validateTailoring validates a generated tailoring against the XCCDF schema
+// with oscap, before it replaces the tailoring file.
+func validateTailoring(tailoringXML string) error {
+	file, err := os.CreateTemp("", "tailoring-*.xml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.WriteString(tailoringXML); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	hclog.Default().Debug("Validating the generated tailoring", "path", file.Name())
+	return oscap.OscapValidate(file.Name())
+}
+
+// writeTailoring writes the tailoring file at policyPath, unless it already
+// exists and the overwrite option is set to keep it or to fail.
+func (s PluginServer) writeTailoring(policyPath, tailoringXML string) error {
+	var dst *os.File
+	var err error
+	switch s.Config.Tailoring.Overwrite {
+	case config.OverwriteFail:
+		dst, err = os.OpenFile(policyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("tailoring file %s already exists and overwrite is set to %q", policyPath, config.OverwriteFail)
+		}
+	case config.OverwriteKeep:
+		_, err = os.Stat(policyPath)
+		if err == nil {
+			hclog.Default().Info("Keeping the existing tailoring file", "path", policyPath)
+			return nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		dst, err = os.Create(policyPath)
+	default:
+		dst, err = os.Create(policyPath)
+	}
 	if err != nil {
 		return err
 	}
@@ -62,139 +248,802 @@ func (s PluginServer) Generate(policy policy.Policy) error {
 	if _, err := dst.WriteString(tailoringXML); err != nil {
 		return err
 	}
+	return dst.Close()
+}
 
-	// Generate remedation files
-	hclog.Default().Info(("Generating remediation files"))
-	pluginDir := filepath.Join(s.Config.Files.Workspace, config.PluginDir)
-	err = oscap.OscapGenerateFix(pluginDir, s.Config.Parameters.Profile, s.Config.Files.Policy, s.Config.Files.Datastream)
+// writeTailoringDocument writes the document of the tailoring file at
+// policyPath in the configured format next to it. The document is made from
+// the tailoring file written, or kept, so both always match.
+func (s PluginServer) writeTailoringDocument(policyPath string) error {
+	tailoringFile, err := os.Open(filepath.Clean(policyPath))
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tailoringFile.Close()
+	document, err := xccdf.ReadTailoringDocument(tailoringFile)
+	if err != nil {
+		return err
+	}
+	data, err := document.Marshal(s.Config.Tailoring.Document)
+	if err != nil {
+		return err
+	}
+	documentPath := s.Config.TailoringDocumentPath(policyPath)
+	hclog.Default().Debug("Writing the tailoring document", "path", documentPath)
+	return os.WriteFile(documentPath, data, 0600)
 }
 
-func (s PluginServer) GetResults(oscalPolicy policy.Policy) (policy.PVPResult, error) {
-	pvpResults := policy.PVPResult{}
-	policyChecks := newChecks()
+// writeArtifactsManifest records the tailoring and remediation files created by
+// Generate in the artifacts manifest of the workspace.
+func (s PluginServer) writeArtifactsManifest(remediationFiles map[string]oscap.GeneratedFile) error {
+	tailoring, err := artifacts.NewArtifact(s.Config.Files.Policy, artifacts.TypeTailoring, "xccdf")
+	if err != nil {
+		return err
+	}
+	manifest := artifacts.Manifest{
+		GeneratedAt: time.Now(),
+		Artifacts:   []artifacts.Artifact{tailoring},
+	}
+	for _, datastream := range s.Config.AdditionalDatastreams() {
+		tailoring, err := artifacts.NewArtifact(s.Config.ForDatastream(datastream).Files.Policy, artifacts.TypeTailoring, "xccdf")
+		if err != nil {
+			return err
+		}
+		manifest.Artifacts = append(manifest.Artifacts, tailoring)
+	}
 
-	_, err := scan.ScanSystem(s.Config, s.Config.Parameters.Profile)
+	fixTypes := make([]string, 0, len(remediationFiles))
+	for fixType := range remediationFiles {
+		fixTypes = append(fixTypes, fixType)
+	}
+	sort.Strings(fixTypes)
+	for _, fixType := range fixTypes {
+		remediation, err := artifacts.NewArtifact(remediationFiles[fixType].Path, artifacts.TypeRemediation, fixType)
+		if err != nil {
+			return err
+		}
+		if s.Config.Scan.RecordCommands {
+			remediation.Command = remediationFiles[fixType].CommandLine
+		}
+		manifest.Artifacts = append(manifest.Artifacts, remediation)
+	}
+
+	manifestPath := artifacts.ManifestPath(s.Config.Files.Workspace)
+	hclog.Default().Debug("Writing artifacts manifest", "path", manifestPath)
+	return artifacts.WriteManifest(manifestPath, manifest)
+}
+
+// selectedPolicy returns the rules of the policy selected in the configuration,
+// so only those rules are tailored, evaluated and reported.
+func (s PluginServer) selectedPolicy(oscalPolicy policy.Policy) (policy.Policy, error) {
+	if ruleIDs := s.Config.SelectedRuleIDs(); len(ruleIDs) > 0 {
+		hclog.Default().Info("Evaluating only selected rules", "rules", ruleIDs)
+		var err error
+		oscalPolicy, err = xccdf.FilterPolicyRules(oscalPolicy, ruleIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if controlIDs := s.Config.SelectedControlIDs(); len(controlIDs) > 0 {
+		// the rules are mapped to controls by their references in the
+		// datastream, since the policy does not hold the controls
+		hclog.Default().Info("Evaluating only the rules of selected controls", "controls", controlIDs)
+		return xccdf.FilterPolicyControls(oscalPolicy, s.Config.Files.Datastream, controlIDs)
+	}
+	return oscalPolicy, nil
+}
+
+func (s PluginServer) GetResults(oscalPolicy policy.Policy) (policy.PVPResult, error) {
+	oscalPolicy, err := s.selectedPolicy(oscalPolicy)
 	if err != nil {
 		return policy.PVPResult{}, err
 	}
 
-	policyChecks.LoadPolicy(oscalPolicy)
+	start := time.Now()
+	if err := s.Config.ResolveARF(start); err != nil {
+		return policy.PVPResult{}, err
+	}
+	hclog.Default().Debug("Writing scan results", "arf", s.Config.Files.ARF)
+	var progress oscap.ProgressFunc
+	if s.Config.Scan.Progress {
+		progress = logProgress(len(oscalPolicy))
+	}
+	var durations map[string]time.Duration
+	if s.Config.Scan.Progress || s.Config.Scan.RuleTiming {
+		durations = make(map[string]time.Duration)
+		progress = recordDurations(durations, progress)
+	}
+	// batched observations are passed on as they are read, so they are not
+	// returned, deduplicated nor sorted
+	var batcher *observationBatcher
+	if s.batching() {
+		if err := s.validateBatching(); err != nil {
+			return policy.PVPResult{}, err
+		}
+		hclog.Default().Debug("Passing observations by batches", "size", s.BatchSize)
+		batcher = s.newObservationBatcher(durations)
+	}
+	var pvpResults policy.PVPResult
+	var commandLines []string
+	if hosts := s.Config.ScanHosts(); len(hosts) > 0 {
+		hclog.Default().Info("Evaluating remote hosts", "hosts", len(hosts), "concurrency", max(s.Config.Scan.Concurrency, 1))
+		hostScans, err := scan.ScanHosts(s.Config, s.Config.Parameters.Profile)
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		for _, hostScan := range hostScans {
+			commandLines = append(commandLines, hostScan.CommandLine)
+		}
+		pvpResults = s.collectHostResults(oscalPolicy, hostScans)
+	} else if datastreams := s.Config.AdditionalDatastreams(); len(datastreams) > 0 {
+		hclog.Default().Info("Evaluating datastreams", "datastreams", len(datastreams)+1)
+		dsScans, err := scan.ScanDatastreams(s.Config, s.Config.Parameters.Profile, progress)
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		for _, dsScan := range dsScans {
+			commandLines = append(commandLines, dsScan.CommandLine)
+		}
+		if batcher != nil {
+			err = s.streamDatastreamResults(oscalPolicy, dsScans, batcher.add)
+		} else {
+			pvpResults, err = s.collectDatastreamResults(oscalPolicy, dsScans)
+		}
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+	} else {
+		_, commandLine, err := scan.ScanSystem(s.Config, s.Config.Parameters.Profile, progress)
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		commandLines = append(commandLines, commandLine)
+		if batcher != nil {
+			err = s.walkObservations(oscalPolicy, s.Config.Files.ARF, s.transformed(batcher.add))
+		} else {
+			pvpResults, err = s.collectResults(oscalPolicy)
+		}
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+	}
 
-	// get some results here
-	file, err := os.Open(filepath.Clean(s.Config.Files.ARF))
-	if err != nil {
+	observations := len(pvpResults.ObservationsByCheck)
+	if batcher != nil {
+		if err := batcher.flush(); err != nil {
+			return policy.PVPResult{}, err
+		}
+		observations = batcher.count
+	}
+	if err := s.checkObservations(observations); err != nil {
 		return policy.PVPResult{}, err
 	}
-	defer file.Close()
 
-	xmlnode, err := utils.ParseContent(bufio.NewReader(file))
+	addDurations(pvpResults.ObservationsByCheck, durations, s.Config.PropertyName(durationProp))
+
+	// failures below the fail severity keep their status but do not block
+	// unless they exceed the failure budget
+	var summary resultsSummary
+	if batcher != nil {
+		summary = batcher.summarize()
+	} else {
+		summary = summarizeResults(pvpResults, s.Config.Results.FailSeverity, s.Config.Results.MaxFailures, s.Config.PropertyName(severityProp))
+	}
+	hclog.Default().Info("Scan results summary", "total", summary.Total, "passed", summary.Passed,
+		"failed", summary.Failed, "blocking", len(summary.BlockingFailures))
+	if s.Config.Scan.RecordCommands {
+		summary.Commands = commandLines
+	}
+	summaryLink, err := s.writeSummary(summary)
 	if err != nil {
 		return policy.PVPResult{}, err
 	}
+	pvpResults.Links = append(pvpResults.Links, summaryLink)
+
+	if s.Config.Results.HTMLReport != "" {
+		hclog.Default().Info("Generating HTML report", "path", s.Config.Results.HTMLReport)
+		reportLink, err := s.writeHTMLReport()
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		pvpResults.Links = append(pvpResults.Links, reportLink)
+		addRelevantEvidence(pvpResults.ObservationsByCheck, reportLink)
+	}
+
+	if s.Config.Results.SystemCharacteristics != "" {
+		hclog.Default().Info("Exporting OVAL system characteristics", "path", s.Config.Results.SystemCharacteristics)
+		sysCharLink, ok, err := s.writeSystemCharacteristics()
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		if ok {
+			pvpResults.Links = append(pvpResults.Links, sysCharLink)
+			addRelevantEvidence(pvpResults.ObservationsByCheck, sysCharLink)
+		}
+	}
+
+	if s.Config.Results.EvidenceBundle != "" {
+		hclog.Default().Info("Writing evidence bundle", "path", s.Config.Results.EvidenceBundle)
+		bundleLink, err := s.writeEvidenceBundle()
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		pvpResults.Links = append(pvpResults.Links, bundleLink)
+		addRelevantEvidence(pvpResults.ObservationsByCheck, bundleLink)
+	}
+
+	if s.Config.Results.AssessmentResults != "" {
+		hclog.Default().Info("Writing assessment results", "path", s.Config.Results.AssessmentResults)
+		assessmentResults := s.toAssessmentResults(pvpResults, start)
+		if s.Config.Results.Findings {
+			ruleControls, err := s.ruleControls()
+			if err != nil {
+				return policy.PVPResult{}, err
+			}
+			s.addFindings(&assessmentResults.Results[0], pvpResults, ruleControls)
+		}
+		assessmentResultsLink, err := s.writeAssessmentResults(assessmentResults)
+		if err != nil {
+			return policy.PVPResult{}, err
+		}
+		pvpResults.Links = append(pvpResults.Links, assessmentResultsLink)
+	}
+	return pvpResults, nil
+}
+
+// addRelevantEvidence references the link as evidence in every observation.
+func addRelevantEvidence(observations []policy.ObservationByCheck, link policy.Link) {
+	for i := range observations {
+		observations[i].RelevantEvidences = append(observations[i].RelevantEvidences, link)
+	}
+}
 
-	// extract hostname from xml to use in subject, this will
-	// map to in inventory item in the OSCAL assessment results
-	targetEl := xmlnode.SelectElement("//target")
-	if targetEl == nil {
-		return policy.PVPResult{}, errors.New("result has no 'target' attribute")
+// logProgress returns a progress function logging the number of evaluated
+// rules out of the total rules in the policy, as a heartbeat during long scans.
+func logProgress(total int) oscap.ProgressFunc {
+	return func(progress oscap.Progress) {
+		hclog.Default().Debug("Rule evaluated", "rule", progress.RuleID, "result", progress.Result)
+		if progress.Completed%progressInterval == 0 || progress.Completed == total {
+			hclog.Default().Info("Scan progress", "completed", progress.Completed, "total", total)
+		}
 	}
-	target := targetEl.InnerText()
-	hclog.Default().Debug(fmt.Sprintf("hostname from results target is %s", target))
+}
 
-	ruleTable := xccdf.NewRuleHashTable(xmlnode)
-	results := xmlnode.SelectElements("//rule-result")
-	for i := range results {
-		result := results[i]
-		ruleIDRef := result.SelectAttr("idref")
+// recordDurations returns a progress function recording the time taken to
+// evaluate each rule in durations, by rule id, before calling next unless it
+// is nil.
+func recordDurations(durations map[string]time.Duration, next oscap.ProgressFunc) oscap.ProgressFunc {
+	return func(progress oscap.Progress) {
+		durations[progress.RuleID] = progress.Duration
+		if next != nil {
+			next(progress)
+		}
+	}
+}
 
-		rule, ok := ruleTable[ruleIDRef]
+// addDurations sets the evaluation duration of the rule of each observation
+// on its subjects. Observations of rules without a recorded duration are left
+// as is.
+func addDurations(observations []policy.ObservationByCheck, durations map[string]time.Duration, propName string) {
+	for i := range observations {
+		duration, ok := durations[observations[i].Title]
 		if !ok {
 			continue
 		}
+		for j := range observations[i].Subjects {
+			observations[i].Subjects[j].Props = append(observations[i].Subjects[j].Props, policy.Property{
+				Name:  propName,
+				Value: duration.Round(time.Millisecond).String(),
+			})
+		}
+	}
+}
+
+// collectResults reads the ARF produced by the scan and maps the rule results
+// of checks in the given policy to observations.
+func (s PluginServer) collectResults(oscalPolicy policy.Policy) (policy.PVPResult, error) {
+	observations, err := s.readObservations(oscalPolicy, s.Config.Files.ARF)
+	if err != nil {
+		return policy.PVPResult{}, err
+	}
+	return s.toPVPResult(observations), nil
+}
 
-		var ovalRefEl *xmlquery.Node
-		for _, check := range rule.SelectElements("//xccdf-1.2:check") {
-			if check.SelectAttr("system") == ovalCheckType {
-				ovalRefEl = check.SelectElement("xccdf-1.2:check-content-ref")
-				break
+// toPVPResult returns the results of the observations, deduplicated and
+// sorted as configured.
+func (s PluginServer) toPVPResult(observations []policy.ObservationByCheck) policy.PVPResult {
+	if s.Transform != nil {
+		observations = transformObservations(observations, s.Transform)
+	}
+	if s.Config.Results.Deduplicate {
+		observations = deduplicateObservations(observations)
+	}
+	if !s.Config.Results.DocumentOrder {
+		sortObservations(observations)
+	}
+	return policy.PVPResult{ObservationsByCheck: observations}
+}
+
+// transformObservations applies the transform to each observation and keeps
+// the ones it does not drop.
+func transformObservations(observations []policy.ObservationByCheck, transform ObservationTransform) []policy.ObservationByCheck {
+	transformed := make([]policy.ObservationByCheck, 0, len(observations))
+	for _, observation := range observations {
+		if observation, ok := transform(observation); ok {
+			transformed = append(transformed, observation)
+		}
+	}
+	return transformed
+}
+
+// readObservations maps the rule results of checks in the given policy found
+// in an ARF file to observations, in document order.
+func (s PluginServer) readObservations(oscalPolicy policy.Policy, arfPath string) ([]policy.ObservationByCheck, error) {
+	var observations []policy.ObservationByCheck
+	err := s.walkObservations(oscalPolicy, arfPath, func(observation policy.ObservationByCheck) error {
+		observations = append(observations, observation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return observations, nil
+}
+
+// walkObservations maps the rule results of checks in the given policy found
+// in an ARF file to observations and calls fn with each of them, in document
+// order, as they are read. An error returned by fn stops the walk.
+func (s PluginServer) walkObservations(oscalPolicy policy.Policy, arfPath string, fn func(policy.ObservationByCheck) error) error {
+	policyChecks := newChecks(s.Config.CheckMapping())
+	policyChecks.LoadPolicy(oscalPolicy)
+	waivers, err := s.Config.Waivers()
+	if err != nil {
+		return err
+	}
+	resourceGroups, err := s.Config.ResourceGroups()
+	if err != nil {
+		return err
+	}
+	targetNames, err := s.Config.TargetNames()
+	if err != nil {
+		return err
+	}
+	// the evidence of all the observations is the ARF file
+	evidenceHref, err := s.Config.EvidenceHref(arfPath)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	// get some results here
+	file, err := s.openARF(arfPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var target string
+	// rule results are expected once per rule, or once per instance of
+	// multiply-instantiated rules
+	seenResults := make(map[[2]string]struct{})
+	collect := func(ruleResult xccdf.RuleResult) error {
+		// the hostname from xml is used in subject, this will
+		// map to in inventory item in the OSCAL assessment results
+		if ruleResult.Target != target {
+			target = ruleResult.Target
+			hclog.Default().Debug(fmt.Sprintf("hostname from results target is %s", target))
+			if name, ok := targetNames[target]; ok {
+				hclog.Default().Debug("Target mapped to its canonical name", "target", target, "name", name)
 			}
 		}
-		if ovalRefEl == nil {
-			continue
+		// resource groups are matched against the target reported in the
+		// ARF, the observations use its canonical name, if any
+		arfTarget := ruleResult.Target
+		if name, ok := targetNames[arfTarget]; ok {
+			ruleResult.Target = name
 		}
-		ovalCheck, err := parseCheck(ovalRefEl)
+		resultKey := [2]string{ruleResult.RuleID, ruleResult.Instance}
+		if _, ok := seenResults[resultKey]; ok {
+			hclog.Default().Warn("Duplicate rule result in ARF", "rule", ruleResult.RuleID, "instance", ruleResult.Instance)
+		}
+		seenResults[resultKey] = struct{}{}
+		observation, ok, err := s.toObservation(ruleResult, policyChecks, evidenceHref)
 		if err != nil {
-			return policy.PVPResult{}, err
+			s.logSkipped(ruleResult, err.Error())
+			return err
 		}
-		if policyChecks.Has(ovalCheck) {
-			mappedResult, err := mapResultStatus(result)
-			if err != nil {
-				return policy.PVPResult{}, err
+		if !ok {
+			return nil
+		}
+		if resources, ok := resourceGroups[arfTarget]; ok {
+			observation = expandResourceGroup(observation, resources)
+		}
+		if waivers != nil {
+			observation = s.applyWaiver(observation, waivers, now)
+		}
+		return fn(observation)
+	}
+
+	if s.Config.Results.RulePrefix != "" {
+		hclog.Default().Info("Collecting only results of rules with prefix", "prefix", s.Config.Results.RulePrefix)
+	}
+	reader, release := s.newARFReader(file)
+	defer func() {
+		if err := release(); err != nil {
+			hclog.Default().Warn("Failed to release ARF file mapping", "arf", arfPath, "err", err)
+		}
+	}()
+	if s.Config.Results.Parser == config.StreamParser {
+		err = xccdf.StreamARF(reader, s.Config.Results.RulePrefix, s.Config.Results.TestResult, collect)
+	} else {
+		var xmlnode *xmlquery.Node
+		xmlnode, err = utils.ParseContent(reader)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pluginerr.ErrARFParse, err)
+		}
+		err = xccdf.WalkARF(xmlnode, s.Config.Results.RulePrefix, s.Config.Results.TestResult, s.Config.Results.OVALVariables, collect)
+	}
+	if unmatched := policyChecks.Unmatched(); len(unmatched) > 0 {
+		hclog.Default().Debug("Checks of rule results not found in the policy", "checks", unmatched)
+	}
+	return err
+}
+
+// openARF opens the ARF file and checks that it is complete, then transforms
+// it with the configured XSLT stylesheet, if any. An ARF written to a remote
+// filesystem may not be visible or complete right after the scan, so reading
+// a missing or incomplete ARF is retried as configured, with a delay doubled
+// at each retry up to maxARFBackoffDelay.
+func (s PluginServer) openARF(arfPath string) (*os.File, error) {
+	delay := s.Config.ARFRetryDelay()
+	for attempt := 1; ; attempt++ {
+		file, err := os.Open(filepath.Clean(arfPath))
+		if err == nil {
+			if err = verifyARF(file, s.Config.ARFBufferSize(), s.Config.ARFMaxSize()); err == nil {
+				if s.Config.Results.ARFXSLT == "" {
+					return file, nil
+				}
+				file.Close()
+				return s.transformARF(arfPath)
 			}
-			observation := policy.ObservationByCheck{
-				Title:     ruleIDRef,
-				Methods:   []string{"AUTOMATED"},
-				Collected: time.Now(),
-				CheckID:   ovalCheck,
-				Subjects: []policy.Subject{
-					{
-						Title:       fmt.Sprintf("Host %s", target),
-						Type:        "inventory-item",
-						ResourceID:  target,
-						EvaluatedOn: time.Now(),
-						Result:      mappedResult,
-						Reason:      fmt.Sprintf("openscap rule-result is %s", result.SelectElement("result").InnerText()),
-						Props: []policy.Property{
-							{
-								Name:  "hostname",
-								Value: target,
-							},
-						},
-					},
-				},
-				RelevantEvidences: []policy.Link{
+			file.Close()
+		}
+		transient := errors.Is(err, fs.ErrNotExist) || errors.Is(err, xccdf.ErrARFIncomplete)
+		if !transient || attempt > s.Config.Results.ARFRetries {
+			return nil, err
+		}
+		hclog.Default().Warn("Failed to read ARF file, retrying", "arf", arfPath, "retry", attempt,
+			"retries", s.Config.Results.ARFRetries, "delay", delay, "err", err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxARFBackoffDelay)
+	}
+}
+
+// maxARFBackoffDelay bounds the doubled delay between two retries of reading
+// the ARF, so the retries wait at most a few minutes in total.
+const maxARFBackoffDelay = 30 * time.Second
+
+// verifyARF checks that the ARF file is complete before its rule results are
+// read, so a truncated or corrupt file is reported as such with its size, and
+// rewinds it. The file is read through a buffer of bufferSize bytes. Files
+// larger than maxSize bytes are rejected without being read, so untrusted ARFs
+// cannot exhaust the memory of the plugin.
+func verifyARF(file *os.File, bufferSize, maxSize int) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > int64(maxSize) {
+		return fmt.Errorf("%w: ARF file %s is %d bytes, more than the maximum of %d bytes", xccdf.ErrARFRejected, file.Name(), info.Size(), maxSize)
+	}
+	// the file may still grow while it is read
+	if err := xccdf.VerifyARF(bufio.NewReaderSize(io.LimitReader(file, int64(maxSize)), bufferSize)); err != nil {
+		if errors.Is(err, xccdf.ErrARFRejected) {
+			return fmt.Errorf("ARF file %s: %w", file.Name(), err)
+		}
+		return fmt.Errorf("invalid ARF file %s (%d bytes), re-run the scan: %w", file.Name(), info.Size(), err)
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return err
+}
+
+// deduplicateObservations keeps the first of the observations with the same
+// check id, subject resource id and result.
+func deduplicateObservations(observations []policy.ObservationByCheck) []policy.ObservationByCheck {
+	type observationKey struct {
+		checkID    string
+		resourceID string
+		result     policy.Result
+	}
+	seen := make(map[observationKey]struct{})
+	deduplicated := observations[:0]
+	for _, observation := range observations {
+		key := observationKey{checkID: observation.CheckID}
+		if len(observation.Subjects) > 0 {
+			key.resourceID = observation.Subjects[0].ResourceID
+			key.result = observation.Subjects[0].Result
+		}
+		if _, ok := seen[key]; ok {
+			hclog.Default().Debug("Dropping duplicate observation", "rule", observation.Title, "check", observation.CheckID)
+			continue
+		}
+		seen[key] = struct{}{}
+		deduplicated = append(deduplicated, observation)
+	}
+	return deduplicated
+}
+
+// sortObservations orders observations by rule idref and then by check id, so
+// identical scans produce identical results regardless of the ARF document order.
+func sortObservations(observations []policy.ObservationByCheck) {
+	sort.SliceStable(observations, func(i, j int) bool {
+		if observations[i].Title != observations[j].Title {
+			return observations[i].Title < observations[j].Title
+		}
+		return observations[i].CheckID < observations[j].CheckID
+	})
+}
+
+// toObservation maps a rule result to an observation with the href of the ARF
+// file it is read from as evidence. It returns false if the rule has no OVAL
+// check or the check is not part of the policy.
+func (s PluginServer) toObservation(ruleResult xccdf.RuleResult, policyChecks checks, evidenceHref string) (policy.ObservationByCheck, bool, error) {
+	var ovalRef *xccdf.RuleCheck
+	for i, check := range ruleResult.Checks {
+		if check.System == ovalCheckType {
+			ovalRef = &ruleResult.Checks[i]
+			break
+		}
+	}
+	if ovalRef == nil {
+		s.logSkipped(ruleResult, "the rule has no OVAL check")
+		return policy.ObservationByCheck{}, false, nil
+	}
+	ovalCheck, err := parseCheck(ovalRef.Name)
+	if err != nil {
+		return policy.ObservationByCheck{}, false, err
+	}
+	if !policyChecks.Has(ovalCheck) {
+		s.logSkipped(ruleResult, fmt.Sprintf("check %s is not in the policy", ovalCheck))
+		return policy.ObservationByCheck{}, false, nil
+	}
+	checkID := policyChecks.CheckID(ovalCheck)
+
+	mappedResult, err := mapResultStatus(ruleResult.Result, s.Config.ResultMapping())
+	if err != nil {
+		return policy.ObservationByCheck{}, false, err
+	}
+	target := ruleResult.Target
+	subjectTitle := fmt.Sprintf("Host %s", target)
+	resourceID, err := expandResourceID(s.Config.Results.ResourceID, ruleResult)
+	if err != nil {
+		return policy.ObservationByCheck{}, false, err
+	}
+	// the subject of an image scan is the image rather than the host
+	// running oscap-podman
+	if s.Config.Scan.Image != "" {
+		subjectTitle = fmt.Sprintf("Image %s", s.Config.Scan.Image)
+		if s.Config.Results.ResourceID == "" {
+			resourceID = s.Config.Scan.Image
+		}
+	}
+	reason := fmt.Sprintf("openscap rule-result is %s", ruleResult.Result)
+	if ruleResult.OVALDetails != "" {
+		reason = fmt.Sprintf("%s: %s", reason, ruleResult.OVALDetails)
+	}
+	if len(ruleResult.Messages) > 0 {
+		reason = fmt.Sprintf("%s: %s", reason, strings.Join(ruleResult.Messages, "; "))
+	}
+	collected := s.collectionTime(ruleResult.Time)
+	observation := policy.ObservationByCheck{
+		Title:     ruleResult.RuleID,
+		Methods:   []string{checkMethod(ovalRef.System)},
+		Collected: collected,
+		CheckID:   checkID,
+		Subjects: []policy.Subject{
+			{
+				Title:       subjectTitle,
+				Type:        s.Config.SubjectType(),
+				ResourceID:  resourceID,
+				EvaluatedOn: collected,
+				Result:      mappedResult,
+				Reason:      reason,
+				Props: []policy.Property{
 					{
-						Href:        fmt.Sprintf("file://%s", s.Config.Files.ARF),
-						Description: "ARF_FILE",
+						Name:  s.Config.PropertyName(hostnameProp),
+						Value: target,
 					},
 				},
-			}
-			pvpResults.ObservationsByCheck = append(pvpResults.ObservationsByCheck, observation)
+			},
+		},
+		RelevantEvidences: []policy.Link{
+			{
+				Href:        evidenceHref,
+				Description: "ARF_FILE",
+			},
+		},
+	}
+	if s.Config.Scan.Image != "" {
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{
+			Name:  s.Config.PropertyName(imageProp),
+			Value: s.Config.Scan.Image,
+		})
+	}
+	if ruleResult.Instance != "" {
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{
+			Name:  s.Config.PropertyName(instanceProp),
+			Value: ruleResult.Instance,
+		})
+	}
+	if ruleResult.Result == "fixed" {
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{
+			Name:  s.Config.PropertyName(remediatedProp),
+			Value: "true",
+		})
+	}
+	if ruleResult.Result == "notchecked" {
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{
+			Name:  s.Config.PropertyName(notCheckedProp),
+			Value: "true",
+		})
+	}
+	if ruleResult.Severity != "" {
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{
+			Name:  s.Config.PropertyName(severityProp),
+			Value: ruleResult.Severity,
+		})
+	}
+	if s.Config.Results.OVALVariables {
+		for _, variable := range ruleResult.OVALVariables {
+			observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{
+				Name:  s.Config.PropertyName(ovalVariableProp),
+				Value: fmt.Sprintf("%s=%s", ovalVariableName(variable), strings.Join(variable.Values, ",")),
+			})
 		}
 	}
-	return pvpResults, nil
+	if s.Config.Results.Provenance && ruleResult.Provenance != nil {
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, s.provenanceProps(*ruleResult.Provenance)...)
+	}
+	return observation, true, nil
+}
+
+// provenanceProps returns the subject properties describing the content and
+// the scanner that produced a result. Unknown values have no property.
+func (s PluginServer) provenanceProps(provenance xccdf.Provenance) []policy.Property {
+	var props []policy.Property
+	for _, prop := range []policy.Property{
+		{Name: benchmarkProp, Value: provenance.Benchmark},
+		{Name: benchmarkVersionProp, Value: provenance.BenchmarkVersion},
+		{Name: scannerProp, Value: provenance.Scanner},
+		{Name: scannerVersionProp, Value: provenance.ScannerVersion},
+		{Name: profileProp, Value: provenance.Profile},
+	} {
+		if prop.Value != "" {
+			props = append(props, policy.Property{Name: s.Config.PropertyName(prop.Name), Value: prop.Value})
+		}
+	}
+	return props
+}
+
+// collectionTime returns the collection time of an observation: the scan time,
+// when configured and known, or else the current time, in the configured time
+// zone. Times in UTC are comparable across hosts whatever the time zone of
+// the system processing the results.
+func (s PluginServer) collectionTime(scanTime time.Time) time.Time {
+	collected := time.Now()
+	if s.Config.Results.TimestampSource == config.TimestampScan && !scanTime.IsZero() {
+		collected = scanTime
+	}
+	if s.Config.Results.TimeZone == config.TimeZoneLocal {
+		return collected.Local()
+	}
+	return collected.UTC()
+}
+
+// ovalVariableName returns the name of an OVAL variable in the properties of
+// the subjects: the id of the XCCDF Value exported to it, without the prefix
+// of the content, or the id of the variable.
+func ovalVariableName(variable xccdf.OVALVariable) string {
+	if variable.ValueID == "" {
+		return variable.ID
+	}
+	return strings.TrimPrefix(variable.ValueID, xccdf.XCCDFCaCNamespace+"_value_")
+}
+
+// expandResourceID returns the subject resource id for a rule result. The
+// template may reference the ARF target as ${target} and any target fact by
+// name, for example ${urn:xccdf:fact:identifier}. An empty template results in
+// the ARF target.
+func expandResourceID(template string, ruleResult xccdf.RuleResult) (string, error) {
+	if template == "" {
+		return ruleResult.Target, nil
+	}
+	var missing []string
+	resourceID := os.Expand(template, func(name string) string {
+		if name == "target" {
+			return ruleResult.Target
+		}
+		value, ok := ruleResult.TargetFacts[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("resource id template %q references unknown facts: %s", template, strings.Join(missing, ", "))
+	}
+	if resourceID == "" {
+		return "", fmt.Errorf("resource id template %q expands to an empty value", template)
+	}
+	return resourceID, nil
+}
+
+// logSkipped logs a rule result not reported as an observation and the reason,
+// when skipped rule results are logged.
+func (s PluginServer) logSkipped(ruleResult xccdf.RuleResult, reason string) {
+	if !s.Config.Results.LogSkipped {
+		return
+	}
+	hclog.Default().Info("Skipped rule result", "rule", ruleResult.RuleID, "instance", ruleResult.Instance,
+		"target", ruleResult.Target, "result", ruleResult.Result, "reason", reason)
 }
 
 // checks is a Set implementation for comparing OSCAL
-// and OVAL checks ids.
-type checks map[string]struct{}
+// and OVAL checks ids. OVAL checks named differently in the content are
+// compared with the OSCAL check ids they are mapped to.
+type checks struct {
+	ids     map[string]struct{}
+	mapping map[string]string
+	// unmatched are the OVAL checks found in no policy check
+	unmatched map[string]struct{}
+}
 
-func newChecks() checks {
-	policyChecks := make(checks)
-	return policyChecks
+func newChecks(mapping map[string]string) checks {
+	return checks{
+		ids:       make(map[string]struct{}),
+		mapping:   mapping,
+		unmatched: make(map[string]struct{}),
+	}
 }
 
 func (c checks) LoadPolicy(oscalPolicy policy.Policy) {
 	for _, rule := range oscalPolicy {
 		for _, check := range rule.Checks {
-			c[check.ID] = struct{}{}
+			c.ids[check.ID] = struct{}{}
 		}
 	}
 }
 
+// CheckID returns the OSCAL check id of an OVAL check.
+func (c checks) CheckID(check string) string {
+	if checkID, ok := c.mapping[check]; ok {
+		return checkID
+	}
+	return check
+}
+
 func (c checks) Has(check string) bool {
-	_, ok := c[check]
+	_, ok := c.ids[c.CheckID(check)]
+	if !ok {
+		c.unmatched[check] = struct{}{}
+	}
 	return ok
 }
 
+// Unmatched returns the sorted OVAL checks found in no policy check so far.
+func (c checks) Unmatched() []string {
+	unmatched := make([]string, 0, len(c.unmatched))
+	for check := range c.unmatched {
+		unmatched = append(unmatched, check)
+	}
+	sort.Strings(unmatched)
+	return unmatched
+}
+
 // parseCheck returns the check short name without the OVAL-specific naming from a
-// rule in results.
-func parseCheck(check *xmlquery.Node) (string, error) {
-	ovalCheckName := strings.TrimSpace(check.SelectAttr("name"))
+// check-content-ref name of a rule in results.
+func parseCheck(checkName string) (string, error) {
+	ovalCheckName := strings.TrimSpace(checkName)
 	if ovalCheckName == "" {
 		return "", errors.New("check-content-ref node has no 'name' attribute")
 	}
@@ -208,21 +1057,39 @@ func parseCheck(check *xmlquery.Node) (string, error) {
 	return trimmedCheckName, nil
 }
 
-func mapResultStatus(result *xmlquery.Node) (policy.Result, error) {
-	resultEl := result.SelectElement("result")
-	if resultEl == nil {
+// mapResultStatus maps an XCCDF rule result status to a policy result. The
+// overrides, by status, take precedence over the default mapping.
+func mapResultStatus(result string, overrides map[string]string) (policy.Result, error) {
+	if result == "" {
 		return policy.ResultInvalid, errors.New("result node has no 'result' attribute")
 	}
-	switch resultEl.InnerText() {
+	if override, ok := overrides[result]; ok {
+		switch override {
+		case "pass":
+			return policy.ResultPass, nil
+		case "fail":
+			return policy.ResultFail, nil
+		case "error":
+			return policy.ResultError, nil
+		case "warning":
+			return policy.ResultWarning, nil
+		}
+		return policy.ResultInvalid, fmt.Errorf("couldn't match %s mapped to %s", result, override)
+	}
+	switch result {
 	case "pass", "fixed":
 		return policy.ResultPass, nil
 	case "fail":
 		return policy.ResultFail, nil
 	case "notselected", "notapplicable":
 		return policy.ResultError, nil
+	// the rule was not evaluated, for example when it needs a manual
+	// check, which is not an error of the evaluation
+	case "notchecked":
+		return policy.ResultWarning, nil
 	case "error", "unknown":
 		return policy.ResultError, nil
 	}
 
-	return policy.ResultInvalid, fmt.Errorf("couldn't match %s", resultEl.InnerText())
+	return policy.ResultInvalid, fmt.Errorf("couldn't match %s", result)
 }
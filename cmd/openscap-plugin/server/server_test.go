@@ -3,67 +3,141 @@
 package server
 
 import (
+	"bytes"
 	"errors"
-	"strings"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
-	"github.com/antchfx/xmlquery"
+	"github.com/hashicorp/go-hclog"
 	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/oscal-compass/oscal-sdk-go/extensions"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/artifacts"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/oscap"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
+var testDataDir = filepath.Join("..", "..", "..", "internal", "complytime", "testdata", "openscap")
+
+// testPolicy creates an OSCAL policy with one rule per given check id.
+func testPolicy(checkIDs ...string) policy.Policy {
+	var oscalPolicy policy.Policy
+	for _, checkID := range checkIDs {
+		oscalPolicy = append(oscalPolicy, extensions.RuleSet{
+			Rule:   extensions.Rule{ID: checkID},
+			Checks: []extensions.Check{{ID: checkID}},
+		})
+	}
+	return oscalPolicy
+}
+
+// newTestServer creates a PluginServer reading results from the given ARF file.
+func newTestServer(arfFile string) PluginServer {
+	s := New()
+	s.Config.Files.ARF = filepath.Join(testDataDir, arfFile)
+	return s
+}
+
+// clearTimestamps removes the processing timestamps from observations so results
+// from different runs can be compared.
+func clearTimestamps(pvpResult policy.PVPResult) policy.PVPResult {
+	for i := range pvpResult.ObservationsByCheck {
+		pvpResult.ObservationsByCheck[i].Collected = time.Time{}
+		for j := range pvpResult.ObservationsByCheck[i].Subjects {
+			pvpResult.ObservationsByCheck[i].Subjects[j].EvaluatedOn = time.Time{}
+		}
+	}
+	return pvpResult
+}
+
 func TestMapResultStatus(t *testing.T) {
 	tests := []struct {
 		name           string
-		xmlContent     string
+		result         string
+		overrides      map[string]string
 		expectedResult policy.Result
 		expectedError  error
 	}{
 		{
 			name:           "Pass result",
-			xmlContent:     `<rule-result><result>pass</result></rule-result>`,
+			result:         "pass",
 			expectedResult: policy.ResultPass,
 			expectedError:  nil,
 		},
 		{
 			name:           "Fail result",
-			xmlContent:     `<rule-result><result>fail</result></rule-result>`,
+			result:         "fail",
 			expectedResult: policy.ResultFail,
 			expectedError:  nil,
 		},
 		{
 			name:           "Not selected result",
-			xmlContent:     `<rule-result><result>notselected</result></rule-result>`,
+			result:         "notselected",
 			expectedResult: policy.ResultError,
 			expectedError:  nil,
 		},
 		{
 			name:           "Not selected result",
-			xmlContent:     `<rule-result><result>notapplicable</result></rule-result>`,
+			result:         "notapplicable",
 			expectedResult: policy.ResultError,
 			expectedError:  nil,
 		},
+		{
+			name:           "Not checked result",
+			result:         "notchecked",
+			expectedResult: policy.ResultWarning,
+			expectedError:  nil,
+		},
 		{
 			name:           "Error result",
-			xmlContent:     `<rule-result><result>error</result></rule-result>`,
+			result:         "error",
 			expectedResult: policy.ResultError,
 			expectedError:  nil,
 		},
 		{
 			name:           "Unknown result",
-			xmlContent:     `<rule-result><result>unknown</result></rule-result>`,
+			result:         "unknown",
 			expectedResult: policy.ResultError,
 			expectedError:  nil,
 		},
+		{
+			name:           "Unknown result mapped to fail",
+			result:         "unknown",
+			overrides:      map[string]string{"unknown": "fail", "notapplicable": "pass"},
+			expectedResult: policy.ResultFail,
+			expectedError:  nil,
+		},
+		{
+			name:           "Not applicable result mapped to warning",
+			result:         "notapplicable",
+			overrides:      map[string]string{"notapplicable": "warning"},
+			expectedResult: policy.ResultWarning,
+			expectedError:  nil,
+		},
+		{
+			name:           "Result not overridden",
+			result:         "fail",
+			overrides:      map[string]string{"unknown": "pass"},
+			expectedResult: policy.ResultFail,
+			expectedError:  nil,
+		},
 		{
 			name:           "Invalid result",
-			xmlContent:     `<rule-result><result>invalid</result></rule-result>`,
+			result:         "invalid",
 			expectedResult: policy.ResultInvalid,
 			expectedError:  errors.New("couldn't match invalid"),
 		},
 		{
 			name:           "No result element",
-			xmlContent:     `<rule-result></rule-result>`,
+			result:         "",
 			expectedResult: policy.ResultInvalid,
 			expectedError:  errors.New("result node has no 'result' attribute"),
 		},
@@ -71,10 +145,7 @@ func TestMapResultStatus(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			node, err := xmlquery.Parse(strings.NewReader(tt.xmlContent))
-			assert.NoError(t, err)
-
-			result, err := mapResultStatus(node.SelectElement("rule-result"))
+			result, err := mapResultStatus(tt.result, tt.overrides)
 			assert.Equal(t, tt.expectedResult, result)
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
@@ -85,36 +156,49 @@ func TestMapResultStatus(t *testing.T) {
 	}
 }
 
+func TestCheckMethod(t *testing.T) {
+	tests := []struct {
+		system string
+		want   string
+	}{
+		{ovalCheckType, "AUTOMATED"},
+		{sceCheckType, "AUTOMATED"},
+		{ocilCheckType, "INTERVIEW"},
+		{"http://example.com/check", "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, checkMethod(tt.system), tt.system)
+	}
+}
+
 func TestParseCheck(t *testing.T) {
 	tests := []struct {
 		name           string
-		xmlContent     string
+		checkName      string
 		expectedResult string
 		expectedError  error
 	}{
 		{
 			name:           "Valid/ExpectedFormat",
-			xmlContent:     `<check-content-ref name="oval:ssg-audit_perm_change_success:def:1"/>`,
+			checkName:      "oval:ssg-audit_perm_change_success:def:1",
 			expectedResult: "audit_perm_change_success",
 		},
 		{
 			name:           "Invalid/UnexpectedFormat",
-			xmlContent:     `<check-content-ref name="ovalssg-audit_perm_change_success:def:1"/>`,
+			checkName:      "ovalssg-audit_perm_change_success:def:1",
 			expectedResult: "",
 			expectedError:  errors.New("check id \"ovalssg-audit_perm_change_success:def:1\" is in unexpected format"),
 		},
 		{
 			name:           "Invalid/NoNameAttribute",
-			xmlContent:     `<check-content-ref/>`,
+			checkName:      "",
 			expectedResult: "",
 			expectedError:  errors.New("check-content-ref node has no 'name' attribute"),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			node, err := xmlquery.Parse(strings.NewReader(tt.xmlContent))
-			assert.NoError(t, err)
-			check, err := parseCheck(node.SelectElement("check-content-ref"))
+			check, err := parseCheck(tt.checkName)
 			assert.Equal(t, tt.expectedResult, check)
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
@@ -124,3 +208,757 @@ func TestParseCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectResults(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+
+	s := newTestServer("arf.xml")
+	treeResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+
+	var gotChecks []string
+	for _, observation := range treeResults.ObservationsByCheck {
+		gotChecks = append(gotChecks, observation.CheckID)
+		require.Len(t, observation.Subjects, 1)
+		assert.Equal(t, "rhel10", observation.Subjects[0].ResourceID)
+	}
+	// observations are sorted by rule idref
+	assert.Equal(t, []string{"aide_build_database", "configure_crypto_policy", "package_aide_installed"}, gotChecks)
+	assert.Equal(t, policy.ResultPass, treeResults.ObservationsByCheck[0].Subjects[0].Result)
+	assert.Equal(t, policy.ResultFail, treeResults.ObservationsByCheck[2].Subjects[0].Result)
+
+	s.Config.Results.Parser = config.StreamParser
+	streamResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, clearTimestamps(treeResults), clearTimestamps(streamResults))
+
+	s.Config.Results.DocumentOrder = true
+	documentResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	gotChecks = nil
+	for _, observation := range documentResults.ObservationsByCheck {
+		gotChecks = append(gotChecks, observation.CheckID)
+	}
+	assert.Equal(t, []string{"package_aide_installed", "aide_build_database", "configure_crypto_policy"}, gotChecks)
+}
+
+func TestCollectResultsRulePrefix(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+
+	s := newTestServer("arf.xml")
+	s.Config.Results.RulePrefix = "aide_"
+	for _, parser := range []string{config.TreeParser, config.StreamParser} {
+		s.Config.Results.Parser = parser
+		pvpResults, err := s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+		require.Len(t, pvpResults.ObservationsByCheck, 1)
+		require.Equal(t, "aide_build_database", pvpResults.ObservationsByCheck[0].CheckID)
+	}
+}
+
+func TestCollectResultsARFReader(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+
+	for _, parser := range []string{config.TreeParser, config.StreamParser} {
+		s := newTestServer("arf.xml")
+		s.Config.Results.Parser = parser
+		want, err := s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+
+		s.Config.Results.ARFBufferSize = 512
+		got, err := s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+		assert.Equal(t, clearTimestamps(want), clearTimestamps(got))
+
+		s.Config.Results.ARFMmap = true
+		got, err = s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+		assert.Equal(t, clearTimestamps(want), clearTimestamps(got))
+	}
+}
+
+func TestCollectResultsIncompleteARF(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	arfPath := filepath.Join(t.TempDir(), "arf.xml")
+	require.NoError(t, os.WriteFile(arfPath, content[:len(content)/2], 0600))
+
+	s := New()
+	s.Config.Files.ARF = arfPath
+	_, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.ErrorIs(t, err, xccdf.ErrARFIncomplete)
+	require.ErrorContains(t, err, fmt.Sprintf("invalid ARF file %s (%d bytes), re-run the scan", arfPath, len(content)/2))
+}
+
+func TestCollectResultsRejectedARF(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	arfPath := filepath.Join(t.TempDir(), "arf.xml")
+	require.NoError(t, os.WriteFile(arfPath, content, 0600))
+
+	// rejected ARFs are not retried
+	s := New()
+	s.Config.Files.ARF = arfPath
+	s.Config.Results.ARFRetries = 3
+	s.Config.Results.ARFMaxSize = len(content) - 1
+	_, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.ErrorIs(t, err, xccdf.ErrARFRejected)
+	require.EqualError(t, err, fmt.Sprintf("ARF rejected: ARF file %s is %d bytes, more than the maximum of %d bytes", arfPath, len(content), len(content)-1))
+
+	s.Config.Results.ARFMaxSize = 0
+	doctype := []byte(`<!DOCTYPE arf [<!ENTITY target SYSTEM "file:///etc/passwd">]>`)
+	require.NoError(t, os.WriteFile(arfPath, bytes.Replace(content, []byte("<arf:asset-report-collection"), append(doctype, "\n<arf:asset-report-collection"...), 1), 0600))
+	_, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.ErrorIs(t, err, xccdf.ErrARFRejected)
+	require.ErrorContains(t, err, "document type declarations are not allowed")
+}
+
+func TestCollectResultsARFRetries(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	arfPath := filepath.Join(t.TempDir(), "arf.xml")
+	// the ARF is incomplete, then complete once the second retry starts
+	require.NoError(t, os.WriteFile(arfPath, content[:len(content)/2], 0600))
+
+	s := New()
+	s.Config.Files.ARF = arfPath
+	s.Config.Results.ARFRetries = 3
+	s.Config.Results.ARFRetryDelay = "50ms"
+	written := make(chan error)
+	go func() {
+		time.Sleep(75 * time.Millisecond)
+		written <- os.WriteFile(arfPath, content, 0600)
+	}()
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, <-written)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+
+	// the ARF is given up on once the retries are exhausted
+	require.NoError(t, os.Remove(arfPath))
+	s.Config.Results.ARFRetries = 1
+	start := time.Now()
+	_, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestCollectResultsInstances(t *testing.T) {
+	s := newTestServer("arf-instances.xml")
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+
+	// an observation is emitted for each instance of the rule
+	require.Len(t, pvpResults.ObservationsByCheck, 2)
+	var gotInstances []string
+	var gotResults []policy.Result
+	for _, observation := range pvpResults.ObservationsByCheck {
+		require.Equal(t, "package_aide_installed", observation.CheckID)
+		gotInstances = append(gotInstances, subjectProp(observation.Subjects[0], instanceProp))
+		gotResults = append(gotResults, observation.Subjects[0].Result)
+	}
+	require.Equal(t, []string{"/", "/var/lib/containers"}, gotInstances)
+	require.Equal(t, []policy.Result{policy.ResultFail, policy.ResultPass}, gotResults)
+
+	s.Config.Results.Parser = config.StreamParser
+	streamResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Equal(t, clearTimestamps(pvpResults), clearTimestamps(streamResults))
+}
+
+func TestCollectResultsOVALDetails(t *testing.T) {
+	s := newTestServer("arf-oval.xml")
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy"))
+	require.NoError(t, err)
+
+	var gotReasons []string
+	for _, observation := range pvpResults.ObservationsByCheck {
+		gotReasons = append(gotReasons, observation.Subjects[0].Reason)
+	}
+	require.Equal(t, []string{
+		"openscap rule-result is pass",
+		`openscap rule-result is fail: expected subexpression "DEFAULT", found subexpression "LEGACY"`,
+		"openscap rule-result is fail: expected at least one item, found none",
+	}, gotReasons)
+}
+
+func TestCollectResultsOVALVariables(t *testing.T) {
+	s := newTestServer("arf-oval.xml")
+	oscalPolicy := testPolicy("aide_build_database", "configure_crypto_policy")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	for _, observation := range pvpResults.ObservationsByCheck {
+		require.Empty(t, subjectProp(observation.Subjects[0], ovalVariableProp))
+	}
+
+	s.Config.Results.OVALVariables = true
+	pvpResults, err = s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 2)
+	require.Empty(t, subjectProp(pvpResults.ObservationsByCheck[0].Subjects[0], ovalVariableProp))
+	require.Equal(t, "var_system_crypto_policy=DEFAULT", subjectProp(pvpResults.ObservationsByCheck[1].Subjects[0], ovalVariableProp))
+}
+
+func TestCollectResultsProvenance(t *testing.T) {
+	s := newTestServer("arf.xml")
+	oscalPolicy := testPolicy("package_aide_installed")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Empty(t, subjectProp(pvpResults.ObservationsByCheck[0].Subjects[0], scannerProp))
+
+	s.Config.Results.Provenance = true
+	for _, parser := range []string{config.TreeParser, config.StreamParser} {
+		s.Config.Results.Parser = parser
+		pvpResults, err = s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+		require.Len(t, pvpResults.ObservationsByCheck, 1)
+		subject := pvpResults.ObservationsByCheck[0].Subjects[0]
+		require.Equal(t, "xccdf_org.ssgproject.content_benchmark_RHEL-10", subjectProp(subject, benchmarkProp))
+		require.Equal(t, "0.1.76", subjectProp(subject, benchmarkVersionProp))
+		require.Equal(t, "openscap", subjectProp(subject, scannerProp))
+		require.Equal(t, "1.3.10", subjectProp(subject, scannerVersionProp))
+		require.Equal(t, "xccdf_complytime.openscapplugin_profile_test_profile_complytime", subjectProp(subject, profileProp))
+	}
+}
+
+func TestCollectResultsImage(t *testing.T) {
+	s := newTestServer("arf.xml")
+	s.Config.Scan.Image = "registry.access.redhat.com/ubi10/ubi:latest"
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+	subject := pvpResults.ObservationsByCheck[0].Subjects[0]
+	require.Equal(t, "Image registry.access.redhat.com/ubi10/ubi:latest", subject.Title)
+	require.Equal(t, "registry.access.redhat.com/ubi10/ubi:latest", subject.ResourceID)
+	require.Equal(t, "registry.access.redhat.com/ubi10/ubi:latest", subjectProp(subject, imageProp))
+
+	// a configured resource id is kept
+	s.Config.Results.ResourceID = "${urn:xccdf:fact:identifier}"
+	pvpResults, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Equal(t, "9f8c3b1e4d2a4c6b8e0f1a2b3c4d5e6f", pvpResults.ObservationsByCheck[0].Subjects[0].ResourceID)
+}
+
+func TestCollectResultsRemediated(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	// package_aide_installed is the first failing rule of the ARF
+	arfPath := filepath.Join(t.TempDir(), "arf.xml")
+	require.NoError(t, os.WriteFile(arfPath, bytes.Replace(content, []byte("<result>fail</result>"), []byte("<result>fixed</result>"), 1), 0600))
+
+	s := New()
+	s.Config.Files.ARF = arfPath
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 2)
+	// rules passing without remediation are not flagged
+	require.Empty(t, subjectProp(pvpResults.ObservationsByCheck[0].Subjects[0], remediatedProp))
+	fixed := pvpResults.ObservationsByCheck[1].Subjects[0]
+	require.Equal(t, policy.ResultPass, fixed.Result)
+	require.Equal(t, "true", subjectProp(fixed, remediatedProp))
+
+	// remediated rules can be reported as soft failures
+	s.Config.Results.ResultMapping = "fixed=warning"
+	pvpResults, err = s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Equal(t, policy.ResultWarning, pvpResults.ObservationsByCheck[1].Subjects[0].Result)
+}
+
+func TestCollectResultsNotChecked(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	// package_aide_installed is the first failing rule of the ARF
+	notChecked := []byte("<result>notchecked</result>\n            <message severity=\"info\">No candidate or applicable check found.</message>")
+	arfPath := filepath.Join(t.TempDir(), "arf.xml")
+	require.NoError(t, os.WriteFile(arfPath, bytes.Replace(content, []byte("<result>fail</result>"), notChecked, 1), 0600))
+
+	s := New()
+	s.Config.Files.ARF = arfPath
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database")
+	for _, parser := range []string{config.TreeParser, config.StreamParser} {
+		s.Config.Results.Parser = parser
+		pvpResults, err := s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+		require.Len(t, pvpResults.ObservationsByCheck, 2)
+		require.Empty(t, subjectProp(pvpResults.ObservationsByCheck[0].Subjects[0], notCheckedProp))
+		subject := pvpResults.ObservationsByCheck[1].Subjects[0]
+		require.Equal(t, policy.ResultWarning, subject.Result)
+		require.Equal(t, "true", subjectProp(subject, notCheckedProp))
+		require.Equal(t, "openscap rule-result is notchecked: No candidate or applicable check found.", subject.Reason)
+	}
+}
+
+func TestCollectResultsTimestamps(t *testing.T) {
+	s := newTestServer("arf.xml")
+	oscalPolicy := testPolicy("package_aide_installed")
+
+	// observations are collected at the processing time, in UTC
+	before := time.Now()
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	collected := pvpResults.ObservationsByCheck[0].Collected
+	require.Equal(t, time.UTC, collected.Location())
+	require.False(t, collected.Before(before.Truncate(time.Second)))
+	require.Equal(t, collected, pvpResults.ObservationsByCheck[0].Subjects[0].EvaluatedOn)
+
+	// or at the time of the rule-result in the ARF
+	s.Config.Results.TimestampSource = config.TimestampScan
+	scanTime := time.Date(2025, 6, 10, 10, 0, 1, 0, time.UTC)
+	for _, parser := range []string{config.TreeParser, config.StreamParser} {
+		s.Config.Results.Parser = parser
+		pvpResults, err = s.collectResults(oscalPolicy)
+		require.NoError(t, err)
+		require.Equal(t, scanTime, pvpResults.ObservationsByCheck[0].Collected)
+		require.Equal(t, scanTime, pvpResults.ObservationsByCheck[0].Subjects[0].EvaluatedOn)
+	}
+
+	s.Config.Results.TimeZone = config.TimeZoneLocal
+	pvpResults, err = s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Equal(t, time.Local, pvpResults.ObservationsByCheck[0].Collected.Location())
+	require.True(t, scanTime.Equal(pvpResults.ObservationsByCheck[0].Collected))
+}
+
+func TestAddDurations(t *testing.T) {
+	s := New()
+	s.Config.Files.ARF = filepath.Join(testDataDir, "arf.xml")
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 2)
+
+	durations := make(map[string]time.Duration)
+	var reported []string
+	progress := recordDurations(durations, func(progress oscap.Progress) {
+		reported = append(reported, progress.RuleID)
+	})
+	progress(oscap.Progress{Completed: 1, RuleID: pvpResults.ObservationsByCheck[0].Title, Result: "pass", Duration: 1500 * time.Microsecond})
+	require.Equal(t, []string{pvpResults.ObservationsByCheck[0].Title}, reported)
+
+	addDurations(pvpResults.ObservationsByCheck, durations, s.Config.PropertyName(durationProp))
+	require.Equal(t, "2ms", subjectProp(pvpResults.ObservationsByCheck[0].Subjects[0], durationProp))
+	// rules without timing have no duration
+	require.Empty(t, subjectProp(pvpResults.ObservationsByCheck[1].Subjects[0], durationProp))
+}
+
+func TestDeduplicateObservations(t *testing.T) {
+	observation := func(title, checkID, resourceID string, result policy.Result) policy.ObservationByCheck {
+		return policy.ObservationByCheck{
+			Title:    title,
+			CheckID:  checkID,
+			Subjects: []policy.Subject{{ResourceID: resourceID, Result: result}},
+		}
+	}
+	observations := []policy.ObservationByCheck{
+		observation("rule_a", "check_a", "host1", policy.ResultPass),
+		observation("rule_a", "check_a", "host1", policy.ResultPass),
+		observation("rule_a", "check_a", "host1", policy.ResultFail),
+		observation("rule_b", "check_a", "host2", policy.ResultPass),
+		observation("rule_c", "check_a", "host1", policy.ResultPass),
+	}
+	require.Equal(t, []policy.ObservationByCheck{
+		observation("rule_a", "check_a", "host1", policy.ResultPass),
+		observation("rule_a", "check_a", "host1", policy.ResultFail),
+		observation("rule_b", "check_a", "host2", policy.ResultPass),
+	}, deduplicateObservations(observations))
+
+	// distinct results of the instances of a rule are preserved
+	s := newTestServer("arf-instances.xml")
+	s.Config.Results.Deduplicate = true
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 2)
+}
+
+func TestPropertyPrefix(t *testing.T) {
+	s := newTestServer("arf.xml")
+	s.Config.Results.PropertyPrefix = "openscap."
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+
+	subject := pvpResults.ObservationsByCheck[0].Subjects[0]
+	require.Equal(t, "rhel10", subjectProp(subject, "openscap.hostname"))
+	require.Equal(t, "medium", subjectProp(subject, "openscap.severity"))
+	require.Empty(t, subjectProp(subject, hostnameProp))
+
+	summary := summarizeResults(pvpResults, "high", 0, s.Config.PropertyName(severityProp))
+	require.Equal(t, 1, summary.Failed)
+	require.False(t, summary.Blocking)
+}
+
+func TestTransform(t *testing.T) {
+	s := newTestServer("arf.xml")
+	s.Transform = func(observation policy.ObservationByCheck) (policy.ObservationByCheck, bool) {
+		if observation.CheckID == "aide_build_database" {
+			return observation, false
+		}
+		observation.Subjects[0].Props = append(observation.Subjects[0].Props, policy.Property{Name: "team", Value: "platform"})
+		return observation, true
+	}
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+	require.Equal(t, "package_aide_installed", pvpResults.ObservationsByCheck[0].CheckID)
+	require.Equal(t, "platform", subjectProp(pvpResults.ObservationsByCheck[0].Subjects[0], "team"))
+}
+
+func TestSelectedPolicy(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+	s := newTestServer("arf.xml")
+
+	gotPolicy, err := s.selectedPolicy(oscalPolicy)
+	require.NoError(t, err)
+	require.Equal(t, oscalPolicy, gotPolicy)
+
+	s.Config.Tailoring.SelectedRules = "configure_crypto_policy"
+	gotPolicy, err = s.selectedPolicy(oscalPolicy)
+	require.NoError(t, err)
+	require.Equal(t, testPolicy("configure_crypto_policy"), gotPolicy)
+
+	// observations only cover the selected rules
+	pvpResults, err := s.collectResults(gotPolicy)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+	require.Equal(t, "configure_crypto_policy", pvpResults.ObservationsByCheck[0].CheckID)
+
+	s.Config.Tailoring.SelectedRules = "absent_rule"
+	_, err = s.selectedPolicy(oscalPolicy)
+	require.EqualError(t, err, "selected rule absent_rule not found in policy")
+
+	// controls are mapped to rules by the references of the datastream
+	s.Config.Tailoring.SelectedRules = ""
+	s.Config.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	for controls, wantRules := range map[string][]string{
+		"sc-13":          {"configure_crypto_policy"},
+		"SC":             {"configure_crypto_policy"},
+		"ac-17":          {"configure_crypto_policy"},
+		"nist:CM-6":      {"package_aide_installed", "aide_build_database", "configure_crypto_policy"},
+		"cis:6.1, sc-13": {"package_aide_installed", "aide_build_database", "configure_crypto_policy"},
+		"cis:6.1":        {"package_aide_installed", "aide_build_database"},
+	} {
+		s.Config.Tailoring.SelectedControls = controls
+		gotPolicy, err = s.selectedPolicy(oscalPolicy)
+		require.NoError(t, err, controls)
+		require.Equal(t, testPolicy(wantRules...), gotPolicy, controls)
+	}
+
+	s.Config.Tailoring.SelectedControls = "sc-13"
+	s.Config.Tailoring.SelectedRules = "package_aide_installed"
+	_, err = s.selectedPolicy(oscalPolicy)
+	require.ErrorContains(t, err, "selected control sc-13 not mapped to any rule of the policy")
+
+	s.Config.Tailoring.SelectedRules = ""
+	s.Config.Tailoring.SelectedControls = "cis:sc-13"
+	_, err = s.selectedPolicy(oscalPolicy)
+	require.ErrorContains(t, err, "selected control cis:sc-13 not mapped to any rule of the policy")
+}
+
+func TestExpandResourceID(t *testing.T) {
+	ruleResult := xccdf.RuleResult{
+		Target: "rhel10",
+		TargetFacts: map[string]string{
+			"urn:xccdf:fact:identifier":            "9f8c3b1e",
+			"urn:xccdf:fact:asset:identifier:fqdn": "rhel10.example.com",
+		},
+	}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{name: "Valid/Default", template: "", want: "rhel10"},
+		{name: "Valid/Static", template: "asset-0042", want: "asset-0042"},
+		{name: "Valid/Fact", template: "${urn:xccdf:fact:identifier}", want: "9f8c3b1e"},
+		{name: "Valid/Combined", template: "${target}@${urn:xccdf:fact:asset:identifier:fqdn}", want: "rhel10@rhel10.example.com"},
+		{
+			name:     "Invalid/UnknownFact",
+			template: "${urn:xccdf:fact:asset:identifier:mac}",
+			wantErr:  "resource id template \"${urn:xccdf:fact:asset:identifier:mac}\" references unknown facts: urn:xccdf:fact:asset:identifier:mac",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandResourceID(tt.template, ruleResult)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	s := newTestServer("arf.xml")
+	s.Config.Results.ResourceID = "${urn:xccdf:fact:identifier}"
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+	require.Equal(t, "9f8c3b1e4d2a4c6b8e0f1a2b3c4d5e6f", pvpResults.ObservationsByCheck[0].Subjects[0].ResourceID)
+}
+
+func TestWriteArtifactsManifest(t *testing.T) {
+	workspace := t.TempDir()
+	pluginDir := filepath.Join(workspace, config.PluginDir)
+	require.NoError(t, os.MkdirAll(pluginDir, 0750))
+
+	s := New()
+	s.Config.Files.Workspace = workspace
+	s.Config.Files.Policy = filepath.Join(pluginDir, "tailoring_policy.xml")
+	require.NoError(t, os.WriteFile(s.Config.Files.Policy, []byte("<Tailoring/>"), 0600))
+	remediationFiles := map[string]oscap.GeneratedFile{
+		"bash":    {Path: filepath.Join(pluginDir, "remediation-script.sh"), CommandLine: "oscap xccdf generate fix --fix-type bash"},
+		"ansible": {Path: filepath.Join(pluginDir, "remediation-playbook.yml"), CommandLine: "oscap xccdf generate fix --fix-type ansible"},
+	}
+	for _, remediationFile := range remediationFiles {
+		require.NoError(t, os.WriteFile(remediationFile.Path, []byte("remediation"), 0600))
+	}
+
+	require.NoError(t, s.writeArtifactsManifest(remediationFiles))
+	manifest, err := artifacts.ReadManifest(artifacts.ManifestPath(workspace))
+	require.NoError(t, err)
+
+	var gotFormats []string
+	for _, artifact := range manifest.Artifacts {
+		gotFormats = append(gotFormats, artifact.Format)
+		require.NotEmpty(t, artifact.Checksum)
+		require.Empty(t, artifact.Command)
+	}
+	require.Equal(t, []string{"xccdf", "ansible", "bash"}, gotFormats)
+	require.Equal(t, artifacts.TypeTailoring, manifest.Artifacts[0].Type)
+
+	// the command lines are only recorded when enabled
+	s.Config.Scan.RecordCommands = true
+	require.NoError(t, s.writeArtifactsManifest(remediationFiles))
+	manifest, err = artifacts.ReadManifest(artifacts.ManifestPath(workspace))
+	require.NoError(t, err)
+	require.Empty(t, manifest.Artifacts[0].Command)
+	require.Equal(t, "oscap xccdf generate fix --fix-type ansible", manifest.Artifacts[1].Command)
+	require.Equal(t, "oscap xccdf generate fix --fix-type bash", manifest.Artifacts[2].Command)
+}
+
+func TestConfigureOscapCheck(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	settings := map[string]string{
+		"workspace":  t.TempDir(),
+		"datastream": filepath.Join(testDataDir, "ssg-rhel-ds.xml"),
+		"results":    "results.xml",
+		"arf":        "arf.xml",
+		"policy":     "tailoring_policy.xml",
+		"profile":    "test_profile",
+	}
+	err := New().Configure(settings)
+	require.ErrorIs(t, err, oscap.ErrNotInstalled)
+	require.ErrorContains(t, err, "oscap binary not found in PATH")
+
+	// oscap is then checked when generating
+	settings["skiposcapcheck"] = "true"
+	s := New()
+	require.NoError(t, s.Configure(settings))
+	require.ErrorIs(t, s.Generate(testPolicy("package_aide_installed")), oscap.ErrNotInstalled)
+}
+
+func TestGenerateRemediationFailure(t *testing.T) {
+	binDir := t.TempDir()
+	argsFile := filepath.Join(binDir, "args")
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "oscap"), []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\nexit 1\n"), 0700))
+	t.Setenv("PATH", binDir)
+
+	workspace := t.TempDir()
+	pluginDir := filepath.Join(workspace, config.PluginDir)
+	require.NoError(t, os.MkdirAll(pluginDir, 0750))
+	s := New()
+	s.Config.Files.Workspace = workspace
+	s.Config.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	s.Config.Files.Policy = filepath.Join(pluginDir, "tailoring_policy.xml")
+	s.Config.Parameters.Profile = "test_profile"
+
+	err := s.Generate(testPolicy("package_aide_installed"))
+	require.ErrorIs(t, err, pluginerr.ErrRemediationFailed)
+	require.ErrorContains(t, err, "oscap error during evaluation")
+	// the remediation is generated for the tailoring profile
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	require.Contains(t, string(args), "--profile test_profile_complytime --tailoring-file "+s.Config.Files.Policy)
+
+	// the tailoring is kept and recorded
+	require.NoError(t, xccdf.VerifyTailoringProfile(s.Config.Files.Policy, "test_profile"))
+	manifest, err := artifacts.ReadManifest(artifacts.ManifestPath(workspace))
+	require.NoError(t, err)
+	require.Len(t, manifest.Artifacts, 1)
+	require.Equal(t, artifacts.TypeTailoring, manifest.Artifacts[0].Type)
+}
+
+func TestGenerateValidateTailoring(t *testing.T) {
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$2" = "validate" ]; then
+	echo "File '$3' line 3: Element 'xccdf-1.2:Profile': This element is not expected."
+	exit 2
+fi
+exit 1
+`
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "oscap"), []byte(script), 0700))
+	t.Setenv("PATH", binDir)
+
+	workspace := t.TempDir()
+	pluginDir := filepath.Join(workspace, config.PluginDir)
+	require.NoError(t, os.MkdirAll(pluginDir, 0750))
+	s := New()
+	s.Config.Files.Workspace = workspace
+	s.Config.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	s.Config.Files.Policy = filepath.Join(pluginDir, "tailoring_policy.xml")
+	s.Config.Parameters.Profile = "test_profile"
+	s.Config.Tailoring.Validate = true
+
+	err := s.Generate(testPolicy("package_aide_installed"))
+	require.ErrorIs(t, err, oscap.ErrSchemaValidation)
+	require.ErrorContains(t, err, "line 3: Element 'xccdf-1.2:Profile': This element is not expected.")
+	// the invalid tailoring is not written
+	require.NoFileExists(t, s.Config.Files.Policy)
+
+	// the validation is skipped unless enabled
+	s.Config.Tailoring.Validate = false
+	require.ErrorIs(t, s.Generate(testPolicy("package_aide_installed")), pluginerr.ErrRemediationFailed)
+	require.FileExists(t, s.Config.Files.Policy)
+}
+
+func TestWriteTailoring(t *testing.T) {
+	tests := []struct {
+		name        string
+		overwrite   string
+		existing    bool
+		wantContent string
+		wantErr     string
+	}{
+		{name: "Default/Existing", existing: true, wantContent: "<new/>"},
+		{name: "Overwrite/Existing", overwrite: config.OverwriteAlways, existing: true, wantContent: "<new/>"},
+		{name: "Fail/Missing", overwrite: config.OverwriteFail, wantContent: "<new/>"},
+		{name: "Fail/Existing", overwrite: config.OverwriteFail, existing: true, wantContent: "<edited/>", wantErr: "already exists"},
+		{name: "Keep/Missing", overwrite: config.OverwriteKeep, wantContent: "<new/>"},
+		{name: "Keep/Existing", overwrite: config.OverwriteKeep, existing: true, wantContent: "<edited/>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Config.Files.Policy = filepath.Join(t.TempDir(), "tailoring_policy.xml")
+			s.Config.Tailoring.Overwrite = tt.overwrite
+			if tt.existing {
+				require.NoError(t, os.WriteFile(s.Config.Files.Policy, []byte("<edited/>"), 0600))
+			}
+
+			err := s.writeTailoring(s.Config.Files.Policy, "<new/>")
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			content, err := os.ReadFile(s.Config.Files.Policy)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantContent, string(content))
+		})
+	}
+}
+
+func TestWriteTailoringDocument(t *testing.T) {
+	s := New()
+	s.Config.Tailoring.Document = config.TailoringDocumentYAML
+	policyPath := filepath.Join(t.TempDir(), "tailoring_policy.xml")
+	require.NoError(t, os.WriteFile(policyPath, []byte(`<Tailoring xmlns="http://checklists.nist.gov/xccdf/1.2" id="tailoring">
+  <Profile id="profile" extends="base"><select idref="rule" selected="true"/></Profile>
+</Tailoring>`), 0600))
+
+	require.NoError(t, s.writeTailoringDocument(policyPath))
+	content, err := os.ReadFile(s.Config.TailoringDocumentPath(policyPath))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "id: tailoring\n")
+	require.Contains(t, string(content), "extends: base\n")
+	require.Contains(t, string(content), "- idref: rule\n    selected: true\n")
+}
+
+func TestCollectResultsARFXSLT(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{
+			// the transformation reports the failures as passing
+			name: "Valid",
+			script: `while IFS= read -r line; do
+	case "$line" in
+	*"<result>fail</result>"*) printf '%s\n' "${line%%<result>*}<result>pass</result>" ;;
+	*) printf '%s\n' "$line" ;;
+	esac
+done < "$5" > "$3"`,
+		},
+		{
+			name:    "Truncated",
+			script:  `printf '<arf:asset-report-collection>' > "$3"`,
+			wantErr: "stylesheet normalize.xsl did not produce a valid ARF",
+		},
+		{
+			name:    "Error",
+			script:  "echo 'compilation error' >&2\nexit 6",
+			wantErr: "failed to transform ARF file " + filepath.Join(testDataDir, "arf.xml") + " with normalize.xsl: exit status 6: compilation error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(binDir, "xsltproc"), []byte("#!/bin/sh\n"+tt.script+"\n"), 0700))
+			t.Setenv("PATH", binDir)
+
+			s := newTestServer("arf.xml")
+			s.Config.Results.ARFXSLT = "normalize.xsl"
+			pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database"))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, pvpResults.ObservationsByCheck, 2)
+			for _, observation := range pvpResults.ObservationsByCheck {
+				require.Equal(t, policy.ResultPass, observation.Subjects[0].Result)
+			}
+		})
+	}
+}
+
+func TestCollectResultsCheckMapping(t *testing.T) {
+	s := newTestServer("arf.xml")
+	oscalPolicy := testPolicy("aide_installed", "aide_build_database")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+
+	s.Config.Results.CheckMapping = "package_aide_installed=aide_installed"
+	pvpResults, err = s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 2)
+	var checkIDs []string
+	for _, observation := range pvpResults.ObservationsByCheck {
+		checkIDs = append(checkIDs, observation.CheckID)
+	}
+	require.ElementsMatch(t, []string{"aide_installed", "aide_build_database"}, checkIDs)
+}
+
+func TestCollectResultsLogSkipped(t *testing.T) {
+	var logs bytes.Buffer
+	previous := hclog.SetDefault(hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Info}))
+	t.Cleanup(func() { hclog.SetDefault(previous) })
+
+	s := newTestServer("arf.xml")
+	oscalPolicy := testPolicy("aide_build_database")
+	_, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.NotContains(t, logs.String(), "Skipped rule result")
+
+	s.Config.Results.LogSkipped = true
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 1)
+	require.Contains(t, logs.String(), "Skipped rule result: rule=xccdf_org.ssgproject.content_rule_package_aide_installed")
+	require.Contains(t, logs.String(), "reason=\"check package_aide_installed is not in the policy\"")
+}
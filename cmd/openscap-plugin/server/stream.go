@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/oscap"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+// StreamResults evaluates the system like GetResults, but calls fn with each
+// observation as it is read from the ARF instead of returning them all at
+// once, so the results of large scans can be processed without holding them
+// in memory. Observations are passed in ARF document order, after the
+// transform, and are neither deduplicated nor sorted. No summary, report,
+// bundle or assessment results are written. An error returned by fn stops
+// the processing of the results.
+func (s PluginServer) StreamResults(oscalPolicy policy.Policy, fn func(policy.ObservationByCheck) error) error {
+	if len(s.Config.ScanHosts()) > 0 {
+		return errors.New("results of remote hosts cannot be streamed")
+	}
+	oscalPolicy, err := s.selectedPolicy(oscalPolicy)
+	if err != nil {
+		return err
+	}
+	if err := s.Config.ResolveARF(time.Now()); err != nil {
+		return err
+	}
+	var progress oscap.ProgressFunc
+	if s.Config.Scan.Progress {
+		progress = logProgress(len(oscalPolicy))
+	}
+	dsScans, err := scan.ScanDatastreams(s.Config, s.Config.Parameters.Profile, progress)
+	if err != nil {
+		return err
+	}
+	return s.streamDatastreamResults(oscalPolicy, dsScans, fn)
+}
+
+// streamDatastreamResults calls fn with the observations read from the ARF of
+// each evaluated datastream. The datastream is set on their subjects when
+// several datastreams were evaluated.
+func (s PluginServer) streamDatastreamResults(oscalPolicy policy.Policy, dsScans []scan.DatastreamScan, fn func(policy.ObservationByCheck) error) error {
+	for _, dsScan := range dsScans {
+		hclog.Default().Debug("Streaming scan results", "arf", dsScan.ARF)
+		transformed := s.transformed(fn)
+		err := s.walkObservations(oscalPolicy, dsScan.ARF, func(observation policy.ObservationByCheck) error {
+			if len(dsScans) > 1 {
+				observation = s.withDatastream(observation, dsScan.Datastream)
+			}
+			return transformed(observation)
+		})
+		if err != nil {
+			return fmt.Errorf("datastream %s: %w", dsScan.Datastream, err)
+		}
+	}
+	return nil
+}
+
+// transformed returns a function applying the transform, if any, to each
+// observation and calling fn with the ones it does not drop.
+func (s PluginServer) transformed(fn func(policy.ObservationByCheck) error) func(policy.ObservationByCheck) error {
+	if s.Transform == nil {
+		return fn
+	}
+	return func(observation policy.ObservationByCheck) error {
+		observation, ok := s.Transform(observation)
+		if !ok {
+			return nil
+		}
+		return fn(observation)
+	}
+}
+
+// NDJSONWriter returns a function for StreamResults writing each observation
+// to w as a line of JSON.
+func NDJSONWriter(w io.Writer) func(policy.ObservationByCheck) error {
+	encoder := json.NewEncoder(w)
+	return func(observation policy.ObservationByCheck) error {
+		if err := encoder.Encode(observation); err != nil {
+			return fmt.Errorf("failed to write observation: %w", err)
+		}
+		return nil
+	}
+}
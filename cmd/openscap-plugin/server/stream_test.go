@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/scan"
+)
+
+func TestStreamDatastreamResults(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database")
+	s := New()
+	dsScans := []scan.DatastreamScan{
+		{Datastream: "/content/ssg-rhel10-ds.xml", ARF: filepath.Join(testDataDir, "arf.xml")},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, s.streamDatastreamResults(oscalPolicy, dsScans, NDJSONWriter(&buf)))
+	var checkIDs []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var observation policy.ObservationByCheck
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &observation))
+		checkIDs = append(checkIDs, observation.CheckID)
+		// the datastream is only set when several are evaluated
+		require.Empty(t, subjectProp(observation.Subjects[0], datastreamProp))
+	}
+	// observations are streamed in document order
+	require.Equal(t, []string{"package_aide_installed", "aide_build_database"}, checkIDs)
+
+	// observations dropped by the transform are not streamed
+	s.Transform = func(observation policy.ObservationByCheck) (policy.ObservationByCheck, bool) {
+		return observation, observation.CheckID != "aide_build_database"
+	}
+	dsScans = append(dsScans, scan.DatastreamScan{Datastream: "/content/app-ds.xml", ARF: filepath.Join(testDataDir, "arf.xml")})
+	datastreams := make(map[string][]string)
+	err := s.streamDatastreamResults(oscalPolicy, dsScans, func(observation policy.ObservationByCheck) error {
+		datastream := subjectProp(observation.Subjects[0], datastreamProp)
+		datastreams[datastream] = append(datastreams[datastream], observation.CheckID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"ssg-rhel10-ds.xml": {"package_aide_installed"},
+		"app-ds.xml":        {"package_aide_installed"},
+	}, datastreams)
+
+	// an error of the callback stops the stream
+	errSink := errors.New("sink unavailable")
+	calls := 0
+	err = s.streamDatastreamResults(oscalPolicy, dsScans, func(policy.ObservationByCheck) error {
+		calls++
+		return errSink
+	})
+	require.ErrorIs(t, err, errSink)
+	require.Equal(t, 1, calls)
+}
+
+func TestStreamResultsHosts(t *testing.T) {
+	s := New()
+	s.Config.Scan.Hosts = "rhel10"
+	err := s.StreamResults(testPolicy("package_aide_installed"), NDJSONWriter(&bytes.Buffer{}))
+	require.EqualError(t, err, "results of remote hosts cannot be streamed")
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+// summaryFile is the name of the results summary written next to the ARF.
+const summaryFile = "summary.json"
+
+const (
+	// severityProp is the subject property holding the severity of the evaluated rule.
+	severityProp = "severity"
+	// hostnameProp is the subject property holding the ARF target.
+	hostnameProp = "hostname"
+	// instanceProp is the subject property holding the evaluated instance
+	// of a multiply-instantiated rule.
+	instanceProp = "instance"
+	// datastreamProp is the subject property holding the file name of the
+	// datastream a rule was evaluated with, when several are evaluated.
+	datastreamProp = "datastream"
+	// remediatedProp is the subject property set when the rule failed and
+	// was remediated by oscap during the scan, which reports it as fixed.
+	remediatedProp = "remediated"
+	// notCheckedProp is the subject property set when the rule was not
+	// evaluated by oscap, which reports it as notchecked.
+	notCheckedProp = "not-checked"
+	// durationProp is the subject property holding the time taken by oscap
+	// to evaluate the rule, when the evaluation is timed.
+	durationProp = "duration"
+	// imageProp is the subject property holding the reference of the
+	// evaluated container image.
+	imageProp = "image"
+	// waivedProp, waiverJustificationProp and waiverExpiresProp are the
+	// subject properties describing the waiver of a failure.
+	waivedProp              = "waived"
+	waiverJustificationProp = "waiver-justification"
+	waiverExpiresProp       = "waiver-expires"
+	// ovalVariableProp is the subject property holding the values of a
+	// variable used by the OVAL check of the rule, as <name>=<values>.
+	ovalVariableProp = "oval-variable"
+	// benchmarkProp, benchmarkVersionProp, scannerProp,
+	// scannerVersionProp and profileProp are the subject properties
+	// describing the content and the scanner that produced the result.
+	benchmarkProp        = "benchmark"
+	benchmarkVersionProp = "benchmark-version"
+	scannerProp          = "scanner"
+	scannerVersionProp   = "scanner-version"
+	profileProp          = "profile"
+)
+
+// resultsSummary counts the results of a scan. Failures of rules below the
+// configured fail severity are counted but are not blocking, unless there are
+// more failures than the configured maximum.
+type resultsSummary struct {
+	FailSeverity     string   `json:"failSeverity,omitempty"`
+	MaxFailures      int      `json:"maxFailures,omitempty"`
+	Total            int      `json:"total"`
+	Passed           int      `json:"passed"`
+	Failed           int      `json:"failed"`
+	BlockingFailures []string `json:"blockingFailures"`
+	Blocking         bool     `json:"blocking"`
+	// Commands are the oscap command lines of the scan, when recorded.
+	Commands []string `json:"commands,omitempty"`
+	// failures are the rules of all failures, blocking or not.
+	failures []string
+}
+
+// summarizeResults builds a resultsSummary from the subjects of the given results,
+// treating failures with a severity below failSeverity as non-blocking. When
+// maxFailures is not zero and there are more failures, all failures are
+// blocking. The severity is read from the subject property named severityName.
+func summarizeResults(pvpResult policy.PVPResult, failSeverity string, maxFailures int, severityName string) resultsSummary {
+	summary := newResultsSummary(failSeverity, maxFailures)
+	for _, observation := range pvpResult.ObservationsByCheck {
+		summary.add(observation, severityName)
+	}
+	summary.finish()
+	return summary
+}
+
+// newResultsSummary returns an empty summary with the given thresholds, to
+// which observations are added one at a time.
+func newResultsSummary(failSeverity string, maxFailures int) resultsSummary {
+	return resultsSummary{
+		FailSeverity:     failSeverity,
+		MaxFailures:      maxFailures,
+		BlockingFailures: []string{},
+	}
+}
+
+// add counts the results of the subjects of the observation.
+func (s *resultsSummary) add(observation policy.ObservationByCheck, severityName string) {
+	for _, subject := range observation.Subjects {
+		s.Total++
+		switch subject.Result {
+		case policy.ResultPass:
+			s.Passed++
+		case policy.ResultFail:
+			s.Failed++
+			s.failures = append(s.failures, observation.Title)
+			if config.MeetsSeverity(subjectProp(subject, severityName), s.FailSeverity) {
+				s.BlockingFailures = append(s.BlockingFailures, observation.Title)
+			}
+		}
+	}
+}
+
+// finish makes all failures blocking when there are more than the maximum,
+// once all the observations are added.
+func (s *resultsSummary) finish() {
+	if s.MaxFailures > 0 && s.Failed > s.MaxFailures {
+		s.BlockingFailures = s.failures
+	}
+	s.Blocking = len(s.BlockingFailures) > 0
+}
+
+// Verdict returns whether the results pass the thresholds set by the
+// failseverity and maxfailures options, along with the rules whose failures
+// make them fail, for example to set the exit code of a CI job. It is the
+// same decision as the blocking field of the results summary.
+func (s PluginServer) Verdict(pvpResult policy.PVPResult) (bool, []string) {
+	summary := summarizeResults(pvpResult, s.Config.Results.FailSeverity, s.Config.Results.MaxFailures, s.Config.PropertyName(severityProp))
+	return !summary.Blocking, summary.BlockingFailures
+}
+
+// subjectProp returns the value of the named subject property, or an empty string.
+func subjectProp(subject policy.Subject, name string) string {
+	for _, prop := range subject.Props {
+		if prop.Name == name {
+			return prop.Value
+		}
+	}
+	return ""
+}
+
+// writeSummary writes the summary in the results directory and returns a link to it.
+func (s PluginServer) writeSummary(summary resultsSummary) (policy.Link, error) {
+	summaryPath := filepath.Join(filepath.Dir(s.Config.Files.ARF), summaryFile)
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return policy.Link{}, fmt.Errorf("failed to encode results summary: %w", err)
+	}
+	if err := os.WriteFile(summaryPath, data, 0600); err != nil {
+		return policy.Link{}, fmt.Errorf("failed to write results summary: %w", err)
+	}
+	return policy.Link{
+		Href:        fmt.Sprintf("file://%s", summaryPath),
+		Description: "RESULTS_SUMMARY",
+	}, nil
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeResults(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+	s := newTestServer("arf.xml")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+
+	summary := summarizeResults(pvpResults, "", 0, severityProp)
+	require.Equal(t, 3, summary.Total)
+	require.Equal(t, 1, summary.Passed)
+	require.Equal(t, 2, summary.Failed)
+	require.True(t, summary.Blocking)
+	require.Len(t, summary.BlockingFailures, 2)
+
+	// package_aide_installed is a medium severity rule, sorted last
+	summary = summarizeResults(pvpResults, "high", 0, severityProp)
+	require.Equal(t, 2, summary.Failed)
+	require.Equal(t, []string{"xccdf_org.ssgproject.content_rule_configure_crypto_policy"}, summary.BlockingFailures)
+	require.True(t, summary.Blocking)
+	// non-blocking failures keep their status
+	require.Equal(t, policy.ResultFail, pvpResults.ObservationsByCheck[2].Subjects[0].Result)
+}
+
+func TestVerdict(t *testing.T) {
+	oscalPolicy := testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy")
+	s := newTestServer("arf.xml")
+	pvpResults, err := s.collectResults(oscalPolicy)
+	require.NoError(t, err)
+
+	// package_aide_installed is a medium severity rule, below the fail severity
+	s.Config.Results.FailSeverity = "high"
+	pass, rules := s.Verdict(pvpResults)
+	require.False(t, pass)
+	require.Equal(t, []string{"xccdf_org.ssgproject.content_rule_configure_crypto_policy"}, rules)
+
+	// both failures are within the budget but only one is blocking
+	s.Config.Results.MaxFailures = 2
+	pass, rules = s.Verdict(pvpResults)
+	require.False(t, pass)
+	require.Len(t, rules, 1)
+
+	// all failures are blocking once the budget is exceeded
+	s.Config.Results.MaxFailures = 1
+	pass, rules = s.Verdict(pvpResults)
+	require.False(t, pass)
+	require.Equal(t, []string{
+		"xccdf_org.ssgproject.content_rule_configure_crypto_policy",
+		"xccdf_org.ssgproject.content_rule_package_aide_installed",
+	}, rules)
+
+	passing := policy.PVPResult{ObservationsByCheck: []policy.ObservationByCheck{
+		{Title: "rule", Subjects: []policy.Subject{{Result: policy.ResultPass}}},
+	}}
+	pass, rules = s.Verdict(passing)
+	require.True(t, pass)
+	require.Empty(t, rules)
+}
+
+func TestWriteSummary(t *testing.T) {
+	s := New()
+	s.Config.Files.ARF = filepath.Join(t.TempDir(), "arf.xml")
+
+	link, err := s.writeSummary(resultsSummary{FailSeverity: "high", Failed: 1, BlockingFailures: []string{}})
+	require.NoError(t, err)
+	require.Equal(t, "RESULTS_SUMMARY", link.Description)
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(s.Config.Files.ARF), summaryFile))
+	require.NoError(t, err)
+	var got resultsSummary
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, "high", got.FailSeverity)
+	require.False(t, got.Blocking)
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+)
+
+// writeSystemCharacteristics exports the OVAL system characteristics of the
+// ARF to the file set in the configuration and returns a link to it. It
+// returns false, without writing the file, when the ARF has no system
+// characteristics.
+func (s PluginServer) writeSystemCharacteristics() (policy.Link, bool, error) {
+	arf, err := os.Open(filepath.Clean(s.Config.Files.ARF))
+	if err != nil {
+		return policy.Link{}, false, fmt.Errorf("failed to open ARF: %w", err)
+	}
+	defer arf.Close()
+
+	path := s.Config.Results.SystemCharacteristics
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return policy.Link{}, false, fmt.Errorf("failed to create system characteristics file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	exported, err := xccdf.ExportSystemCharacteristics(arf, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && exported == 0 {
+		hclog.Default().Warn("The ARF has no OVAL system characteristics to export", "arf", s.Config.Files.ARF)
+		err = os.Remove(path)
+		return policy.Link{}, false, err
+	}
+	if err != nil {
+		return policy.Link{}, false, fmt.Errorf("failed to export system characteristics to %s: %w", path, err)
+	}
+	return policy.Link{
+		Href:        fmt.Sprintf("file://%s", path),
+		Description: "OVAL_SYSTEM_CHARACTERISTICS",
+	}, true, nil
+}
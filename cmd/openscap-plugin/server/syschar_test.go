@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSystemCharacteristics(t *testing.T) {
+	s := newTestServer("arf-oval.xml")
+	s.Config.Results.SystemCharacteristics = filepath.Join(t.TempDir(), "system-characteristics.xml")
+
+	link, ok, err := s.writeSystemCharacteristics()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "file://"+s.Config.Results.SystemCharacteristics, link.Href)
+	require.Equal(t, "OVAL_SYSTEM_CHARACTERISTICS", link.Description)
+	content, err := os.ReadFile(s.Config.Results.SystemCharacteristics)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "<ind-sys:subexpression>LEGACY</ind-sys:subexpression>")
+
+	// no file is written when the ARF has no system characteristics
+	s = newTestServer("arf.xml")
+	s.Config.Results.SystemCharacteristics = filepath.Join(t.TempDir(), "system-characteristics.xml")
+	_, ok, err = s.writeSystemCharacteristics()
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoFileExists(t, s.Config.Results.SystemCharacteristics)
+}
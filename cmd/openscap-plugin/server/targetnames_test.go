@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectResultsTargetNames(t *testing.T) {
+	dir := t.TempDir()
+	namesPath := filepath.Join(dir, "targets.json")
+	require.NoError(t, os.WriteFile(namesPath, []byte(`{"targets": {"rhel10": "rhel10.example.com"}}`), 0600))
+
+	s := newTestServer("arf.xml")
+	s.Config.Results.TargetNames = namesPath
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	subject := pvpResults.ObservationsByCheck[0].Subjects[0]
+	require.Equal(t, "Host rhel10.example.com", subject.Title)
+	require.Equal(t, "rhel10.example.com", subject.ResourceID)
+	require.Equal(t, "rhel10.example.com", subjectProp(subject, hostnameProp))
+
+	// resource id templates use the canonical name
+	s.Config.Results.ResourceID = "inventory/${target}"
+	pvpResults, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Equal(t, "inventory/rhel10.example.com", pvpResults.ObservationsByCheck[0].Subjects[0].ResourceID)
+
+	// resource groups are matched against the target reported in the ARF
+	groupsPath := filepath.Join(dir, "groups.json")
+	require.NoError(t, os.WriteFile(groupsPath, []byte(`{"groups": [
+		{"target": "rhel10", "resources": ["node-a", "node-b"]}
+	]}`), 0600))
+	s.Config.Results.ResourceID = ""
+	s.Config.Results.ResourceGroups = groupsPath
+	pvpResults, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck[0].Subjects, 2)
+
+	// unmapped targets are unchanged
+	require.NoError(t, os.WriteFile(namesPath, []byte(`{"targets": {"rhel9": "rhel9.example.com"}}`), 0600))
+	s.Config.Results.ResourceGroups = ""
+	pvpResults, err = s.collectResults(testPolicy("package_aide_installed"))
+	require.NoError(t, err)
+	require.Equal(t, "rhel10", pvpResults.ObservationsByCheck[0].Subjects[0].ResourceID)
+}
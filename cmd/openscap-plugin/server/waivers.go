@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+	"github.com/complytime/complyctl/cmd/openscap-plugin/xccdf"
+)
+
+// contentRulePrefix is the prefix of the rule ids of the content, removed to
+// match the rule ids of waivers.
+const contentRulePrefix = xccdf.XCCDFCaCNamespace + "_rule_"
+
+// applyWaiver reports the failures of a waived rule as warnings, with the
+// waiver on their subjects, so accepted risks are not counted as failures.
+// Failures of rules with an expired waiver are reported as usual.
+func (s PluginServer) applyWaiver(observation policy.ObservationByCheck, waivers map[string]config.Waiver, now time.Time) policy.ObservationByCheck {
+	ruleID := strings.TrimPrefix(observation.Title, contentRulePrefix)
+	waiver, ok := waivers[ruleID]
+	if !ok {
+		return observation
+	}
+	for i, subject := range observation.Subjects {
+		if subject.Result != policy.ResultFail {
+			continue
+		}
+		if waiver.Expired(now) {
+			hclog.Default().Warn("Waiver expired, the failure is reported", "rule", ruleID, "expires", waiver.Expires)
+			continue
+		}
+		subject.Result = policy.ResultWarning
+		subject.Reason = fmt.Sprintf("%s, waived", subject.Reason)
+		subject.Props = append(subject.Props, policy.Property{
+			Name:  s.Config.PropertyName(waivedProp),
+			Value: "true",
+		})
+		if waiver.Justification != "" {
+			subject.Props = append(subject.Props, policy.Property{
+				Name:  s.Config.PropertyName(waiverJustificationProp),
+				Value: waiver.Justification,
+			})
+		}
+		if waiver.Expires != "" {
+			subject.Props = append(subject.Props, policy.Property{
+				Name:  s.Config.PropertyName(waiverExpiresProp),
+				Value: waiver.Expires,
+			})
+		}
+		observation.Subjects[i] = subject
+	}
+	return observation
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectResultsWaivers(t *testing.T) {
+	waiversPath := filepath.Join(t.TempDir(), "waivers.json")
+	require.NoError(t, os.WriteFile(waiversPath, []byte(`{"waivers": [
+		{"rule": "package_aide_installed", "expires": "2999-12-31", "justification": "AIDE is replaced by a central agent"},
+		{"rule": "configure_crypto_policy", "expires": "2000-01-01"},
+		{"rule": "aide_build_database"}
+	]}`), 0600))
+
+	s := newTestServer("arf.xml")
+	s.Config.Results.Waivers = waiversPath
+	pvpResults, err := s.collectResults(testPolicy("package_aide_installed", "aide_build_database", "configure_crypto_policy"))
+	require.NoError(t, err)
+	require.Len(t, pvpResults.ObservationsByCheck, 3)
+
+	// passing rules are not affected by their waiver
+	passed := pvpResults.ObservationsByCheck[0].Subjects[0]
+	require.Equal(t, policy.ResultPass, passed.Result)
+	require.Empty(t, subjectProp(passed, waivedProp))
+
+	// failures with an expired waiver are reported as usual
+	expired := pvpResults.ObservationsByCheck[1].Subjects[0]
+	require.Equal(t, policy.ResultFail, expired.Result)
+	require.Empty(t, subjectProp(expired, waivedProp))
+
+	waived := pvpResults.ObservationsByCheck[2].Subjects[0]
+	require.Equal(t, policy.ResultWarning, waived.Result)
+	require.Equal(t, "openscap rule-result is fail, waived", waived.Reason)
+	require.Equal(t, "true", subjectProp(waived, waivedProp))
+	require.Equal(t, "AIDE is replaced by a central agent", subjectProp(waived, waiverJustificationProp))
+	require.Equal(t, "2999-12-31", subjectProp(waived, waiverExpiresProp))
+}
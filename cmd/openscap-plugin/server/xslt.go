@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// transformARF applies the configured XSLT stylesheet to an ARF file with
+// xsltproc and returns the transformed document, opened and checked to be a
+// complete ARF. The transformed document is removed once the file is closed.
+func (s PluginServer) transformARF(arfPath string) (*os.File, error) {
+	xsltprocPath, err := exec.LookPath("xsltproc")
+	if err != nil {
+		return nil, fmt.Errorf("command not found: xsltproc: %w", err)
+	}
+	output, err := os.CreateTemp("", "arf-transformed-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	outputPath := output.Name()
+	defer os.Remove(outputPath)
+	if err := output.Close(); err != nil {
+		return nil, err
+	}
+
+	stylesheet := s.Config.Results.ARFXSLT
+	hclog.Default().Debug("Transforming ARF file", "arf", arfPath, "stylesheet", stylesheet)
+	cmd := exec.Command(xsltprocPath, "--nonet", "--output", outputPath, stylesheet, filepath.Clean(arfPath))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to transform ARF file %s with %s: %w: %s", arfPath, stylesheet, err, strings.TrimSpace(string(out)))
+	}
+
+	// the file stays readable once removed
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyARF(file, s.Config.ARFBufferSize(), s.Config.ARFMaxSize()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("stylesheet %s did not produce a valid ARF: %w", stylesheet, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,541 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+const xccdfURI string = "http://checklists.nist.gov/xccdf/1.2"
+
+// ErrARFIncomplete is returned when an ARF is not well-formed or has no
+// results, for example when the scan was interrupted.
+var ErrARFIncomplete = errors.New("ARF appears incomplete or corrupt")
+
+// ErrARFRejected is returned for ARFs that are not parsed because they could
+// be malicious, such as documents with a document type declaration.
+var ErrARFRejected = errors.New("ARF rejected")
+
+// VerifyARF checks that an ARF read from r is well-formed and has at least
+// one TestResult with a rule-result, without decoding the elements. It is a
+// lightweight check run before the rule results are read.
+//
+// The XML decoder neither expands the entities declared in a DTD nor resolves
+// external entities, but ARFs written by oscap have no document type
+// declaration, so documents with one are rejected before they are parsed.
+func VerifyARF(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	var testResults, ruleResults int
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrARFIncomplete, err)
+		}
+		if directive, ok := token.(xml.Directive); ok && bytes.HasPrefix(bytes.TrimSpace(directive), []byte("DOCTYPE")) {
+			return fmt.Errorf("%w: document type declarations are not allowed", ErrARFRejected)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != xccdfURI {
+			continue
+		}
+		switch start.Name.Local {
+		case "TestResult":
+			testResults++
+		case "rule-result":
+			ruleResults++
+		}
+	}
+	switch {
+	case testResults == 0:
+		return fmt.Errorf("%w: no TestResult found", ErrARFIncomplete)
+	case ruleResults == 0:
+		return fmt.Errorf("%w: no rule-result found", ErrARFIncomplete)
+	}
+	return nil
+}
+
+// RuleCheck is a check referenced by a rule in the Benchmark of an ARF.
+type RuleCheck struct {
+	System string
+	Name   string
+}
+
+// RuleResult is a rule-result from an ARF combined with the checks declared
+// by the evaluated rule.
+type RuleResult struct {
+	Target string
+	// TargetFacts holds the facts collected about the target by name.
+	// It is shared by all rule results of the same TestResult.
+	TargetFacts map[string]string
+	RuleID      string
+	// Instance identifies the evaluated instance of a multiply-instantiated
+	// rule, which has a rule-result per instance.
+	Instance string
+	Result   string
+	Severity string
+	Checks   []RuleCheck
+	// OVALDetails summarizes the expected and collected values of the
+	// failing OVAL tests of a failed rule, when the ARF has OVAL results.
+	// It is only read by WalkARF.
+	OVALDetails string
+	// Messages are the messages oscap attached to the rule-result, such as
+	// the reason a rule was not checked.
+	Messages []string
+	// Time is when the rule was evaluated, from the time of the rule-result
+	// or else the end-time of its TestResult, in UTC. It is the zero time
+	// when the ARF has neither.
+	Time time.Time
+	// OVALVariables are the values of the variables used by the OVAL
+	// checks of the rule, sorted by id, when the ARF has OVAL results. It
+	// is only read by WalkARF.
+	OVALVariables []OVALVariable
+	// Provenance describes the content and the scanner of the TestResult.
+	// It is shared by all rule results of the same TestResult.
+	Provenance *Provenance
+}
+
+// OVALVariable is the value of a variable used by an OVAL check.
+type OVALVariable struct {
+	// ID is the id of the OVAL variable.
+	ID string
+	// ValueID is the id of the XCCDF Value exported to the variable by the
+	// rule, if any.
+	ValueID string
+	Values  []string
+}
+
+// RuleResultFunc is called for every rule-result found in an ARF whose rule
+// is defined in the ARF Benchmark.
+type RuleResultFunc func(RuleResult) error
+
+// matchesRulePrefix reports whether the id of a rule, without the prefix of
+// the content rule ids, starts with rulePrefix. An empty rulePrefix matches
+// every rule.
+func matchesRulePrefix(ruleID, rulePrefix string) bool {
+	return strings.HasPrefix(removePrefix(ruleID, ruleIDPrefix), rulePrefix)
+}
+
+// arfTimeLayouts are the layouts of the times of an ARF, such as the end-time
+// of a TestResult, with or without a time zone depending on the oscap version.
+var arfTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05"}
+
+// parseARFTime returns the time of an ARF attribute in UTC, or the zero time
+// when it is missing or invalid. oscap writes times without a time zone in the
+// local time of the scanned system, so they are read in the local time zone,
+// which is the time zone of the scanned system unless it is scanned remotely.
+func parseARFTime(value string) time.Time {
+	for _, layout := range arfTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// ruleResultTime returns the time a rule-result was evaluated at, from its
+// time attribute or else the end-time of its TestResult.
+func ruleResultTime(ruleTime string, testResultEnd time.Time) time.Time {
+	if t := parseARFTime(ruleTime); !t.IsZero() {
+		return t
+	}
+	return testResultEnd
+}
+
+// selectTestResult returns the TestResult of an ARF document with the given
+// id or, when testResultID is empty, the latest one by end-time. TestResults
+// ending at the same time are ordered as in the document.
+func selectTestResult(arfDom *xmlquery.Node, testResultID string) (*xmlquery.Node, error) {
+	var selected *xmlquery.Node
+	var selectedEnd time.Time
+	for _, testResult := range arfDom.SelectElements("//TestResult") {
+		if testResultID != "" {
+			if testResult.SelectAttr("id") == testResultID {
+				return testResult, nil
+			}
+			continue
+		}
+		end := parseARFTime(testResult.SelectAttr("end-time"))
+		if selected == nil || !end.Before(selectedEnd) {
+			selected, selectedEnd = testResult, end
+		}
+	}
+	if testResultID != "" {
+		return nil, fmt.Errorf("%w: no TestResult with id %q", pluginerr.ErrARFParse, testResultID)
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("%w: no TestResult found", pluginerr.ErrARFParse)
+	}
+	return selected, nil
+}
+
+// WalkARF calls fn for each rule-result in an ARF document already loaded
+// in memory whose rule id starts with rulePrefix. Only the rule-results of
+// the TestResult with id testResultID, or of the latest TestResult when it is
+// empty, are visited, so results of previous evaluations stored in the same
+// ARF are not mixed with the current ones. The values of the OVAL variables of
+// the checks are only read from the OVAL results when withOVALVariables is set.
+func WalkARF(arfDom *xmlquery.Node, rulePrefix, testResultID string, withOVALVariables bool, fn RuleResultFunc) error {
+	testResult, err := selectTestResult(arfDom, testResultID)
+	if err != nil {
+		return err
+	}
+	targetEl := testResult.SelectElement("target")
+	if targetEl == nil {
+		return fmt.Errorf("%w: result has no 'target' attribute", pluginerr.ErrARFParse)
+	}
+	target := targetEl.InnerText()
+	end := parseARFTime(testResult.SelectAttr("end-time"))
+	facts := make(map[string]string)
+	for _, fact := range testResult.SelectElements("target-facts/fact") {
+		facts[fact.SelectAttr("name")] = fact.InnerText()
+	}
+	provenance := newProvenance(testResult.SelectAttr("test-system"), testResult.SelectAttr("version"))
+	if benchmark := testResult.SelectElement("benchmark"); benchmark != nil {
+		provenance.Benchmark = benchmark.SelectAttr("id")
+	}
+	if profile := testResult.SelectElement("profile"); profile != nil {
+		provenance.Profile = profile.SelectAttr("idref")
+	}
+
+	ruleTable := NewRuleHashTable(arfDom)
+	ovalDetails := readOVALDetails(arfDom)
+	var ovalVariables map[string][]OVALVariable
+	if withOVALVariables {
+		ovalVariables = readOVALVariables(arfDom)
+	}
+	for _, result := range testResult.SelectElements("rule-result") {
+		ruleIDRef := result.SelectAttr("idref")
+		if !matchesRulePrefix(ruleIDRef, rulePrefix) {
+			continue
+		}
+		rule, ok := ruleTable[ruleIDRef]
+		if !ok {
+			continue
+		}
+
+		var checks []RuleCheck
+		for _, check := range rule.SelectElements("//xccdf-1.2:check") {
+			checkRef := check.SelectElement("xccdf-1.2:check-content-ref")
+			if checkRef == nil {
+				continue
+			}
+			checks = append(checks, RuleCheck{
+				System: check.SelectAttr("system"),
+				Name:   checkRef.SelectAttr("name"),
+			})
+		}
+
+		var resultValue string
+		if resultEl := result.SelectElement("result"); resultEl != nil {
+			resultValue = resultEl.InnerText()
+		}
+		var instance string
+		if instanceEl := result.SelectElement("instance"); instanceEl != nil {
+			instance = instanceEl.InnerText()
+		}
+		var messages []string
+		for _, message := range result.SelectElements("message") {
+			messages = append(messages, strings.TrimSpace(message.InnerText()))
+		}
+		var details []string
+		if resultValue == "fail" {
+			for _, check := range checks {
+				if detail, ok := ovalDetails[check.Name]; ok {
+					details = append(details, detail)
+				}
+			}
+		}
+
+		exports := make(map[string]string)
+		for _, export := range result.SelectElements("check/check-export") {
+			exports[export.SelectAttr("export-name")] = export.SelectAttr("value-id")
+		}
+		var variables []OVALVariable
+		for _, check := range checks {
+			for _, variable := range ovalVariables[check.Name] {
+				variable.ValueID = exports[variable.ID]
+				variables = append(variables, variable)
+			}
+		}
+
+		ruleResult := RuleResult{
+			Target:        target,
+			TargetFacts:   facts,
+			RuleID:        ruleIDRef,
+			Instance:      instance,
+			Result:        resultValue,
+			Severity:      rule.SelectAttr("severity"),
+			Checks:        checks,
+			OVALDetails:   strings.Join(details, "; "),
+			Messages:      messages,
+			Time:          ruleResultTime(result.SelectAttr("time"), end),
+			OVALVariables: variables,
+			Provenance:    provenance,
+		}
+		if err := fn(ruleResult); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The following structs are used by StreamARF to decode single elements
+// of the ARF without loading the whole document.
+type arfCheckContentRef struct {
+	Name string `xml:"name,attr"`
+}
+
+type arfCheck struct {
+	System      string               `xml:"system,attr"`
+	ContentRefs []arfCheckContentRef `xml:"check-content-ref"`
+}
+
+type arfRule struct {
+	ID            string     `xml:"id,attr"`
+	Severity      string     `xml:"severity,attr"`
+	Checks        []arfCheck `xml:"check"`
+	ComplexChecks []arfCheck `xml:"complex-check>check"`
+}
+
+// arfRuleInfo is the rule information kept by StreamARF while decoding.
+type arfRuleInfo struct {
+	severity string
+	checks   []RuleCheck
+}
+
+type arfTargetFacts struct {
+	Facts []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"fact"`
+}
+
+type arfRuleResult struct {
+	IDRef    string   `xml:"idref,attr"`
+	Time     string   `xml:"time,attr"`
+	Result   *string  `xml:"result"`
+	Instance string   `xml:"instance"`
+	Messages []string `xml:"message"`
+}
+
+// StreamARF calls fn for each rule-result in an ARF read incrementally from
+// r whose rule id starts with rulePrefix. Only the rule checks and severities
+// are kept in memory, so it is suitable for ARF files too large to be loaded
+// as a document tree. Rules and rule-results not matching rulePrefix are
+// skipped without being decoded. The ARF is expected to declare the Benchmark
+// before the TestResult, as produced by oscap. The OVAL results, which follow
+// the TestResult, are not read.
+//
+// As WalkARF, only the rule-results of the TestResult with id testResultID
+// are visited, as they are read. When testResultID is empty, the latest
+// TestResult is found by a first pass over the ARF, which only reads the
+// end-time of the TestResults, and r is then read again from the start.
+func StreamARF(r io.ReadSeeker, rulePrefix, testResultID string, fn RuleResultFunc) error {
+	// selected is the position of the latest TestResult in the ARF
+	selected := -1
+	if testResultID == "" {
+		var err error
+		if selected, err = latestTestResult(r); err != nil {
+			return err
+		}
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	decoder := xml.NewDecoder(r)
+	rules := make(map[string]arfRuleInfo)
+	var target string
+	var targetFound bool
+	var facts map[string]string
+	// provenance is set while a TestResult is read
+	var provenance *Provenance
+	var end time.Time
+	// testResults counts all the TestResults read and visited the ones
+	// whose rule results are visited
+	var testResults, visited int
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", pluginerr.ErrARFParse, err)
+		}
+
+		if end, ok := token.(xml.EndElement); ok && end.Name.Space == xccdfURI && end.Name.Local == "TestResult" {
+			if !targetFound {
+				return fmt.Errorf("%w: result has no 'target' attribute", pluginerr.ErrARFParse)
+			}
+			provenance = nil
+			continue
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != xccdfURI {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Rule":
+			if !matchesRulePrefix(startAttr(start, "id"), rulePrefix) {
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("%w: invalid rule: %w", pluginerr.ErrARFParse, err)
+				}
+				continue
+			}
+			var rule arfRule
+			if err := decoder.DecodeElement(&rule, &start); err != nil {
+				return fmt.Errorf("%w: invalid rule: %w", pluginerr.ErrARFParse, err)
+			}
+			var checks []RuleCheck
+			for _, check := range append(rule.Checks, rule.ComplexChecks...) {
+				if len(check.ContentRefs) == 0 {
+					continue
+				}
+				checks = append(checks, RuleCheck{
+					System: check.System,
+					Name:   check.ContentRefs[0].Name,
+				})
+			}
+			rules[rule.ID] = arfRuleInfo{severity: rule.Severity, checks: checks}
+		case "TestResult":
+			testResults++
+			if (testResultID != "" && startAttr(start, "id") != testResultID) || (testResultID == "" && testResults-1 != selected) {
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("%w: invalid TestResult: %w", pluginerr.ErrARFParse, err)
+				}
+				continue
+			}
+			visited++
+			target, targetFound = "", false
+			facts = make(map[string]string)
+			provenance = newProvenance(startAttr(start, "test-system"), startAttr(start, "version"))
+			end = parseARFTime(startAttr(start, "end-time"))
+		case "benchmark":
+			// the tailoring also references its benchmark
+			if provenance != nil {
+				provenance.Benchmark = startAttr(start, "id")
+			}
+		case "profile":
+			if provenance != nil {
+				provenance.Profile = startAttr(start, "idref")
+			}
+		case "target":
+			if targetFound {
+				continue
+			}
+			if err := decoder.DecodeElement(&target, &start); err != nil {
+				return fmt.Errorf("%w: invalid target: %w", pluginerr.ErrARFParse, err)
+			}
+			targetFound = true
+		case "target-facts":
+			var targetFacts arfTargetFacts
+			if err := decoder.DecodeElement(&targetFacts, &start); err != nil {
+				return fmt.Errorf("%w: invalid target facts: %w", pluginerr.ErrARFParse, err)
+			}
+			for _, fact := range targetFacts.Facts {
+				facts[fact.Name] = fact.Value
+			}
+		case "rule-result":
+			if !matchesRulePrefix(startAttr(start, "idref"), rulePrefix) {
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("%w: invalid rule-result: %w", pluginerr.ErrARFParse, err)
+				}
+				continue
+			}
+			var result arfRuleResult
+			if err := decoder.DecodeElement(&result, &start); err != nil {
+				return fmt.Errorf("%w: invalid rule-result: %w", pluginerr.ErrARFParse, err)
+			}
+			if !targetFound {
+				return fmt.Errorf("%w: result has no 'target' attribute", pluginerr.ErrARFParse)
+			}
+			rule, ok := rules[result.IDRef]
+			if !ok {
+				continue
+			}
+			var resultValue string
+			if result.Result != nil {
+				resultValue = *result.Result
+			}
+			var messages []string
+			for _, message := range result.Messages {
+				messages = append(messages, strings.TrimSpace(message))
+			}
+			ruleResult := RuleResult{
+				Target:      target,
+				TargetFacts: facts,
+				RuleID:      result.IDRef,
+				Instance:    result.Instance,
+				Result:      resultValue,
+				Severity:    rule.severity,
+				Checks:      rule.checks,
+				Messages:    messages,
+				Time:        ruleResultTime(result.Time, end),
+				Provenance:  provenance,
+			}
+			if err := fn(ruleResult); err != nil {
+				return err
+			}
+		}
+	}
+
+	if visited == 0 {
+		return fmt.Errorf("%w: no TestResult with id %q", pluginerr.ErrARFParse, testResultID)
+	}
+	return nil
+}
+
+// latestTestResult returns the position in the ARF read from r of its
+// latest TestResult by end-time. TestResults ending at the same time are
+// ordered as in the document. Only the TestResult elements are decoded.
+func latestTestResult(r io.Reader) (int, error) {
+	decoder := xml.NewDecoder(r)
+	latest := -1
+	var latestEnd time.Time
+	for testResults := 0; ; {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", pluginerr.ErrARFParse, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != xccdfURI || start.Name.Local != "TestResult" {
+			continue
+		}
+		if end := parseARFTime(startAttr(start, "end-time")); latest < 0 || !end.Before(latestEnd) {
+			latest, latestEnd = testResults, end
+		}
+		testResults++
+	}
+	if latest < 0 {
+		return 0, fmt.Errorf("%w: no TestResult found", pluginerr.ErrARFParse)
+	}
+	return latest, nil
+}
+
+// startAttr returns the value of the unqualified attribute name of an element.
+func startAttr(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,317 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+// collectRuleResults returns a RuleResultFunc appending the visited rule
+// results to the given slice.
+func collectRuleResults(ruleResults *[]RuleResult) RuleResultFunc {
+	return func(ruleResult RuleResult) error {
+		*ruleResults = append(*ruleResults, ruleResult)
+		return nil
+	}
+}
+
+func TestWalkARF(t *testing.T) {
+	arfDom, err := LoadDsTest(t, "arf.xml")
+	require.NoError(t, err)
+
+	var ruleResults []RuleResult
+	require.NoError(t, WalkARF(arfDom, "", "", false, collectRuleResults(&ruleResults)))
+	require.Len(t, ruleResults, 5)
+
+	want := RuleResult{
+		Target: "rhel10",
+		TargetFacts: map[string]string{
+			"urn:xccdf:fact:scanner:name":               "OpenSCAP",
+			"urn:xccdf:fact:scanner:version":            "1.3.10",
+			"urn:xccdf:fact:asset:identifier:fqdn":      "rhel10.example.com",
+			"urn:xccdf:fact:asset:identifier:host_name": "rhel10",
+			"urn:xccdf:fact:identifier":                 "9f8c3b1e4d2a4c6b8e0f1a2b3c4d5e6f",
+			"urn:xccdf:fact:asset:identifier:ipv4":      "127.0.0.1",
+		},
+		RuleID:   "xccdf_org.ssgproject.content_rule_package_aide_installed",
+		Result:   "fail",
+		Severity: "medium",
+		Checks: []RuleCheck{
+			{
+				System: "http://oval.mitre.org/XMLSchema/oval-definitions-5",
+				Name:   "oval:ssg-package_aide_installed:def:1",
+			},
+			{
+				System: "http://scap.nist.gov/schema/ocil/2",
+				Name:   "ocil:ssg-package_aide_installed_ocil:questionnaire:1",
+			},
+		},
+		Time: time.Date(2025, 6, 10, 10, 0, 1, 0, time.UTC),
+		Provenance: &Provenance{
+			Benchmark:        "xccdf_org.ssgproject.content_benchmark_RHEL-10",
+			BenchmarkVersion: "0.1.76",
+			Scanner:          "openscap",
+			ScannerVersion:   "1.3.10",
+			Profile:          "xccdf_complytime.openscapplugin_profile_test_profile_complytime",
+		},
+	}
+	require.Equal(t, want, ruleResults[0])
+
+	// failed rules are described from the OVAL results when available
+	arfDom, err = LoadDsTest(t, "arf-oval.xml")
+	require.NoError(t, err)
+	ruleResults = nil
+	require.NoError(t, WalkARF(arfDom, "", "", false, collectRuleResults(&ruleResults)))
+	require.Equal(t, "expected at least one item, found none", ruleResults[0].OVALDetails)
+	require.Empty(t, ruleResults[1].OVALDetails)
+	// the OVAL variables are only read when requested
+	for _, ruleResult := range ruleResults {
+		require.Empty(t, ruleResult.OVALVariables)
+	}
+	ruleResults = nil
+	require.NoError(t, WalkARF(arfDom, "", "", true, collectRuleResults(&ruleResults)))
+	require.Empty(t, ruleResults[0].OVALVariables)
+	require.Equal(t, "xccdf_org.ssgproject.content_rule_configure_crypto_policy", ruleResults[3].RuleID)
+	require.Equal(t, []OVALVariable{{
+		ID:      "oval:ssg-var_system_crypto_policy:var:1",
+		ValueID: "xccdf_org.ssgproject.content_value_var_system_crypto_policy",
+		Values:  []string{"DEFAULT"},
+	}}, ruleResults[3].OVALVariables)
+
+	noTarget, err := xmlquery.Parse(strings.NewReader(`<TestResult><rule-result idref="rule"/></TestResult>`))
+	require.NoError(t, err)
+	require.EqualError(t, WalkARF(noTarget, "", "", false, collectRuleResults(&ruleResults)), "error parsing ARF [code=arf-parse]: result has no 'target' attribute")
+}
+
+// TestStreamARF ensures the streaming parser returns the same rule results
+// as the tree-based parser.
+func TestStreamARF(t *testing.T) {
+	arfDom, err := LoadDsTest(t, "arf.xml")
+	require.NoError(t, err)
+	var treeResults []RuleResult
+	require.NoError(t, WalkARF(arfDom, "", "", false, collectRuleResults(&treeResults)))
+
+	file, err := os.Open(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	defer file.Close()
+	var streamResults []RuleResult
+	require.NoError(t, StreamARF(file, "", "", collectRuleResults(&streamResults)))
+	require.Equal(t, treeResults, streamResults)
+
+	// rule-results are visited as they are read, not once the whole ARF is
+	// read
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	reader := strings.NewReader(string(content))
+	errStop := errors.New("stop")
+	require.ErrorIs(t, StreamARF(reader, "", "", func(RuleResult) error { return errStop }), errStop)
+	require.Positive(t, reader.Len())
+
+	// rule-results without a time are evaluated at the end of their TestResult
+	noTime := `<Benchmark xmlns="http://checklists.nist.gov/xccdf/1.2"><Rule id="rule"/>` +
+		`<TestResult end-time="2025-06-10T10:05:00"><target>host</target>` +
+		`<rule-result idref="rule"><result>pass</result></rule-result></TestResult></Benchmark>`
+	streamResults = nil
+	require.NoError(t, StreamARF(strings.NewReader(noTime), "", "", collectRuleResults(&streamResults)))
+	require.Len(t, streamResults, 1)
+	// times without a time zone are in the local time of the scanned system
+	require.Equal(t, time.Date(2025, 6, 10, 10, 5, 0, 0, time.Local).UTC(), streamResults[0].Time)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "Invalid/NoTarget",
+			content: `<TestResult xmlns="http://checklists.nist.gov/xccdf/1.2"></TestResult>`,
+			wantErr: "error parsing ARF [code=arf-parse]: result has no 'target' attribute",
+		},
+		{
+			name:    "Invalid/Truncated",
+			content: `<TestResult xmlns="http://checklists.nist.gov/xccdf/1.2"><target>host</target>`,
+			wantErr: "error parsing ARF [code=arf-parse]: XML syntax error on line 1: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ruleResults []RuleResult
+			err := StreamARF(strings.NewReader(tt.content), "", "", collectRuleResults(&ruleResults))
+			require.EqualError(t, err, tt.wantErr)
+			require.ErrorIs(t, err, pluginerr.ErrARFParse)
+		})
+	}
+}
+
+func TestARFRulePrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		rulePrefix string
+		wantRules  []string
+	}{
+		{
+			name:       "Valid/Empty",
+			rulePrefix: "",
+			wantRules: []string{
+				"xccdf_org.ssgproject.content_rule_package_aide_installed",
+				"xccdf_org.ssgproject.content_rule_aide_build_database",
+				"xccdf_org.ssgproject.content_rule_security_patches_up_to_date",
+				"xccdf_org.ssgproject.content_rule_configure_crypto_policy",
+				"xccdf_org.ssgproject.content_rule_configure_ssh_crypto_policy",
+			},
+		},
+		{
+			name:       "Valid/Prefix",
+			rulePrefix: "configure_",
+			wantRules: []string{
+				"xccdf_org.ssgproject.content_rule_configure_crypto_policy",
+				"xccdf_org.ssgproject.content_rule_configure_ssh_crypto_policy",
+			},
+		},
+		{
+			name:       "Valid/NoMatch",
+			rulePrefix: "sshd_",
+		},
+	}
+	arfDom, err := LoadDsTest(t, "arf.xml")
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var treeResults []RuleResult
+			require.NoError(t, WalkARF(arfDom, tt.rulePrefix, "", false, collectRuleResults(&treeResults)))
+			var treeRules []string
+			for _, ruleResult := range treeResults {
+				treeRules = append(treeRules, ruleResult.RuleID)
+			}
+			require.Equal(t, tt.wantRules, treeRules)
+
+			file, err := os.Open(filepath.Join(testDataDir, "arf.xml"))
+			require.NoError(t, err)
+			defer file.Close()
+			var streamResults []RuleResult
+			require.NoError(t, StreamARF(file, tt.rulePrefix, "", collectRuleResults(&streamResults)))
+			require.Equal(t, treeResults, streamResults)
+		})
+	}
+}
+
+// previousTestResultID is the id of the TestResult of a previous evaluation
+// added by writeRepeatedARF.
+const previousTestResultID = "xccdf_org.open-scap_testresult_previous"
+
+// writeRepeatedARF writes a copy of arf.xml with a TestResult of a previous
+// evaluation, where all rules passed, after the TestResult of the fixture.
+func writeRepeatedARF(t *testing.T) string {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	arf := string(content)
+	start := strings.Index(arf, "<TestResult ")
+	end := strings.Index(arf, "</TestResult>") + len("</TestResult>")
+	previous := arf[start:end]
+	previous = strings.Replace(previous, `id="xccdf_org.open-scap_testresult_xccdf_complytime.openscapplugin_profile_test_profile_complytime"`,
+		`id="`+previousTestResultID+`"`, 1)
+	previous = strings.Replace(previous, `end-time="2025-06-10T10:05:00+00:00"`, `end-time="2025-06-09T10:05:00+00:00"`, 1)
+	previous = strings.ReplaceAll(previous, "<result>fail</result>", "<result>pass</result>")
+	arfPath := filepath.Join(t.TempDir(), "arf.xml")
+	require.NoError(t, os.WriteFile(arfPath, []byte(arf[:end]+previous+arf[end:]), 0600))
+	return arfPath
+}
+
+func TestARFTestResult(t *testing.T) {
+	arfPath := writeRepeatedARF(t)
+	arfDom, err := loadDataStream(arfPath)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		testResultID string
+		wantResult   string
+		wantErr      string
+	}{
+		// the latest TestResult is selected regardless of the document order
+		{name: "Valid/Latest", wantResult: "fail"},
+		{name: "Valid/ID", testResultID: previousTestResultID, wantResult: "pass"},
+		{name: "Invalid/ID", testResultID: "unknown", wantErr: `error parsing ARF [code=arf-parse]: no TestResult with id "unknown"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var treeResults []RuleResult
+			err := WalkARF(arfDom, "", tt.testResultID, false, collectRuleResults(&treeResults))
+			file, openErr := os.Open(arfPath)
+			require.NoError(t, openErr)
+			defer file.Close()
+			var streamResults []RuleResult
+			streamErr := StreamARF(file, "", tt.testResultID, collectRuleResults(&streamResults))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.EqualError(t, streamErr, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, streamErr)
+			// rule-results of the other TestResult are not mixed in
+			require.Len(t, treeResults, 5)
+			require.Equal(t, tt.wantResult, treeResults[0].Result)
+			require.Equal(t, treeResults, streamResults)
+		})
+	}
+}
+
+func TestVerifyARF(t *testing.T) {
+	file, err := os.Open(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, VerifyARF(file))
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "Invalid/Truncated",
+			content: `<TestResult xmlns="http://checklists.nist.gov/xccdf/1.2"><rule-result idref="rule">`,
+			wantErr: "ARF appears incomplete or corrupt: XML syntax error on line 1: unexpected EOF",
+		},
+		{
+			name:    "Invalid/NoTestResult",
+			content: `<Benchmark xmlns="http://checklists.nist.gov/xccdf/1.2"></Benchmark>`,
+			wantErr: "ARF appears incomplete or corrupt: no TestResult found",
+		},
+		{
+			name:    "Invalid/NoRuleResult",
+			content: `<TestResult xmlns="http://checklists.nist.gov/xccdf/1.2"><target>host</target></TestResult>`,
+			wantErr: "ARF appears incomplete or corrupt: no rule-result found",
+		},
+		{
+			name:    "Invalid/Empty",
+			content: "",
+			wantErr: "ARF appears incomplete or corrupt: no TestResult found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyARF(strings.NewReader(tt.content))
+			require.EqualError(t, err, tt.wantErr)
+			require.ErrorIs(t, err, ErrARFIncomplete)
+		})
+	}
+
+	// entity declarations of a DTD are rejected before any entity is used
+	billionLaughs := `<?xml version="1.0"?>
+<!DOCTYPE lolz [<!ENTITY lol "lol"><!ENTITY lol2 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">]>
+<TestResult xmlns="http://checklists.nist.gov/xccdf/1.2"><rule-result idref="rule">&lol2;</rule-result></TestResult>`
+	err = VerifyARF(strings.NewReader(billionLaughs))
+	require.EqualError(t, err, "ARF rejected: document type declarations are not allowed")
+	require.ErrorIs(t, err, ErrARFRejected)
+}
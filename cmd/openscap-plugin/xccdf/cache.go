@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+// TailoringCache memoizes the tailoring files generated by PolicyToXML, so
+// generating the tailoring of the same policy again returns immediately. It
+// holds at most a fixed number of tailoring files, evicting the least recently
+// used first. It is safe for concurrent use.
+type TailoringCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// tailoringCacheEntry is a tailoring file generated for a key.
+type tailoringCacheEntry struct {
+	key          string
+	tailoringXML string
+	skippedRules []string
+}
+
+// NewTailoringCache returns a cache holding at most size tailoring files.
+func NewTailoringCache(size int) *TailoringCache {
+	return &TailoringCache{
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// tailoringCacheKey identifies the inputs of a tailoring file: the policy, the
+// profile, the base profile, the content of the datastream and the
+// namespaces, so a change to any of them results in a new tailoring file.
+func tailoringCacheKey(oscalPolicy policy.Policy, cfg *config.Config) (string, error) {
+	policyContent, err := json.Marshal(oscalPolicy)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode policy: %w", err)
+	}
+	dsChecksum, err := config.FileChecksum(cfg.Files.Datastream)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	inputs := [][]byte{
+		policyContent,
+		[]byte(cfg.Parameters.Profile),
+		[]byte(cfg.BaseProfile()),
+		[]byte(cfg.Files.Datastream),
+		[]byte(dsChecksum),
+		[]byte(cfg.NamespacePrefix()),
+		[]byte(cfg.Tailoring.Namespaces),
+	}
+	for _, input := range inputs {
+		// inputs are length-prefixed so they cannot run into each other
+		fmt.Fprintf(hash, "%d:%s", len(input), input)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// PolicyToXML returns the tailoring file generated by PolicyToXML for the
+// policy and the configuration, generating it only when it is not cached.
+// A cached tailoring file keeps the version time of its generation.
+func (c *TailoringCache) PolicyToXML(oscalPolicy policy.Policy, cfg *config.Config) (string, []string, error) {
+	key, err := tailoringCacheKey(oscalPolicy, cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		entry := element.Value.(*tailoringCacheEntry)
+		c.mu.Unlock()
+		return entry.tailoringXML, entry.skippedRules, nil
+	}
+	c.mu.Unlock()
+
+	tailoringXML, skippedRules, err := PolicyToXML(oscalPolicy, cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&tailoringCacheEntry{key: key, tailoringXML: tailoringXML, skippedRules: skippedRules})
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*tailoringCacheEntry).key)
+		}
+	}
+	return tailoringXML, skippedRules, nil
+}
+
+// Len returns the number of cached tailoring files.
+func (c *TailoringCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
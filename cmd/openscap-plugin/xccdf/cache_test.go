@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/oscal-compass/oscal-sdk-go/extensions"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+// TestTailoringCache tests that tailoring files are generated once per policy,
+// profile and datastream content, and that the cache is bounded.
+func TestTailoringCache(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(testDataDir, "ssg-rhel-ds.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsPath := filepath.Join(t.TempDir(), "ssg-rhel-ds.xml")
+	if err := os.WriteFile(dsPath, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := new(config.Config)
+	cfg.Files.Datastream = dsPath
+	cfg.Parameters.Profile = "test_profile"
+	policyA := policy.Policy{{Rule: extensions.Rule{ID: "account_unique_id"}}}
+	policyB := policy.Policy{{Rule: extensions.Rule{ID: "package_telnet_removed"}}}
+
+	cache := NewTailoringCache(2)
+	first, _, err := cache.PolicyToXML(policyA, cfg)
+	if err != nil {
+		t.Fatalf("PolicyToXML() error = %v", err)
+	}
+	second, _, err := cache.PolicyToXML(policyA, cfg)
+	if err != nil {
+		t.Fatalf("PolicyToXML() error = %v", err)
+	}
+	if first != second || cache.Len() != 1 {
+		t.Errorf("PolicyToXML() did not return the cached tailoring, cache has %d entries", cache.Len())
+	}
+
+	if _, _, err := cache.PolicyToXML(policyB, cfg); err != nil {
+		t.Fatalf("PolicyToXML() error = %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("PolicyToXML() with another policy, cache has %d entries, want 2", cache.Len())
+	}
+	keyB, err := tailoringCacheKey(policyB, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a change of the datastream content results in a new tailoring, and
+	// the least recently used one is evicted
+	if err := os.WriteFile(dsPath, append(content, '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cache.PolicyToXML(policyA, cfg); err != nil {
+		t.Fatalf("PolicyToXML() error = %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("PolicyToXML() with a changed datastream, cache has %d entries, want 2", cache.Len())
+	}
+	if _, ok := cache.entries[keyB]; !ok {
+		t.Errorf("PolicyToXML() evicted the most recently used tailoring")
+	}
+}
@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+// cceSystem is the system of the CCE identifiers of rules.
+const cceSystem = "https://ncp.nist.gov/cce"
+
+// CatalogReference is a control of a framework a rule is mapped to.
+type CatalogReference struct {
+	// Framework is the short name the benchmark gives to the framework,
+	// such as nist or cis, or the href of the reference when the
+	// benchmark does not name it.
+	Framework string `json:"framework"`
+	Control   string `json:"control"`
+}
+
+// CatalogRule describes a rule of a datastream for policy authoring.
+type CatalogRule struct {
+	// ID is the id of the rule as used in policies, without the prefix of
+	// the content rule ids.
+	ID         string             `json:"id"`
+	Title      string             `json:"title"`
+	Severity   string             `json:"severity,omitempty"`
+	CCE        string             `json:"cce,omitempty"`
+	References []CatalogReference `json:"references,omitempty"`
+}
+
+// GetDsRuleCatalog returns the rules of a datastream with their metadata and
+// the controls they are mapped to. Rules are sorted by id and their
+// references by framework, keeping the order of the datastream within a
+// framework, so the catalog of a datastream is always the same.
+func GetDsRuleCatalog(dsPath string) ([]CatalogRule, error) {
+	dsDom, err := loadDataStream(dsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", pluginerr.ErrDatastreamInvalid, err)
+	}
+
+	frameworks, err := getDsFrameworks(dsDom)
+	if err != nil {
+		return nil, err
+	}
+	dsRules, err := getDsElements(dsDom, "//xccdf-1.2:Rule")
+	if err != nil {
+		return nil, fmt.Errorf("error getting rules from datastream: %w", err)
+	}
+
+	catalog := []CatalogRule{}
+	for _, rule := range dsRules {
+		ruleID, err := getDsElementAttrValue(rule, "id")
+		if err != nil {
+			return nil, fmt.Errorf("error getting value of 'id' attribute: %w", err)
+		}
+		catalogRule := CatalogRule{
+			ID:       removePrefix(ruleID, ruleIDPrefix),
+			Severity: getDsOptionalAttrValue(rule, "severity"),
+		}
+		ruleTitle, err := getDsElementTitle(rule)
+		if err != nil {
+			return nil, fmt.Errorf("error getting rule title: %w", err)
+		}
+		if ruleTitle != nil {
+			catalogRule.Title = ruleTitle.InnerText()
+		}
+
+		idents, err := getDsElements(rule, "xccdf-1.2:ident")
+		if err != nil {
+			return nil, fmt.Errorf("error getting identifiers of rule %s: %w", ruleID, err)
+		}
+		for _, ident := range idents {
+			if getDsOptionalAttrValue(ident, "system") == cceSystem {
+				catalogRule.CCE = ident.InnerText()
+				break
+			}
+		}
+
+		references, err := getDsElements(rule, "xccdf-1.2:reference")
+		if err != nil {
+			return nil, fmt.Errorf("error getting references of rule %s: %w", ruleID, err)
+		}
+		for _, reference := range references {
+			href := getDsOptionalAttrValue(reference, "href")
+			framework, ok := frameworks[href]
+			if !ok {
+				framework = href
+			}
+			catalogRule.References = append(catalogRule.References, CatalogReference{
+				Framework: framework,
+				Control:   reference.InnerText(),
+			})
+		}
+		sort.SliceStable(catalogRule.References, func(i, j int) bool {
+			return catalogRule.References[i].Framework < catalogRule.References[j].Framework
+		})
+		catalog = append(catalog, catalogRule)
+	}
+	sort.SliceStable(catalog, func(i, j int) bool {
+		return catalog[i].ID < catalog[j].ID
+	})
+	return catalog, nil
+}
+
+// getDsFrameworks returns the short names of the frameworks declared by the
+// references of the benchmarks of a datastream, by href.
+func getDsFrameworks(dsDom *xmlquery.Node) (map[string]string, error) {
+	references, err := getDsElements(dsDom, "//xccdf-1.2:Benchmark/xccdf-1.2:reference")
+	if err != nil {
+		return nil, fmt.Errorf("error getting references of benchmark: %w", err)
+	}
+	frameworks := make(map[string]string, len(references))
+	for _, reference := range references {
+		frameworks[getDsOptionalAttrValue(reference, "href")] = reference.InnerText()
+	}
+	return frameworks, nil
+}
+
+// controlEnhancement and controlStatement match the enhancement numbers and
+// the statement parts of NIST control ids as written in datastream
+// references, such as AC-6(1) and CM-6(a).
+var (
+	controlEnhancement = regexp.MustCompile(`\(([0-9]+)\)`)
+	controlStatement   = regexp.MustCompile(`\([a-z]\)`)
+)
+
+// normalizeControlID returns a control id in the form of OSCAL control ids,
+// so the control ids of datastream references and of OSCAL catalogs can be
+// compared: AC-6(1) is ac-6.1, and CM-6(a), a statement part of the
+// control, is cm-6.
+func normalizeControlID(controlID string) string {
+	controlID = strings.ToLower(strings.TrimSpace(controlID))
+	controlID = controlEnhancement.ReplaceAllString(controlID, ".$1")
+	return controlStatement.ReplaceAllString(controlID, "")
+}
+
+// controlMatches reports whether a normalized control id of a reference is
+// the selected control id, one of its enhancements or one of the controls of
+// a selected family.
+func controlMatches(selected, controlID string) bool {
+	return controlID == selected || strings.HasPrefix(controlID, selected+".") || strings.HasPrefix(controlID, selected+"-")
+}
+
+// FilterPolicyControls returns the rules of the OSCAL policy mapped to one of
+// the given controls by the references of the rules in the datastream. A
+// control id, such as ac-6 or AC-6, also selects the enhancements of the
+// control, and a family, such as ac, all of its controls. A control id can
+// be restricted to a framework with its short name, such as nist:ac-6. Each
+// control must be mapped to at least one rule of the policy.
+func FilterPolicyControls(oscalPolicy policy.Policy, dsPath string, controlIDs []string) (policy.Policy, error) {
+	catalog, err := GetDsRuleCatalog(dsPath)
+	if err != nil {
+		return nil, err
+	}
+	references := make(map[string][]CatalogReference, len(catalog))
+	for _, rule := range catalog {
+		references[rule.ID] = rule.References
+	}
+
+	var filteredPolicy policy.Policy
+	matched := make(map[string]bool, len(controlIDs))
+	for _, rule := range oscalPolicy {
+		selected := false
+		for _, reference := range references[rule.Rule.ID] {
+			for _, referenceControl := range strings.Split(reference.Control, ",") {
+				referenceControl = normalizeControlID(referenceControl)
+				for _, controlID := range controlIDs {
+					framework, control, qualified := strings.Cut(controlID, ":")
+					if !qualified {
+						framework, control = "", controlID
+					}
+					if framework != "" && framework != reference.Framework {
+						continue
+					}
+					if controlMatches(normalizeControlID(control), referenceControl) {
+						matched[controlID] = true
+						selected = true
+					}
+				}
+			}
+		}
+		if selected {
+			filteredPolicy = append(filteredPolicy, rule)
+		}
+	}
+	for _, controlID := range controlIDs {
+		if !matched[controlID] {
+			return nil, fmt.Errorf("selected control %s not mapped to any rule of the policy in %s", controlID, dsPath)
+		}
+	}
+	return filteredPolicy, nil
+}
+
+// RuleControls returns the controls of a framework, such as nist, the rules
+// of a datastream are mapped to by their references, as OSCAL control ids,
+// by rule id as used in policies. Rules without references to the framework
+// are not included.
+func RuleControls(dsPath, framework string) (map[string][]string, error) {
+	catalog, err := GetDsRuleCatalog(dsPath)
+	if err != nil {
+		return nil, err
+	}
+	ruleControls := make(map[string][]string)
+	for _, rule := range catalog {
+		var controls []string
+		for _, reference := range rule.References {
+			if reference.Framework != framework {
+				continue
+			}
+			for _, control := range strings.Split(reference.Control, ",") {
+				control = normalizeControlID(control)
+				if control != "" && !slices.Contains(controls, control) {
+					controls = append(controls, control)
+				}
+			}
+		}
+		if len(controls) > 0 {
+			ruleControls[rule.ID] = controls
+		}
+	}
+	return ruleControls, nil
+}
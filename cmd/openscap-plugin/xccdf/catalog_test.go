@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestGetDsRuleCatalog(t *testing.T) {
+	catalog, err := GetDsRuleCatalog(filepath.Join(testDataDir, "ssg-rhel-ds.xml"))
+	if err != nil {
+		t.Fatalf("GetDsRuleCatalog() error = %v", err)
+	}
+	if len(catalog) != 376 {
+		t.Errorf("got %d rules, want 376", len(catalog))
+	}
+	if !sort.SliceIsSorted(catalog, func(i, j int) bool { return catalog[i].ID < catalog[j].ID }) {
+		t.Errorf("GetDsRuleCatalog() rules are not sorted by id")
+	}
+
+	i := slices.IndexFunc(catalog, func(rule CatalogRule) bool { return rule.ID == "package_aide_installed" })
+	if i < 0 {
+		t.Fatalf("GetDsRuleCatalog() has no package_aide_installed rule")
+	}
+	rule := catalog[i]
+	if rule.Title != "Install AIDE" || rule.Severity != "medium" || rule.CCE != "CCE-90477-1" {
+		t.Errorf("GetDsRuleCatalog() rule = %s, %s, %s", rule.Title, rule.Severity, rule.CCE)
+	}
+	// references are named after the frameworks declared by the benchmark
+	if !slices.Contains(rule.References, CatalogReference{Framework: "nist", Control: "CM-6(a)"}) {
+		t.Errorf("GetDsRuleCatalog() references = %v, want nist CM-6(a)", rule.References)
+	}
+	if !sort.SliceIsSorted(rule.References, func(i, j int) bool { return rule.References[i].Framework < rule.References[j].Framework }) {
+		t.Errorf("GetDsRuleCatalog() references are not sorted by framework")
+	}
+
+	for _, dsFile := range []string{"absent.xml", "invalid.xml"} {
+		if _, err := GetDsRuleCatalog(filepath.Join(testDataDir, dsFile)); err == nil {
+			t.Errorf("GetDsRuleCatalog() of %s expected an error", dsFile)
+		}
+	}
+}
+
+func TestNormalizeControlID(t *testing.T) {
+	tests := map[string]string{
+		"CM-6(a)":    "cm-6",
+		"AC-6(1)":    "ac-6.1",
+		"IA-5(1)(c)": "ia-5.1",
+		" ac-6.1 ":   "ac-6.1",
+		"SC-13":      "sc-13",
+		"6.1.1":      "6.1.1",
+		"APO13.01":   "apo13.01",
+	}
+	for controlID, want := range tests {
+		if got := normalizeControlID(controlID); got != want {
+			t.Errorf("normalizeControlID(%q) = %q, want %q", controlID, got, want)
+		}
+	}
+}
+
+func TestControlMatches(t *testing.T) {
+	tests := []struct {
+		selected  string
+		controlID string
+		want      bool
+	}{
+		{"ac-6", "ac-6", true},
+		{"ac-6", "ac-6.1", true},
+		{"ac", "ac-6.1", true},
+		{"ac-6", "ac-60", false},
+		{"ac-6.1", "ac-6", false},
+		{"6.1", "6.1.1", true},
+		{"6.1", "6.10", false},
+	}
+	for _, tt := range tests {
+		if got := controlMatches(tt.selected, tt.controlID); got != tt.want {
+			t.Errorf("controlMatches(%q, %q) = %v, want %v", tt.selected, tt.controlID, got, tt.want)
+		}
+	}
+}
+
+func TestRuleControls(t *testing.T) {
+	ruleControls, err := RuleControls(filepath.Join(testDataDir, "ssg-rhel-ds.xml"), "nist")
+	if err != nil {
+		t.Fatalf("RuleControls() error = %v", err)
+	}
+	// statement parts of a control are mapped to the control
+	if got := ruleControls["package_aide_installed"]; !slices.Equal(got, []string{"cm-6"}) {
+		t.Errorf("RuleControls() package_aide_installed = %v, want [cm-6]", got)
+	}
+	if got := ruleControls["configure_crypto_policy"]; !slices.Contains(got, "sc-13") {
+		t.Errorf("RuleControls() configure_crypto_policy = %v, want sc-13", got)
+	}
+
+	ruleControls, err = RuleControls(filepath.Join(testDataDir, "ssg-rhel-ds.xml"), "unknown")
+	if err != nil {
+		t.Fatalf("RuleControls() error = %v", err)
+	}
+	if len(ruleControls) != 0 {
+		t.Errorf("RuleControls() of unknown framework = %v, want none", ruleControls)
+	}
+}
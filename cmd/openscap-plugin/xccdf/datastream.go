@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/ComplianceAsCode/compliance-operator/pkg/xccdf"
 	"github.com/antchfx/xmlquery"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
 const (
@@ -239,7 +243,7 @@ func initProfile(dsProfile *xmlquery.Node, dsProfileId string) (*xccdf.ProfileEl
 func GetDsProfile(profileId string, dsPath string) (*xccdf.ProfileElement, error) {
 	dsDom, err := loadDataStream(dsPath)
 	if err != nil {
-		return nil, fmt.Errorf("error loading datastream: %w", err)
+		return nil, fmt.Errorf("%w: %w", pluginerr.ErrDatastreamInvalid, err)
 	}
 
 	dsProfileID := getDsProfileID(profileId)
@@ -249,7 +253,7 @@ func GetDsProfile(profileId string, dsPath string) (*xccdf.ProfileElement, error
 	}
 
 	if dsProfile == nil {
-		return nil, fmt.Errorf("profile not found: %s", dsProfileID)
+		return nil, fmt.Errorf("%w: %s", pluginerr.ErrProfileNotFound, dsProfileID)
 	}
 
 	parsedProfile, err := initProfile(dsProfile, dsProfileID)
@@ -260,10 +264,49 @@ func GetDsProfile(profileId string, dsPath string) (*xccdf.ProfileElement, error
 	return parsedProfile, nil
 }
 
+// GetDsPlatforms returns the CPE names of the platforms the profile applies to,
+// declared in the profile or, when it declares none, in the benchmark.
+// Platforms defined with the CPE applicability language are not returned.
+func GetDsPlatforms(profileId string, dsPath string) ([]string, error) {
+	dsDom, err := loadDataStream(dsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", pluginerr.ErrDatastreamInvalid, err)
+	}
+
+	dsProfileID := getDsProfileID(profileId)
+	dsProfile, err := getDsProfile(dsDom, dsProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("error processing profile %s in datastream: %w", profileId, err)
+	}
+	if dsProfile == nil {
+		return nil, fmt.Errorf("%w: %s", pluginerr.ErrProfileNotFound, dsProfileID)
+	}
+
+	platformElements, err := getDsElements(dsProfile, "xccdf-1.2:platform")
+	if err != nil {
+		return nil, fmt.Errorf("error getting platforms of profile %s: %w", profileId, err)
+	}
+	if len(platformElements) == 0 {
+		platformElements, err = getDsElements(dsDom, "//xccdf-1.2:Benchmark/xccdf-1.2:platform")
+		if err != nil {
+			return nil, fmt.Errorf("error getting platforms of benchmark: %w", err)
+		}
+	}
+
+	var platforms []string
+	for _, platformElement := range platformElements {
+		idref := getDsOptionalAttrValue(platformElement, "idref")
+		if strings.HasPrefix(idref, "cpe:") {
+			platforms = append(platforms, idref)
+		}
+	}
+	return platforms, nil
+}
+
 func GetDsVariablesValues(dsPath string) ([]DsVariables, error) {
 	dsDom, err := loadDataStream(dsPath)
 	if err != nil {
-		return nil, fmt.Errorf("error loading datastream: %w", err)
+		return nil, fmt.Errorf("%w: %w", pluginerr.ErrDatastreamInvalid, err)
 	}
 
 	dsVariables, err := getDsElements(dsDom, "//xccdf-1.2:Value")
@@ -343,7 +386,7 @@ func ResolveDsVariableOptions(profile *xccdf.ProfileElement, variables []DsVaria
 func GetDsRules(dsPath string) ([]DsRules, error) {
 	dsDom, err := loadDataStream(dsPath)
 	if err != nil {
-		return nil, fmt.Errorf("error loading datastream: %w", err)
+		return nil, fmt.Errorf("%w: %w", pluginerr.ErrDatastreamInvalid, err)
 	}
 
 	dsRules, err := getDsElements(dsDom, "//xccdf-1.2:Rule")
@@ -409,6 +452,10 @@ func newByIdHashTable(nodes []*xmlquery.Node) NodeByIdHashTable {
 		ruleDefinition := nodes[i]
 		ruleId := ruleDefinition.SelectAttr("id")
 
+		// duplicated ids are invalid XCCDF, the last definition is kept
+		if _, ok := table[ruleId]; ok {
+			hclog.Default().Warn("Duplicate definition id, keeping the last definition", "id", ruleId)
+		}
 		table[ruleId] = ruleDefinition
 	}
 
@@ -3,13 +3,17 @@
 package xccdf
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/ComplianceAsCode/compliance-operator/pkg/xccdf"
 	"github.com/antchfx/xmlquery"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
 )
 
 var testDataDir = filepath.Join("..", "..", "..", "internal", "complytime", "testdata", "openscap")
@@ -520,6 +524,7 @@ func TestGetDsProfile(t *testing.T) {
 		dsPath    string
 		expected  *xccdf.ProfileElement
 		wantErr   bool
+		wantErrIs error
 	}{
 		{
 			profileId: "test_profile",
@@ -542,12 +547,14 @@ func TestGetDsProfile(t *testing.T) {
 			dsPath:    filepath.Join(testDataDir, "ssg-rhel-ds.xml"),
 			expected:  nil,
 			wantErr:   true,
+			wantErrIs: pluginerr.ErrProfileNotFound,
 		},
 		{
 			profileId: "absent_datastream",
 			dsPath:    filepath.Join(testDataDir, "absent.xml"),
 			expected:  nil,
 			wantErr:   true,
+			wantErrIs: pluginerr.ErrDatastreamInvalid,
 		},
 	}
 
@@ -558,6 +565,9 @@ func TestGetDsProfile(t *testing.T) {
 				t.Errorf("GetDsProfile() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("GetDsProfile() error = %v, want %v", err, tt.wantErrIs)
+			}
 			if result != nil && tt.expected != nil {
 				if result.ID != tt.expected.ID {
 					t.Errorf("got ID %s, want %s", result.ID, tt.expected.ID)
@@ -864,3 +874,20 @@ func TestGetDsRules(t *testing.T) {
 		})
 	}
 }
+
+// TestGetDsPlatforms tests the GetDsPlatforms function.
+// The test profile declares no platform, so the benchmark platforms are returned.
+func TestGetDsPlatforms(t *testing.T) {
+	dsPath := filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	platforms, err := GetDsPlatforms("test_profile", dsPath)
+	if err != nil {
+		t.Fatalf("GetDsPlatforms() error = %v", err)
+	}
+	if !reflect.DeepEqual(platforms, []string{"cpe:/o:redhat:enterprise_linux:10"}) {
+		t.Errorf("GetDsPlatforms() = %v, want %v", platforms, []string{"cpe:/o:redhat:enterprise_linux:10"})
+	}
+
+	if _, err := GetDsPlatforms("absent_profile", dsPath); !errors.Is(err, pluginerr.ErrProfileNotFound) {
+		t.Errorf("GetDsPlatforms() error = %v, want %v", err, pluginerr.ErrProfileNotFound)
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+// TailoringDocument is the content of a tailoring file as a JSON or YAML
+// document, for tools editing the tailoring without manipulating XCCDF. The
+// tailoring file remains the one evaluated by oscap.
+type TailoringDocument struct {
+	ID          string           `json:"id"`
+	Version     string           `json:"version"`
+	VersionTime string           `json:"versionTime,omitempty"`
+	Benchmark   string           `json:"benchmark"`
+	Profile     TailoringProfile `json:"profile"`
+}
+
+// TailoringProfile is the tailoring profile of a TailoringDocument.
+type TailoringProfile struct {
+	ID          string               `json:"id"`
+	Extends     string               `json:"extends,omitempty"`
+	Title       string               `json:"title,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Selections  []TailoringSelection `json:"selections"`
+	Values      []TailoringValue     `json:"values,omitempty"`
+}
+
+// TailoringSelection is the selection of a rule by a TailoringProfile.
+type TailoringSelection struct {
+	IDRef    string `json:"idref"`
+	Selected bool   `json:"selected"`
+}
+
+// TailoringValue is the value of a variable set by a TailoringProfile.
+type TailoringValue struct {
+	IDRef string `json:"idref"`
+	Value string `json:"value"`
+}
+
+// tailoringFile decodes a tailoring file whatever its namespace prefix.
+type tailoringFile struct {
+	XMLName   xml.Name `xml:"Tailoring"`
+	ID        string   `xml:"id,attr"`
+	Benchmark struct {
+		Href string `xml:"href,attr"`
+	} `xml:"benchmark"`
+	Version struct {
+		Time  string `xml:"time,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"version"`
+	Profile struct {
+		ID          string `xml:"id,attr"`
+		Extends     string `xml:"extends,attr"`
+		Title       string `xml:"title"`
+		Description string `xml:"description"`
+		Selections  []struct {
+			IDRef    string `xml:"idref,attr"`
+			Selected bool   `xml:"selected,attr"`
+		} `xml:"select"`
+		Values []struct {
+			IDRef string `xml:"idref,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"set-value"`
+	} `xml:"Profile"`
+}
+
+// ReadTailoringDocument decodes the tailoring file read from r, keeping the
+// selections and values in document order.
+func ReadTailoringDocument(r io.Reader) (TailoringDocument, error) {
+	var file tailoringFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return TailoringDocument{}, fmt.Errorf("error parsing tailoring file: %w", err)
+	}
+	document := TailoringDocument{
+		ID:          file.ID,
+		Version:     strings.TrimSpace(file.Version.Value),
+		VersionTime: file.Version.Time,
+		Benchmark:   file.Benchmark.Href,
+		Profile: TailoringProfile{
+			ID:          file.Profile.ID,
+			Extends:     file.Profile.Extends,
+			Title:       strings.TrimSpace(file.Profile.Title),
+			Description: strings.TrimSpace(file.Profile.Description),
+			Selections:  []TailoringSelection{},
+		},
+	}
+	for _, selection := range file.Profile.Selections {
+		document.Profile.Selections = append(document.Profile.Selections, TailoringSelection{IDRef: selection.IDRef, Selected: selection.Selected})
+	}
+	for _, value := range file.Profile.Values {
+		document.Profile.Values = append(document.Profile.Values, TailoringValue{IDRef: value.IDRef, Value: strings.TrimSpace(value.Value)})
+	}
+	return document, nil
+}
+
+// Marshal encodes the document in the given format, config.TailoringDocumentJSON
+// or config.TailoringDocumentYAML.
+func (d TailoringDocument) Marshal(format string) ([]byte, error) {
+	switch format {
+	case config.TailoringDocumentJSON:
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case config.TailoringDocumentYAML:
+		return yaml.Marshal(d)
+	default:
+		return nil, fmt.Errorf("unsupported tailoring document format %q", format)
+	}
+}
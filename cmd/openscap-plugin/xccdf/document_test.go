@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
+)
+
+const testTailoring = `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf:Tailoring xmlns:xccdf="http://checklists.nist.gov/xccdf/1.2" id="xccdf_complytime.openscapplugin_tailoring_complytime">
+  <xccdf:benchmark href="/usr/share/xml/scap/ssg/content/ssg-rhel10-ds.xml"></xccdf:benchmark>
+  <xccdf:version time="2025-06-10T10:00:00Z">1</xccdf:version>
+  <xccdf:Profile id="xccdf_complytime.openscapplugin_profile_test_complytime" extends="xccdf_org.ssgproject.content_profile_test">
+    <xccdf:title override="true">ComplyTime Tailoring Profile - Test</xccdf:title>
+    <xccdf:select idref="xccdf_org.ssgproject.content_rule_package_telnet_removed" selected="false"></xccdf:select>
+    <xccdf:select idref="xccdf_org.ssgproject.content_rule_account_unique_id" selected="true"></xccdf:select>
+    <xccdf:set-value idref="xccdf_org.ssgproject.content_value_var_password_hashing_algorithm">YESCRYPT</xccdf:set-value>
+  </xccdf:Profile>
+</xccdf:Tailoring>`
+
+func TestReadTailoringDocument(t *testing.T) {
+	document, err := ReadTailoringDocument(strings.NewReader(testTailoring))
+	require.NoError(t, err)
+	require.Equal(t, TailoringDocument{
+		ID:          "xccdf_complytime.openscapplugin_tailoring_complytime",
+		Version:     "1",
+		VersionTime: "2025-06-10T10:00:00Z",
+		Benchmark:   "/usr/share/xml/scap/ssg/content/ssg-rhel10-ds.xml",
+		Profile: TailoringProfile{
+			ID:      "xccdf_complytime.openscapplugin_profile_test_complytime",
+			Extends: "xccdf_org.ssgproject.content_profile_test",
+			Title:   "ComplyTime Tailoring Profile - Test",
+			Selections: []TailoringSelection{
+				{IDRef: "xccdf_org.ssgproject.content_rule_package_telnet_removed", Selected: false},
+				{IDRef: "xccdf_org.ssgproject.content_rule_account_unique_id", Selected: true},
+			},
+			Values: []TailoringValue{
+				{IDRef: "xccdf_org.ssgproject.content_value_var_password_hashing_algorithm", Value: "YESCRYPT"},
+			},
+		},
+	}, document)
+
+	data, err := document.Marshal(config.TailoringDocumentJSON)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"idref": "xccdf_org.ssgproject.content_rule_account_unique_id",`)
+	require.NotContains(t, string(data), "description")
+
+	data, err = document.Marshal(config.TailoringDocumentYAML)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "- idref: xccdf_org.ssgproject.content_value_var_password_hashing_algorithm\n    value: YESCRYPT\n")
+
+	_, err = document.Marshal("toml")
+	require.EqualError(t, err, `unsupported tailoring document format "toml"`)
+
+	_, err = ReadTailoringDocument(strings.NewReader("<Benchmark/>"))
+	require.ErrorContains(t, err, "error parsing tailoring file")
+}
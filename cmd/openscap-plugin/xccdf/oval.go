@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// maxOVALItems is the number of collected items described for a failing
+// OVAL test, the others are only counted.
+const maxOVALItems = 3
+
+// checkExistence describes the number of items expected by the
+// check_existence attribute of an OVAL test.
+var checkExistence = map[string]string{
+	"all_exist":           "at least one item",
+	"at_least_one_exists": "at least one item",
+	"only_one_exists":     "exactly one item",
+	"none_exist":          "no item",
+	"any_exist":           "any number of items",
+}
+
+// ovalResults indexes by id the elements of an oval_results report of an
+// ARF needed to describe failing tests.
+type ovalResults struct {
+	tests       map[string]*xmlquery.Node
+	states      map[string]*xmlquery.Node
+	testResults map[string]*xmlquery.Node
+	items       map[string]*xmlquery.Node
+}
+
+// readOVALDetails returns, by OVAL definition id, a summary of the expected
+// and collected values of the failing tests of the definitions that
+// evaluated to false in the OVAL results of an ARF, such as
+// `expected subexpression "DEFAULT", found subexpression "LEGACY"`.
+// Definitions without OVAL results, as produced by oscap when the results
+// are not exported, have no summary.
+func readOVALDetails(arfDom *xmlquery.Node) map[string]string {
+	details := make(map[string]string)
+	for _, resultsEl := range arfDom.SelectElements("//oval_results") {
+		results := newOVALResults(resultsEl)
+		for _, definition := range resultsEl.SelectElements("results/system/definitions/definition") {
+			if definition.SelectAttr("result") != "false" {
+				continue
+			}
+			var testDetails []string
+			for _, criterion := range definition.SelectElements(".//criterion") {
+				if criterion.SelectAttr("result") != "false" || criterion.SelectAttr("negate") == "true" {
+					continue
+				}
+				if detail := results.describeTest(criterion.SelectAttr("test_ref")); detail != "" {
+					testDetails = append(testDetails, detail)
+				}
+			}
+			if len(testDetails) > 0 {
+				details[definition.SelectAttr("definition_id")] = strings.Join(testDetails, "; ")
+			}
+		}
+	}
+	return details
+}
+
+// readOVALVariables returns, by OVAL definition id, the values of the
+// variables used by the tests of the definitions in the OVAL results of an
+// ARF, sorted by variable id.
+func readOVALVariables(arfDom *xmlquery.Node) map[string][]OVALVariable {
+	variables := make(map[string][]OVALVariable)
+	for _, resultsEl := range arfDom.SelectElements("//oval_results") {
+		testResults := childrenByAttr(resultsEl.SelectElement("results/system/tests"), "test_id")
+		for _, definition := range resultsEl.SelectElements("results/system/definitions/definition") {
+			values := make(map[string][]string)
+			for _, criterion := range definition.SelectElements(".//criterion") {
+				testResult, ok := testResults[criterion.SelectAttr("test_ref")]
+				if !ok {
+					continue
+				}
+				for _, variable := range testResult.SelectElements("tested_variable") {
+					id := variable.SelectAttr("variable_id")
+					if !slices.Contains(values[id], variable.InnerText()) {
+						values[id] = append(values[id], variable.InnerText())
+					}
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			definitionVariables := make([]OVALVariable, 0, len(values))
+			for id, variableValues := range values {
+				definitionVariables = append(definitionVariables, OVALVariable{ID: id, Values: variableValues})
+			}
+			slices.SortFunc(definitionVariables, func(a, b OVALVariable) int {
+				return strings.Compare(a.ID, b.ID)
+			})
+			variables[definition.SelectAttr("definition_id")] = definitionVariables
+		}
+	}
+	return variables
+}
+
+// newOVALResults indexes the tests and states of the OVAL definitions and
+// the test results and collected items of an oval_results element.
+func newOVALResults(resultsEl *xmlquery.Node) ovalResults {
+	return ovalResults{
+		tests:       childrenByAttr(resultsEl.SelectElement("oval_definitions/tests"), "id"),
+		states:      childrenByAttr(resultsEl.SelectElement("oval_definitions/states"), "id"),
+		testResults: childrenByAttr(resultsEl.SelectElement("results/system/tests"), "test_id"),
+		items:       childrenByAttr(resultsEl.SelectElement("results/system/oval_system_characteristics/system_data"), "id"),
+	}
+}
+
+// childrenByAttr returns the child elements of parent by the value of their
+// attribute attr.
+func childrenByAttr(parent *xmlquery.Node, attr string) map[string]*xmlquery.Node {
+	children := make(map[string]*xmlquery.Node)
+	if parent == nil {
+		return children
+	}
+	for _, child := range childElements(parent) {
+		children[child.SelectAttr(attr)] = child
+	}
+	return children
+}
+
+// childElements returns the child elements of node, whatever their
+// namespace.
+func childElements(node *xmlquery.Node) []*xmlquery.Node {
+	var elements []*xmlquery.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.ElementNode {
+			elements = append(elements, child)
+		}
+	}
+	return elements
+}
+
+// describeTest summarizes the expected and collected values of a test. It
+// returns an empty string when the test is not found in the results.
+func (r ovalResults) describeTest(testID string) string {
+	test, ok := r.tests[testID]
+	if !ok {
+		return ""
+	}
+	testResult, ok := r.testResults[testID]
+	if !ok {
+		return ""
+	}
+	var items []*xmlquery.Node
+	for _, testedItem := range testResult.SelectElements("tested_item") {
+		if item, ok := r.items[testedItem.SelectAttr("item_id")]; ok {
+			items = append(items, item)
+		}
+	}
+
+	var state *xmlquery.Node
+	for _, child := range childElements(test) {
+		if child.Data == "state" {
+			state = r.states[child.SelectAttr("state_ref")]
+			break
+		}
+	}
+	if state == nil {
+		existence, ok := checkExistence[test.SelectAttr("check_existence")]
+		if !ok {
+			existence = checkExistence["at_least_one_exists"]
+		}
+		return fmt.Sprintf("expected %s, found %s", existence, countItems(len(items)))
+	}
+
+	var expected, entities []string
+	for _, entity := range childElements(state) {
+		entities = append(entities, entity.Data)
+		expected = append(expected, describeStateEntity(entity, testResult))
+	}
+	if len(items) == 0 {
+		return fmt.Sprintf("expected %s, found none", strings.Join(expected, ", "))
+	}
+	var found []string
+	for _, item := range items[:min(len(items), maxOVALItems)] {
+		var values []string
+		for _, child := range childElements(item) {
+			for _, entity := range entities {
+				if child.Data == entity {
+					values = append(values, fmt.Sprintf("%s %q", entity, child.InnerText()))
+				}
+			}
+		}
+		if len(values) > 0 {
+			found = append(found, strings.Join(values, ", "))
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Sprintf("expected %s, found %s", strings.Join(expected, ", "), countItems(len(items)))
+	}
+	if len(items) > maxOVALItems {
+		found = append(found, fmt.Sprintf("and %d more", len(items)-maxOVALItems))
+	}
+	return fmt.Sprintf("expected %s, found %s", strings.Join(expected, ", "), strings.Join(found, "; "))
+}
+
+// describeStateEntity describes the value expected by a state entity. The
+// values of entities referencing a variable are read from the variable
+// values used by the test.
+func describeStateEntity(entity, testResult *xmlquery.Node) string {
+	var values []string
+	if varRef := entity.SelectAttr("var_ref"); varRef != "" {
+		for _, variable := range testResult.SelectElements("tested_variable") {
+			if variable.SelectAttr("variable_id") == varRef {
+				values = append(values, fmt.Sprintf("%q", variable.InnerText()))
+			}
+		}
+	} else {
+		values = append(values, fmt.Sprintf("%q", entity.InnerText()))
+	}
+	value := strings.Join(values, " or ")
+	if operation := entity.SelectAttr("operation"); operation != "" && operation != "equals" {
+		return fmt.Sprintf("%s %s %s", entity.Data, operation, value)
+	}
+	return fmt.Sprintf("%s %s", entity.Data, value)
+}
+
+// countItems describes a number of collected items.
+func countItems(count int) string {
+	switch count {
+	case 0:
+		return "none"
+	case 1:
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", count)
+}
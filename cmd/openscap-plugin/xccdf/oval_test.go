@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOVALDetails(t *testing.T) {
+	arfDom, err := LoadDsTest(t, "arf-oval.xml")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"oval:ssg-package_aide_installed:def:1":  "expected at least one item, found none",
+		"oval:ssg-configure_crypto_policy:def:1": `expected subexpression "DEFAULT", found subexpression "LEGACY"`,
+	}, readOVALDetails(arfDom))
+
+	// ARF files without OVAL results have no details
+	arfDom, err = LoadDsTest(t, "arf.xml")
+	require.NoError(t, err)
+	require.Empty(t, readOVALDetails(arfDom))
+}
+
+func TestReadOVALVariables(t *testing.T) {
+	arfDom, err := LoadDsTest(t, "arf-oval.xml")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]OVALVariable{
+		"oval:ssg-configure_crypto_policy:def:1": {{ID: "oval:ssg-var_system_crypto_policy:var:1", Values: []string{"DEFAULT"}}},
+	}, readOVALVariables(arfDom))
+
+	arfDom, err = LoadDsTest(t, "arf.xml")
+	require.NoError(t, err)
+	require.Empty(t, readOVALVariables(arfDom))
+}
+
+func TestDescribeTest(t *testing.T) {
+	resultsDom, err := xmlquery.Parse(strings.NewReader(`<oval_results xmlns:ind="urn:ind" xmlns:ind-sys="urn:ind-sys">
+  <oval_definitions>
+    <tests>
+      <ind:textfilecontent54_test id="tst:1" check_existence="at_least_one_exists"><ind:state state_ref="ste:1"/></ind:textfilecontent54_test>
+      <ind:textfilecontent54_test id="tst:2" check_existence="none_exist"/>
+    </tests>
+    <states>
+      <ind:textfilecontent54_state id="ste:1"><ind:text operation="pattern match">^umask 027$</ind:text></ind:textfilecontent54_state>
+    </states>
+  </oval_definitions>
+  <results><system>
+    <tests>
+      <test test_id="tst:1" result="false"><tested_item item_id="1"/><tested_item item_id="2"/><tested_item item_id="3"/><tested_item item_id="4"/></test>
+      <test test_id="tst:2" result="false"><tested_item item_id="1"/><tested_item item_id="2"/></test>
+    </tests>
+    <oval_system_characteristics><system_data>
+      <ind-sys:textfilecontent_item id="1"><ind-sys:text>umask 022</ind-sys:text></ind-sys:textfilecontent_item>
+      <ind-sys:textfilecontent_item id="2"><ind-sys:text>umask 002</ind-sys:text></ind-sys:textfilecontent_item>
+      <ind-sys:textfilecontent_item id="3"><ind-sys:text>umask 077</ind-sys:text></ind-sys:textfilecontent_item>
+      <ind-sys:textfilecontent_item id="4"><ind-sys:text>umask 000</ind-sys:text></ind-sys:textfilecontent_item>
+    </system_data></oval_system_characteristics>
+  </system></results>
+</oval_results>`))
+	require.NoError(t, err)
+	results := newOVALResults(resultsDom.SelectElement("oval_results"))
+
+	// only the first collected items are described
+	require.Equal(t, `expected text pattern match "^umask 027$", found text "umask 022"; text "umask 002"; text "umask 077"; and 1 more`,
+		results.describeTest("tst:1"))
+	require.Equal(t, "expected no item, found 2 items", results.describeTest("tst:2"))
+	require.Empty(t, results.describeTest("tst:3"))
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import "strings"
+
+// Provenance describes the content and the scanner that produced the results
+// of a TestResult, so the results remain interpretable once the content or
+// the scanner is updated.
+type Provenance struct {
+	// Benchmark is the id of the evaluated Benchmark and BenchmarkVersion
+	// its version.
+	Benchmark        string
+	BenchmarkVersion string
+	// Scanner and ScannerVersion are the product and the version of the
+	// test system, such as openscap and 1.3.10 for the test system
+	// cpe:/a:redhat:openscap:1.3.10. Scanner is the test system itself
+	// when it is not a CPE name with a product.
+	Scanner        string
+	ScannerVersion string
+	// Profile is the id of the evaluated profile.
+	Profile string
+}
+
+// newProvenance returns the provenance of a TestResult from its test-system
+// and version attributes. The Benchmark and the profile are read from its
+// elements.
+func newProvenance(testSystem, version string) *Provenance {
+	provenance := &Provenance{BenchmarkVersion: version, Scanner: testSystem}
+	// cpe:/part:vendor:product:version
+	if cpe, ok := strings.CutPrefix(testSystem, "cpe:/"); ok {
+		parts := strings.Split(cpe, ":")
+		if len(parts) > 2 && parts[2] != "" {
+			provenance.Scanner = parts[2]
+			if len(parts) > 3 {
+				provenance.ScannerVersion = parts[3]
+			}
+		}
+	}
+	return provenance
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProvenance(t *testing.T) {
+	tests := []struct {
+		name       string
+		testSystem string
+		want       *Provenance
+	}{
+		{
+			name:       "Valid/CPE",
+			testSystem: "cpe:/a:redhat:openscap:1.3.10",
+			want:       &Provenance{BenchmarkVersion: "0.1.76", Scanner: "openscap", ScannerVersion: "1.3.10"},
+		},
+		{
+			name:       "Valid/CPEWithoutVersion",
+			testSystem: "cpe:/a:redhat:openscap",
+			want:       &Provenance{BenchmarkVersion: "0.1.76", Scanner: "openscap"},
+		},
+		{
+			name:       "Valid/NotCPE",
+			testSystem: "OpenSCAP 1.3.10",
+			want:       &Provenance{BenchmarkVersion: "0.1.76", Scanner: "OpenSCAP 1.3.10"},
+		},
+		{
+			name:       "Valid/CPEWithoutProduct",
+			testSystem: "cpe:/a:redhat",
+			want:       &Provenance{BenchmarkVersion: "0.1.76", Scanner: "cpe:/a:redhat"},
+		},
+		{
+			name: "Valid/Empty",
+			want: &Provenance{BenchmarkVersion: "0.1.76"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, newProvenance(tt.testSystem, "0.1.76"))
+		})
+	}
+}
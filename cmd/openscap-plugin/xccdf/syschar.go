@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+// ovalSystemCharacteristicsURI is the namespace of the system characteristics
+// collected by the OVAL checks.
+const ovalSystemCharacteristicsURI string = "http://oval.mitre.org/XMLSchema/oval-system-characteristics-5"
+
+// ExportSystemCharacteristics copies the oval_system_characteristics elements
+// of the OVAL results in the ARF read from arf to w, as the children of a
+// system_characteristics root element, and returns the number of elements
+// copied. The ARF is read incrementally and the elements are copied as they
+// are in the ARF, with the namespaces they declare, so the collected system
+// state is preserved as oscap wrote it. Nothing is written when the ARF has
+// no system characteristics, for example when oscap ran with --thin-results.
+func ExportSystemCharacteristics(arf io.ReaderAt, w io.Writer) (int, error) {
+	decoder := xml.NewDecoder(io.NewSectionReader(arf, 0, math.MaxInt64))
+	var exported int
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return exported, fmt.Errorf("%w: %w", pluginerr.ErrARFParse, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != ovalSystemCharacteristicsURI || start.Name.Local != "oval_system_characteristics" {
+			continue
+		}
+		if err := decoder.Skip(); err != nil {
+			return exported, fmt.Errorf("%w: %w", pluginerr.ErrARFParse, err)
+		}
+		if exported == 0 {
+			if _, err := io.WriteString(w, xml.Header+"<system_characteristics>\n"); err != nil {
+				return exported, err
+			}
+		}
+		if _, err := io.Copy(w, io.NewSectionReader(arf, offset, decoder.InputOffset()-offset)); err != nil {
+			return exported, err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+	if exported > 0 {
+		if _, err := io.WriteString(w, "</system_characteristics>\n"); err != nil {
+			return exported, err
+		}
+	}
+	return exported, nil
+}
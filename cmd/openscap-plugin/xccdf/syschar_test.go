@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package xccdf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+func TestExportSystemCharacteristics(t *testing.T) {
+	arf, err := os.Open(filepath.Join(testDataDir, "arf-oval.xml"))
+	require.NoError(t, err)
+	defer arf.Close()
+
+	var exported bytes.Buffer
+	count, err := ExportSystemCharacteristics(arf, &exported)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// the export is a document with the collected items as they are in the ARF
+	doc, err := xmlquery.Parse(&exported)
+	require.NoError(t, err)
+	items := doc.SelectElements("/system_characteristics/oval_system_characteristics/system_data/*")
+	require.Len(t, items, 2)
+	require.Equal(t, "http://oval.mitre.org/XMLSchema/oval-system-characteristics-5#independent", items[1].NamespaceURI)
+	require.Equal(t, "LEGACY", items[1].SelectElement("*[local-name()='subexpression']").InnerText())
+
+	// ARF files without OVAL results have no system characteristics
+	arf, err = os.Open(filepath.Join(testDataDir, "arf.xml"))
+	require.NoError(t, err)
+	defer arf.Close()
+	exported.Reset()
+	count, err = ExportSystemCharacteristics(arf, &exported)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	require.Empty(t, exported.String())
+
+	_, err = ExportSystemCharacteristics(strings.NewReader("<arf><unclosed>"), &exported)
+	require.ErrorIs(t, err, pluginerr.ErrARFParse)
+}
@@ -4,11 +4,19 @@ package xccdf
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ComplianceAsCode/compliance-operator/pkg/xccdf"
+	"github.com/antchfx/xmlquery"
 	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
 
 	"github.com/complytime/complyctl/cmd/openscap-plugin/config"
@@ -20,6 +28,10 @@ const (
 	XCCDFTailoringSuffix string = "complytime"
 )
 
+// ErrTailoringProfileMismatch is returned when a tailoring file has no
+// tailoring profile for the configured profile.
+var ErrTailoringProfileMismatch = errors.New("tailoring file does not match the profile")
+
 func removePrefix(str, prefix string) string {
 	return strings.TrimPrefix(str, prefix)
 }
@@ -122,16 +134,26 @@ func selectAdditionalRules(tailoringSelections, dsProfileSelections []xccdf.Sele
 	return tailoringSelections
 }
 
-func getTailoringSelections(oscalPolicy policy.Policy, dsProfile *xccdf.ProfileElement, dsPath string) ([]xccdf.SelectElement, error) {
-	dsRules, err := GetDsRules(dsPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get rules from datastream: %w", err)
+// removeUnknownRules returns the rules of the OSCAL policy defined in the
+// Datastream, and the ids of the rules that are not.
+func removeUnknownRules(oscalPolicy policy.Policy, dsRules []DsRules) (policy.Policy, []string) {
+	var knownPolicy policy.Policy
+	var unknownRules []string
+	for _, rule := range oscalPolicy {
+		if validateRuleExistence(rule.Rule.ID, dsRules) {
+			knownPolicy = append(knownPolicy, rule)
+		} else {
+			unknownRules = append(unknownRules, rule.Rule.ID)
+		}
 	}
+	return knownPolicy, unknownRules
+}
 
+func getTailoringSelections(oscalPolicy policy.Policy, dsProfile *xccdf.ProfileElement, dsRules []DsRules) ([]xccdf.SelectElement, error) {
 	// All OSCAL Policy rules should be present in the Datastream
 	for _, rule := range oscalPolicy {
 		if !validateRuleExistence(rule.Rule.ID, dsRules) {
-			return nil, fmt.Errorf("rule %s not found in datastream: %s", rule.Rule.ID, dsPath)
+			return nil, fmt.Errorf("rule %s not found in datastream", rule.Rule.ID)
 		}
 	}
 
@@ -198,45 +220,81 @@ func getTailoringValues(oscalPolicy policy.Policy, dsProfile *xccdf.ProfileEleme
 	return tailoringValues, nil
 }
 
-func getTailoringProfile(profileId string, dsPath string, oscalPolicy policy.Policy) (*xccdf.ProfileElement, error) {
+// getTailoringProfile returns the tailoring profile of the OSCAL policy, and
+// the ids of the policy rules skipped because they are not defined in the
+// Datastream. The tailoring profile extends the base profile of the
+// Datastream and only holds the rule selections and variable values that
+// differ from it.
+func getTailoringProfile(profileId, baseProfileId string, dsPath string, oscalPolicy policy.Policy) (*xccdf.ProfileElement, []string, error) {
 	tailoringProfile := new(xccdf.ProfileElement)
 	tailoringProfile.ID = getTailoringProfileID(profileId)
 
-	dsProfile, err := GetDsProfile(profileId, dsPath)
+	dsProfile, err := GetDsProfile(baseProfileId, dsPath)
 	if err != nil {
-		return tailoringProfile, fmt.Errorf("failed to get base profile from datastream: %w", err)
+		return tailoringProfile, nil, fmt.Errorf("failed to get base profile from datastream: %w", err)
 	}
 
-	tailoringProfile.Extends = getTailoringExtendedProfileID(profileId)
+	dsRules, err := GetDsRules(dsPath)
+	if err != nil {
+		return tailoringProfile, nil, fmt.Errorf("failed to get rules from datastream: %w", err)
+	}
+	// a stale rule in the policy must not prevent the other rules from
+	// being evaluated, so it is left out of the tailoring
+	oscalPolicy, skippedRules := removeUnknownRules(oscalPolicy, dsRules)
+
+	tailoringProfile.Extends = getTailoringExtendedProfileID(baseProfileId)
 
 	tailoringProfile.Title = &xccdf.TitleOrDescriptionElement{
 		Override: true,
 		Value:    getTailoringProfileTitle(dsProfile.Title.Value),
 	}
 
-	tailoringProfile.Selections, err = getTailoringSelections(oscalPolicy, dsProfile, dsPath)
+	tailoringProfile.Selections, err = getTailoringSelections(oscalPolicy, dsProfile, dsRules)
 	if err != nil {
-		return tailoringProfile, fmt.Errorf("failed to get selections for tailoring profile: %w", err)
+		return tailoringProfile, skippedRules, fmt.Errorf("failed to get selections for tailoring profile: %w", err)
 	}
 
 	tailoringProfile.Values, err = getTailoringValues(oscalPolicy, dsProfile, dsPath)
 	if err != nil {
-		return tailoringProfile, fmt.Errorf("failed to get values for tailoring profile: %w", err)
+		return tailoringProfile, skippedRules, fmt.Errorf("failed to get values for tailoring profile: %w", err)
 	}
-	return tailoringProfile, nil
+	return tailoringProfile, skippedRules, nil
 }
 
-func PolicyToXML(oscalPolicy policy.Policy, config *config.Config) (string, error) {
+// FilterPolicyRules returns the rules of the OSCAL policy with the given ids.
+// All the given ids must be rules in the policy.
+func FilterPolicyRules(oscalPolicy policy.Policy, ruleIDs []string) (policy.Policy, error) {
+	var filteredPolicy policy.Policy
+	for _, ruleID := range ruleIDs {
+		found := false
+		for _, rule := range oscalPolicy {
+			if rule.Rule.ID == ruleID {
+				filteredPolicy = append(filteredPolicy, rule)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("selected rule %s not found in policy", ruleID)
+		}
+	}
+	return filteredPolicy, nil
+}
+
+// PolicyToXML returns the tailoring file of the OSCAL policy, and the ids of
+// the policy rules left out of it because they are not defined in the
+// Datastream.
+func PolicyToXML(oscalPolicy policy.Policy, config *config.Config) (string, []string, error) {
 	datastreamPath := config.Files.Datastream
 	profileId := config.Parameters.Profile
 
 	if oscalPolicy == nil {
-		return "", fmt.Errorf("OSCAL policy is empty")
+		return "", nil, fmt.Errorf("OSCAL policy is empty")
 	}
 
-	tailoringProfile, err := getTailoringProfile(profileId, datastreamPath, oscalPolicy)
+	tailoringProfile, skippedRules, err := getTailoringProfile(profileId, config.BaseProfile(), datastreamPath, oscalPolicy)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	tailoring := xccdf.TailoringElement{
@@ -249,7 +307,169 @@ func PolicyToXML(oscalPolicy policy.Policy, config *config.Config) (string, erro
 
 	output, err := xml.MarshalIndent(tailoring, "", "  ")
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	tailoringXML := applyNamespaces(string(output), config.NamespacePrefix(), config.Namespaces())
+	return xccdf.XMLHeader + "\n" + tailoringXML, skippedRules, nil
+}
+
+// applyNamespaces replaces the prefix of the XCCDF namespace of a marshaled
+// tailoring with prefix, and declares the additional namespaces, sorted by
+// prefix, on its root element. The XCCDF elements are always marshaled with
+// the default prefix, and no text can contain it unescaped.
+func applyNamespaces(tailoringXML, prefix string, namespaces map[string]string) string {
+	defaultDeclaration := fmt.Sprintf(`xmlns:%s="%s"`, config.DefaultNamespacePrefix, xccdf.XCCDFURI)
+	declarations := []string{fmt.Sprintf(`xmlns:%s="%s"`, prefix, xccdf.XCCDFURI)}
+	for _, namespacePrefix := range slices.Sorted(maps.Keys(namespaces)) {
+		declarations = append(declarations, fmt.Sprintf(`xmlns:%s="%s"`, namespacePrefix, namespaces[namespacePrefix]))
+	}
+	tailoringXML = strings.Replace(tailoringXML, defaultDeclaration, strings.Join(declarations, " "), 1)
+	if prefix == config.DefaultNamespacePrefix {
+		return tailoringXML
+	}
+	return strings.NewReplacer(
+		"<"+config.DefaultNamespacePrefix+":", "<"+prefix+":",
+		"</"+config.DefaultNamespacePrefix+":", "</"+prefix+":",
+	).Replace(tailoringXML)
+}
+
+// VerifyTailoringProfile checks that the tailoring file has the tailoring
+// profile generated for the profile. A tailoring file generated for another
+// profile, or edited since it was generated, would otherwise lead oscap to
+// evaluate another profile or to fail to find it.
+func VerifyTailoringProfile(tailoringPath, profileId string) error {
+	file, err := os.Open(filepath.Clean(tailoringPath))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	tailoringDom, err := xmlquery.Parse(file)
+	if err != nil {
+		return fmt.Errorf("error parsing tailoring file %s: %w", tailoringPath, err)
+	}
+
+	wantID := getTailoringProfileID(profileId)
+	var profileIDs []string
+	for _, profile := range tailoringDom.SelectElements("//*[local-name()='Tailoring']/*[local-name()='Profile']") {
+		profileID := profile.SelectAttr("id")
+		if profileID == wantID {
+			return nil
+		}
+		profileIDs = append(profileIDs, profileID)
+	}
+	if len(profileIDs) == 0 {
+		return fmt.Errorf("%w: %s has no profile, expected %s for profile %q", ErrTailoringProfileMismatch, tailoringPath, wantID, profileId)
+	}
+	return fmt.Errorf("%w: %s has profile %s, expected %s for profile %q, run the generate command again",
+		ErrTailoringProfileMismatch, tailoringPath, strings.Join(profileIDs, ", "), wantID, profileId)
+}
+
+// TailoringDiff is the semantic difference between two tailoring files, in
+// terms of rule selections and variable values. Rules and variables are
+// sorted by id.
+type TailoringDiff struct {
+	// AddedSelections and RemovedSelections are the rule selections only
+	// found in the new and the old tailoring. A rule whose selection changed
+	// is found in both.
+	AddedSelections   []xccdf.SelectElement
+	RemovedSelections []xccdf.SelectElement
+	// AddedValues and RemovedValues are the variables only set in the new
+	// and the old tailoring.
+	AddedValues   []xccdf.SetValueElement
+	RemovedValues []xccdf.SetValueElement
+	ChangedValues []ValueChange
+}
+
+// ValueChange is a variable set to different values in two tailoring files.
+type ValueChange struct {
+	IDRef    string
+	OldValue string
+	NewValue string
+}
+
+// Empty reports whether the tailoring files have the same rule selections
+// and variable values.
+func (d TailoringDiff) Empty() bool {
+	return len(d.AddedSelections) == 0 && len(d.RemovedSelections) == 0 &&
+		len(d.AddedValues) == 0 && len(d.RemovedValues) == 0 && len(d.ChangedValues) == 0
+}
+
+// tailoringContent holds the rule selections and the variable values of a
+// tailoring file by id.
+type tailoringContent struct {
+	selections map[string]bool
+	values     map[string]string
+}
+
+// readTailoring parses the rule selections and variable values of a
+// tailoring file, whatever its namespace prefix. Later selections and values of
+// the same id take precedence.
+func readTailoring(r io.Reader) (tailoringContent, error) {
+	tailoringDom, err := xmlquery.Parse(r)
+	if err != nil {
+		return tailoringContent{}, fmt.Errorf("error parsing tailoring file: %w", err)
+	}
+	if tailoringDom.SelectElement("//*[local-name()='Tailoring']") == nil {
+		return tailoringContent{}, errors.New("error parsing tailoring file: no 'Tailoring' element")
+	}
+
+	content := tailoringContent{
+		selections: make(map[string]bool),
+		values:     make(map[string]string),
+	}
+	for _, selection := range tailoringDom.SelectElements("//*[local-name()='Profile']/*[local-name()='select']") {
+		selected, err := strconv.ParseBool(strings.TrimSpace(selection.SelectAttr("selected")))
+		if err != nil {
+			return tailoringContent{}, fmt.Errorf("invalid selection of rule %s: %w", selection.SelectAttr("idref"), err)
+		}
+		content.selections[selection.SelectAttr("idref")] = selected
+	}
+	for _, value := range tailoringDom.SelectElements("//*[local-name()='Profile']/*[local-name()='set-value']") {
+		content.values[value.SelectAttr("idref")] = strings.TrimSpace(value.InnerText())
+	}
+	return content, nil
+}
+
+// DiffTailoring compares the rule selections and variable values of two
+// tailoring files. The order of the elements and the formatting of the
+// documents are not significant.
+func DiffTailoring(oldTailoring, newTailoring io.Reader) (TailoringDiff, error) {
+	oldContent, err := readTailoring(oldTailoring)
+	if err != nil {
+		return TailoringDiff{}, fmt.Errorf("old tailoring: %w", err)
+	}
+	newContent, err := readTailoring(newTailoring)
+	if err != nil {
+		return TailoringDiff{}, fmt.Errorf("new tailoring: %w", err)
+	}
+
+	var diff TailoringDiff
+	for _, ruleID := range slices.Sorted(maps.Keys(newContent.selections)) {
+		selected := newContent.selections[ruleID]
+		if oldSelected, ok := oldContent.selections[ruleID]; !ok || oldSelected != selected {
+			diff.AddedSelections = append(diff.AddedSelections, xccdf.SelectElement{IDRef: ruleID, Selected: selected})
+		}
+	}
+	for _, ruleID := range slices.Sorted(maps.Keys(oldContent.selections)) {
+		selected := oldContent.selections[ruleID]
+		if newSelected, ok := newContent.selections[ruleID]; !ok || newSelected != selected {
+			diff.RemovedSelections = append(diff.RemovedSelections, xccdf.SelectElement{IDRef: ruleID, Selected: selected})
+		}
+	}
+	for _, varID := range slices.Sorted(maps.Keys(newContent.values)) {
+		value := newContent.values[varID]
+		oldValue, ok := oldContent.values[varID]
+		switch {
+		case !ok:
+			diff.AddedValues = append(diff.AddedValues, xccdf.SetValueElement{IDRef: varID, Value: value})
+		case oldValue != value:
+			diff.ChangedValues = append(diff.ChangedValues, ValueChange{IDRef: varID, OldValue: oldValue, NewValue: value})
+		}
+	}
+	for _, varID := range slices.Sorted(maps.Keys(oldContent.values)) {
+		if _, ok := newContent.values[varID]; !ok {
+			diff.RemovedValues = append(diff.RemovedValues, xccdf.SetValueElement{IDRef: varID, Value: oldContent.values[varID]})
+		}
 	}
-	return xccdf.XMLHeader + "\n" + string(output), nil
+	return diff, nil
 }
@@ -3,8 +3,12 @@
 package xccdf
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -357,6 +361,10 @@ func TestSelectAdditionalRules(t *testing.T) {
 func TestGetTailoringSelections(t *testing.T) {
 	dsPath := filepath.Join(testDataDir, "ssg-rhel-ds.xml")
 	parsedProfile, _ := getProfileElementTest(t, "xccdf_org.ssgproject.content_profile_test_profile")
+	dsRules, err := GetDsRules(dsPath)
+	if err != nil {
+		t.Fatalf("GetDsRules() error = %v", err)
+	}
 
 	tests := []struct {
 		name           string
@@ -415,7 +423,7 @@ func TestGetTailoringSelections(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := getTailoringSelections(tt.oscalPolicy, parsedProfile, dsPath)
+			result, err := getTailoringSelections(tt.oscalPolicy, parsedProfile, dsRules)
 			if (err != nil) != tt.expectedError {
 				t.Errorf("getTailoringSelections() error = %v; want %v", err, tt.expectedError)
 			}
@@ -675,10 +683,13 @@ func TestGetTailoringProfile(t *testing.T) {
 		},
 	}
 
-	result, err := getTailoringProfile(profileId, dsPath, tailoringPolicy)
+	result, skippedRules, err := getTailoringProfile(profileId, profileId, dsPath, tailoringPolicy)
 	if err != nil {
 		t.Fatalf("getTailoringProfile() error = %v", err)
 	}
+	if len(skippedRules) != 0 {
+		t.Errorf("getTailoringProfile() skipped rules = %v; want none", skippedRules)
+	}
 
 	if result.ID != expected.ID {
 		t.Errorf("getTailoringProfile().ID = %v; want %v", result.ID, expected.ID)
@@ -721,6 +732,8 @@ func TestPolicyToXML(t *testing.T) {
 				},
 			},
 		},
+		// rules unknown to the datastream are left out of the tailoring
+		{Rule: extensions.Rule{ID: "this_rule_is_not_in_datastream"}},
 	}
 
 	cfg := new(config.Config)
@@ -742,10 +755,13 @@ func TestPolicyToXML(t *testing.T) {
   </xccdf-1.2:Profile>
 </xccdf-1.2:Tailoring>`
 
-	result, err := PolicyToXML(tailoringPolicy, cfg)
+	result, skippedRules, err := PolicyToXML(tailoringPolicy, cfg)
 	if err != nil {
 		t.Fatalf("PolicyToXML() error = %v", err)
 	}
+	if !reflect.DeepEqual(skippedRules, []string{"this_rule_is_not_in_datastream"}) {
+		t.Errorf("PolicyToXML() skipped rules = %v; want [this_rule_is_not_in_datastream]", skippedRules)
+	}
 
 	// It takes some seconds to generate the tailoring file and the time differs.
 	// So we remove the time attribute to compare the XMLs.
@@ -755,4 +771,256 @@ func TestPolicyToXML(t *testing.T) {
 	if actual != expected {
 		t.Errorf("PolicyToXML() = %v; want %v", actual, expected)
 	}
+
+	// the tailoring profile of another profile extends the base profile
+	cfg.Parameters.Profile = "custom"
+	cfg.Tailoring.BaseProfile = profileId
+	result, _, err = PolicyToXML(tailoringPolicy, cfg)
+	if err != nil {
+		t.Fatalf("PolicyToXML() with base profile error = %v", err)
+	}
+	wantProfile := `<xccdf-1.2:Profile id="xccdf_complytime.openscapplugin_profile_custom_complytime" extends="xccdf_org.ssgproject.content_profile_test_profile">`
+	if !strings.Contains(result, wantProfile) {
+		t.Errorf("PolicyToXML() with base profile = %v; want profile %v", result, wantProfile)
+	}
+	if removeVersionTimeTest(result) != strings.Replace(expected, "profile_test_profile_complytime", "profile_custom_complytime", 1) {
+		t.Errorf("PolicyToXML() with base profile = %v; want the selections and values of the base profile", result)
+	}
+
+	cfg.Tailoring.BaseProfile = "missing_profile"
+	if _, _, err := PolicyToXML(tailoringPolicy, cfg); err == nil {
+		t.Errorf("PolicyToXML() with missing base profile error = nil; want error")
+	}
+}
+
+// TestPolicyToXMLNamespaces tests the namespaces of the tailoring generated by
+// the PolicyToXML function.
+func TestPolicyToXMLNamespaces(t *testing.T) {
+	tailoringPolicy := policy.Policy{{Rule: extensions.Rule{ID: "account_unique_id"}}}
+	cfg := new(config.Config)
+	cfg.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	cfg.Parameters.Profile = "test_profile"
+	defaultXML, _, err := PolicyToXML(tailoringPolicy, cfg)
+	if err != nil {
+		t.Fatalf("PolicyToXML() error = %v", err)
+	}
+
+	cfg.Tailoring.NamespacePrefix = "xccdf"
+	cfg.Tailoring.Namespaces = "xsi=http://www.w3.org/2001/XMLSchema-instance,dc=http://purl.org/dc/elements/1.1/"
+	result, _, err := PolicyToXML(tailoringPolicy, cfg)
+	if err != nil {
+		t.Fatalf("PolicyToXML() with namespaces error = %v", err)
+	}
+	wantRoot := `<xccdf:Tailoring xmlns:xccdf="http://checklists.nist.gov/xccdf/1.2" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" id="xccdf_complytime.openscapplugin_tailoring_complytime">`
+	if !strings.Contains(result, wantRoot) {
+		t.Errorf("PolicyToXML() with namespaces = %v; want root element %v", result, wantRoot)
+	}
+	if strings.Contains(result, "xccdf-1.2") {
+		t.Errorf("PolicyToXML() with namespaces = %v; want no default prefix", result)
+	}
+
+	// the content is the same whatever the namespaces
+	diff, err := DiffTailoring(strings.NewReader(defaultXML), strings.NewReader(result))
+	if err != nil {
+		t.Fatalf("DiffTailoring() error = %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("DiffTailoring() = %v; want no difference", diff)
+	}
+	tailoringPath := filepath.Join(t.TempDir(), "tailoring.xml")
+	if err := os.WriteFile(tailoringPath, []byte(result), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTailoringProfile(tailoringPath, "test_profile"); err != nil {
+		t.Errorf("VerifyTailoringProfile() with namespaces error = %v", err)
+	}
+}
+
+// TestVerifyTailoringProfile tests the VerifyTailoringProfile function.
+func TestVerifyTailoringProfile(t *testing.T) {
+	tailoringPolicy := policy.Policy{{Rule: extensions.Rule{ID: "account_unique_id"}}}
+	cfg := new(config.Config)
+	cfg.Files.Datastream = filepath.Join(testDataDir, "ssg-rhel-ds.xml")
+	cfg.Parameters.Profile = "test_profile"
+	tailoringXML, _, err := PolicyToXML(tailoringPolicy, cfg)
+	if err != nil {
+		t.Fatalf("PolicyToXML() error = %v", err)
+	}
+	tailoringPath := filepath.Join(t.TempDir(), "tailoring.xml")
+	if err := os.WriteFile(tailoringPath, []byte(tailoringXML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyTailoringProfile(tailoringPath, "test_profile"); err != nil {
+		t.Errorf("VerifyTailoringProfile() error = %v", err)
+	}
+
+	err = VerifyTailoringProfile(tailoringPath, "cis")
+	if !errors.Is(err, ErrTailoringProfileMismatch) {
+		t.Errorf("VerifyTailoringProfile() with another profile error = %v; want %v", err, ErrTailoringProfileMismatch)
+	}
+	wantMessage := "has profile xccdf_complytime.openscapplugin_profile_test_profile_complytime, expected xccdf_complytime.openscapplugin_profile_cis_complytime"
+	if err != nil && !strings.Contains(err.Error(), wantMessage) {
+		t.Errorf("VerifyTailoringProfile() with another profile error = %v; want %q", err, wantMessage)
+	}
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.xml")
+	if err := os.WriteFile(emptyPath, []byte(`<Tailoring/>`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTailoringProfile(emptyPath, "test_profile"); !errors.Is(err, ErrTailoringProfileMismatch) {
+		t.Errorf("VerifyTailoringProfile() without profile error = %v; want %v", err, ErrTailoringProfileMismatch)
+	}
+
+	if err := VerifyTailoringProfile(filepath.Join(t.TempDir(), "missing.xml"), "test_profile"); err == nil {
+		t.Errorf("VerifyTailoringProfile() with missing file error = nil; want error")
+	}
+}
+
+// TestFilterPolicyRules tests the FilterPolicyRules function.
+func TestFilterPolicyRules(t *testing.T) {
+	oscalPolicy := policy.Policy{
+		{Rule: extensions.Rule{ID: "package_telnet-server_removed"}},
+		{Rule: extensions.Rule{ID: "package_telnet_removed"}},
+		{Rule: extensions.Rule{ID: "account_unique_id"}},
+	}
+
+	tests := []struct {
+		name          string
+		ruleIDs       []string
+		expectedRules []string
+		expectedError bool
+	}{
+		{
+			name:          "Selected rules in policy",
+			ruleIDs:       []string{"account_unique_id", "package_telnet_removed"},
+			expectedRules: []string{"account_unique_id", "package_telnet_removed"},
+		},
+		{
+			name:          "Selected rule not in policy",
+			ruleIDs:       []string{"account_unique_id", "this_rule_is_not_in_policy"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FilterPolicyRules(oscalPolicy, tt.ruleIDs)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("FilterPolicyRules() error = %v; want %v", err, tt.expectedError)
+			}
+			var gotRules []string
+			for _, rule := range result {
+				gotRules = append(gotRules, rule.Rule.ID)
+			}
+			if !reflect.DeepEqual(gotRules, tt.expectedRules) {
+				t.Errorf("FilterPolicyRules() = %v; want %v", gotRules, tt.expectedRules)
+			}
+		})
+	}
+}
+
+// TestDiffTailoring tests the DiffTailoring function.
+func TestDiffTailoring(t *testing.T) {
+	oldTailoring := `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf-1.2:Tailoring xmlns:xccdf-1.2="http://checklists.nist.gov/xccdf/1.2" id="xccdf_complytime.openscapplugin_tailoring_complytime">
+  <xccdf-1.2:Profile id="xccdf_complytime.openscapplugin_profile_test_profile_complytime">
+    <xccdf-1.2:select idref="rule_kept" selected="true"></xccdf-1.2:select>
+    <xccdf-1.2:select idref="rule_removed" selected="true"></xccdf-1.2:select>
+    <xccdf-1.2:select idref="rule_changed" selected="false"></xccdf-1.2:select>
+    <xccdf-1.2:set-value idref="var_kept">1</xccdf-1.2:set-value>
+    <xccdf-1.2:set-value idref="var_changed">yescrypt</xccdf-1.2:set-value>
+    <xccdf-1.2:set-value idref="var_removed">600</xccdf-1.2:set-value>
+  </xccdf-1.2:Profile>
+</xccdf-1.2:Tailoring>`
+
+	// the same content with different namespace prefixes, order and spacing
+	equivalentTailoring := `<Tailoring xmlns="http://checklists.nist.gov/xccdf/1.2" id="tailoring">
+  <Profile id="profile">
+    <set-value idref="var_removed"> 600 </set-value>
+    <select selected="0" idref="rule_changed"/>
+    <select idref="rule_kept" selected="1"/>
+    <set-value idref="var_changed">yescrypt</set-value>
+    <select idref="rule_removed" selected="true"/>
+    <set-value idref="var_kept">
+      1
+    </set-value>
+  </Profile>
+</Tailoring>`
+
+	newTailoring := `<Tailoring xmlns="http://checklists.nist.gov/xccdf/1.2" id="tailoring">
+  <Profile id="profile">
+    <select idref="rule_kept" selected="true"/>
+    <select idref="rule_changed" selected="true"/>
+    <select idref="rule_added" selected="true"/>
+    <set-value idref="var_kept">1</set-value>
+    <set-value idref="var_changed">sha512</set-value>
+    <set-value idref="var_added">900</set-value>
+  </Profile>
+</Tailoring>`
+
+	tests := []struct {
+		name          string
+		oldTailoring  string
+		newTailoring  string
+		expectedDiff  TailoringDiff
+		expectedError string
+	}{
+		{
+			name:         "Equivalent tailoring files",
+			oldTailoring: oldTailoring,
+			newTailoring: equivalentTailoring,
+		},
+		{
+			name:         "Changed tailoring files",
+			oldTailoring: oldTailoring,
+			newTailoring: newTailoring,
+			expectedDiff: TailoringDiff{
+				AddedSelections: []xccdf.SelectElement{
+					{IDRef: "rule_added", Selected: true},
+					{IDRef: "rule_changed", Selected: true},
+				},
+				RemovedSelections: []xccdf.SelectElement{
+					{IDRef: "rule_changed", Selected: false},
+					{IDRef: "rule_removed", Selected: true},
+				},
+				AddedValues:   []xccdf.SetValueElement{{IDRef: "var_added", Value: "900"}},
+				RemovedValues: []xccdf.SetValueElement{{IDRef: "var_removed", Value: "600"}},
+				ChangedValues: []ValueChange{{IDRef: "var_changed", OldValue: "yescrypt", NewValue: "sha512"}},
+			},
+		},
+		{
+			name:          "Not a tailoring file",
+			oldTailoring:  oldTailoring,
+			newTailoring:  `<Benchmark xmlns="http://checklists.nist.gov/xccdf/1.2"/>`,
+			expectedError: "new tailoring: error parsing tailoring file: no 'Tailoring' element",
+		},
+		{
+			name:          "Invalid selection",
+			oldTailoring:  `<Tailoring><Profile><select idref="rule" selected="yes"/></Profile></Tailoring>`,
+			newTailoring:  newTailoring,
+			expectedError: `old tailoring: invalid selection of rule rule: strconv.ParseBool: parsing "yes": invalid syntax`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffTailoring(strings.NewReader(tt.oldTailoring), strings.NewReader(tt.newTailoring))
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("DiffTailoring() error = %v; want %v", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DiffTailoring() error = %v", err)
+			}
+			if !reflect.DeepEqual(diff, tt.expectedDiff) {
+				t.Errorf("DiffTailoring() = %+v; want %+v", diff, tt.expectedDiff)
+			}
+			if diff.Empty() != reflect.DeepEqual(tt.expectedDiff, TailoringDiff{}) {
+				t.Errorf("TailoringDiff.Empty() = %v", diff.Empty())
+			}
+		})
+	}
 }
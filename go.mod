@@ -4,6 +4,7 @@ go 1.24.0
 
 require (
 	github.com/ComplianceAsCode/compliance-operator v1.7.0
+	github.com/ProtonMail/go-crypto v1.2.0
 	github.com/adrg/xdg v0.5.3
 	github.com/antchfx/xmlquery v1.4.4
 	github.com/charmbracelet/bubbles v0.21.0
@@ -24,7 +25,6 @@ require (
 require (
 	dario.cat/mergo v1.0.2 // indirect
 	github.com/Microsoft/go-winio v0.6.2 // indirect
-	github.com/ProtonMail/go-crypto v1.2.0 // indirect
 	github.com/antchfx/xpath v1.3.3 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
@@ -27,6 +27,10 @@ const (
 	DataRootDir            = "/usr/share"
 	PluginBinaryRootDir    = "/usr/libexec/"
 	DefaultPluginConfigDir = "/etc/complytime/config.d/"
+	// PluginConfigDirEnv is the environment variable setting the directory
+	// where users customize plugin configuration options.
+	PluginConfigDirEnv = "COMPLYTIME_PLUGIN_CONFIG_DIR"
+	pluginConfigDir    = "config.d"
 )
 
 // ErrNoComponentDefinitionsFound returns an error indicated the supplied directory
@@ -197,3 +201,26 @@ func ActionsContextFromPlan(assessmentPlan *oscalTypes.AssessmentPlan) (*actions
 	inputContext.Settings = apSettings
 	return inputContext, nil
 }
+
+// PluginConfigDirs returns the directories where users customize plugin
+// configuration options, in search order: the directory set with the
+// PluginConfigDirEnv environment variable, the user directory in the XDG
+// configuration home and the system-wide DefaultPluginConfigDir.
+func PluginConfigDirs() []string {
+	var dirs []string
+	if dir := os.Getenv(PluginConfigDirEnv); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	return append(dirs, filepath.Join(xdg.ConfigHome, ApplicationDir, pluginConfigDir), DefaultPluginConfigDir)
+}
+
+// findPluginConfigDir returns the first of the given directories that exists,
+// or an empty string if none exists.
+func findPluginConfigDir(dirs []string) string {
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
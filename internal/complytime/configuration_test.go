@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/adrg/xdg"
 	"github.com/oscal-compass/oscal-sdk-go/validation"
 	"github.com/stretchr/testify/require"
 )
@@ -41,6 +42,23 @@ func TestApplicationDirectory(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestPluginConfigDirs(t *testing.T) {
+	xdgConfigDir := filepath.Join(xdg.ConfigHome, "complytime", "config.d")
+	t.Setenv(PluginConfigDirEnv, "")
+	require.Equal(t, []string{xdgConfigDir, DefaultPluginConfigDir}, PluginConfigDirs())
+
+	envDir := t.TempDir()
+	t.Setenv(PluginConfigDirEnv, envDir)
+	require.Equal(t, []string{envDir, xdgConfigDir, DefaultPluginConfigDir}, PluginConfigDirs())
+
+	// the first existing directory is used
+	missingDir := filepath.Join(envDir, "missing")
+	file := filepath.Join(envDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0600))
+	require.Equal(t, envDir, findPluginConfigDir([]string{missingDir, file, envDir}))
+	require.Equal(t, "", findPluginConfigDir([]string{missingDir, file}))
+}
+
 func TestFindComponentDefinitions(t *testing.T) {
 	compDefs, err := FindComponentDefinitions("testdata/complytime/bundles", validation.NoopValidator{})
 	require.NoError(t, err)
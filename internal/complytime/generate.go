@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package complytime
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+// GeneratePolicies generates the policy of each launched plugin for the policy
+// of its component, in plugin id order. A plugin that generated its policy but
+// failed to generate its remediation files is logged and does not stop the
+// other plugins, since its policy can still be evaluated. Any other error stops
+// the generation.
+func GeneratePolicies(ctx context.Context, inputContext *actions.InputContext, providers map[plugin.ID]policy.Provider, logger hclog.Logger) error {
+	for _, pluginId := range slices.Sorted(maps.Keys(providers)) {
+		err := actions.GeneratePolicy(ctx, inputContext, map[plugin.ID]policy.Provider{pluginId: providers[pluginId]})
+		if pluginerr.Is(err, pluginerr.ErrRemediationFailed) {
+			logger.Warn("Policy generated without remediation files", "plugin", pluginId, "err", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package complytime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/oscal-compass/oscal-sdk-go/extensions"
+	"github.com/oscal-compass/oscal-sdk-go/models/components"
+	"github.com/oscal-compass/oscal-sdk-go/settings"
+	"github.com/stretchr/testify/require"
+
+	"github.com/complytime/complyctl/pkg/pluginerr"
+)
+
+// generateProvider is a policy.Provider failing to generate with a fixed
+// error.
+type generateProvider struct {
+	resultsProvider
+	err    error
+	called bool
+}
+
+func (p *generateProvider) Generate(policy.Policy) error {
+	p.called = true
+	return p.err
+}
+
+func TestGeneratePolicies(t *testing.T) {
+	var validationComponents []components.Component
+	for _, id := range []string{"first", "second"} {
+		props := []oscalTypes.Property{
+			{Name: extensions.RuleIdProp, Value: "rule_" + id, Ns: extensions.TrestleNameSpace, Remarks: "rule_set_" + id},
+			{Name: extensions.CheckIdProp, Value: "check_" + id, Ns: extensions.TrestleNameSpace, Remarks: "rule_set_" + id},
+		}
+		validationComponents = append(validationComponents, components.NewDefinedComponentAdapter(oscalTypes.DefinedComponent{
+			Type:  "validation",
+			Title: id,
+			Props: &props,
+		}))
+	}
+	inputContext, err := actions.NewContext(validationComponents)
+	require.NoError(t, err)
+	inputContext.Settings = settings.NewSettings(map[string]struct{}{"rule_first": {}, "rule_second": {}}, nil)
+
+	// a remediation failure of the first plugin, received over gRPC with its
+	// message only, does not stop the second
+	remediationErr := fmt.Errorf("%w: oscap failed", pluginerr.ErrRemediationFailed)
+	first := &generateProvider{err: errors.New("rpc error: code = Internal desc = " + remediationErr.Error())}
+	second := &generateProvider{}
+	providers := map[plugin.ID]policy.Provider{"first": first, "second": second}
+	require.NoError(t, GeneratePolicies(context.Background(), inputContext, providers, hclog.NewNullLogger()))
+	require.True(t, first.called)
+	require.True(t, second.called)
+
+	// other failures stop the generation
+	first.err = errors.New("rpc error: code = Internal desc = invalid datastream")
+	second.called = false
+	require.ErrorContains(t, GeneratePolicies(context.Background(), inputContext, providers, hclog.NewNullLogger()), "plugin first: rpc error")
+	require.False(t, second.called)
+}
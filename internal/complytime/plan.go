@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
 	"github.com/oscal-compass/oscal-sdk-go/extensions"
@@ -74,6 +75,32 @@ func Settings(plan *oscalTypes.AssessmentPlan) (settings.Settings, error) {
 	return settings.Settings{}, ErrNoActivities
 }
 
+// RulesByControl returns the ids of the rules assessing each control of the
+// given assessment plan, by control id, from the controls related to its
+// activities.
+func RulesByControl(plan *oscalTypes.AssessmentPlan) (map[string][]string, error) {
+	if plan.LocalDefinitions == nil || plan.LocalDefinitions.Activities == nil {
+		return nil, ErrNoActivities
+	}
+	rulesByControl := make(map[string][]string)
+	for _, activity := range *plan.LocalDefinitions.Activities {
+		if activity.RelatedControls == nil {
+			continue
+		}
+		for _, selection := range activity.RelatedControls.ControlSelections {
+			if selection.IncludeControls == nil {
+				continue
+			}
+			for _, control := range *selection.IncludeControls {
+				if !slices.Contains(rulesByControl[control.ControlId], activity.Title) {
+					rulesByControl[control.ControlId] = append(rulesByControl[control.ControlId], activity.Title)
+				}
+			}
+		}
+	}
+	return rulesByControl, nil
+}
+
 // loadControlTitlesFromSource loads all control titles from a source and returns them as a map
 func loadControlTitlesFromSource(controlSource string, appDir ApplicationDirectory, validator validation.Validator) (map[string]string, error) {
 	profile, err := LoadProfile(appDir, controlSource, validator)
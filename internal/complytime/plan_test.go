@@ -69,3 +69,29 @@ func TestPlan(t *testing.T) {
 	require.NotNil(t, ap.Metadata.Props)
 	require.Contains(t, *ap.Metadata.Props, wantProp)
 }
+
+func TestRulesByControl(t *testing.T) {
+	_, err := RulesByControl(&oscalTypes.AssessmentPlan{})
+	require.ErrorIs(t, err, ErrNoActivities)
+
+	relatedControls := func(controlIds ...string) *oscalTypes.ReviewedControls {
+		var controls []oscalTypes.AssessedControlsSelectControlById
+		for _, controlId := range controlIds {
+			controls = append(controls, oscalTypes.AssessedControlsSelectControlById{ControlId: controlId})
+		}
+		return &oscalTypes.ReviewedControls{ControlSelections: []oscalTypes.AssessedControls{{IncludeControls: &controls}}}
+	}
+	plan := &oscalTypes.AssessmentPlan{LocalDefinitions: &oscalTypes.LocalDefinitions{
+		Activities: &[]oscalTypes.Activity{
+			{Title: "rule_a", RelatedControls: relatedControls("ac-1", "ac-2")},
+			{Title: "rule_b", RelatedControls: relatedControls("ac-1", "ac-1")},
+			{Title: "rule_c"},
+		},
+	}}
+	rulesByControl, err := RulesByControl(plan)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"ac-1": {"rule_a", "rule_b"},
+		"ac-2": {"rule_a"},
+	}, rulesByControl)
+}
@@ -3,11 +3,19 @@
 package complytime
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/oscal-compass/compliance-to-policy-go/v2/framework"
@@ -25,9 +33,20 @@ type PluginOptions struct {
 	// Profile is the compliance profile that the plugin should use for
 	// pre-defined policy groups.
 	Profile string `config:"profile"`
+	// PluginProfiles are the profiles used by plugins, by plugin id. They
+	// take precedence over Profile.
+	PluginProfiles map[string]string
 	// UserConfigRoot is the root directory where users customize
 	// plugin configuration options
 	UserConfigRoot string `config:"userconfigroot"`
+	// PluginConfigRoots are root directories where users customize the
+	// configuration options of a plugin, by plugin id. They are searched
+	// before UserConfigRoot.
+	PluginConfigRoots map[string]string
+	// RequiredOptions are the options that must be explicitly configured for
+	// every plugin, with a non-empty value set in the user plugin
+	// configuration or in the environment, instead of a manifest default.
+	RequiredOptions []string
 }
 
 // NewPluginOptions created a new PluginOptions struct.
@@ -43,7 +62,9 @@ func (p PluginOptions) Validate() error {
 	if p.Workspace == "" {
 		return errors.New("workspace must be set")
 	}
-	if p.Profile == "" {
+	// The profile can also be set by PluginProfiles or by the default of a
+	// plugin configuration file, so it is only required without them.
+	if p.Profile == "" && len(p.PluginProfiles) == 0 && p.UserConfigRoot == "" && len(p.PluginConfigRoots) == 0 {
 		return errors.New("profile must be set")
 	}
 	if p.UserConfigRoot != "" {
@@ -51,87 +72,722 @@ func (p PluginOptions) Validate() error {
 			return errors.New("user config root does not exist")
 		}
 	}
+	for pluginId, configRoot := range p.PluginConfigRoots {
+		if _, err := os.Stat(configRoot); os.IsNotExist(err) {
+			return fmt.Errorf("user config root for plugin %s does not exist", pluginId)
+		}
+	}
+	for _, option := range p.RequiredOptions {
+		if strings.TrimSpace(option) == "" {
+			return errors.New("required option names must not be empty")
+		}
+	}
+	// Generated files written in the workspace could overwrite the
+	// plugin configuration files.
+	if p.UserConfigRoot != "" && containsPath(p.Workspace, p.UserConfigRoot) {
+		return fmt.Errorf("user config root %s must not be in workspace %s", p.UserConfigRoot, p.Workspace)
+	}
+	for pluginId, configRoot := range p.PluginConfigRoots {
+		if containsPath(p.Workspace, configRoot) {
+			return fmt.Errorf("user config root for plugin %s must not be in workspace %s", pluginId, p.Workspace)
+		}
+	}
 	return nil
 }
 
+// containsPath returns whether path is dir or a path below dir, once symbolic
+// links are resolved.
+func containsPath(dir, path string) bool {
+	rel, err := filepath.Rel(resolvePath(dir), resolvePath(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// resolvePath returns the absolute path with symbolic links resolved. Paths
+// that do not exist yet, such as a new workspace, are only made absolute.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// LaunchOptions control how Plugins launches the requested plugins.
+type LaunchOptions struct {
+	// MaxConcurrentLaunches is the maximum number of plugins launched at the
+	// same time. Plugins are launched one at a time when it is lower than two.
+	MaxConcurrentLaunches int
+	// BestEffort skips the requested plugins that are not installed, with a
+	// warning, instead of failing.
+	BestEffort bool
+	// LaunchTimeout is the time given to each plugin to launch and be
+	// configured. There is no timeout when it is not positive.
+	LaunchTimeout time.Duration
+	// Dependencies are the plugins each plugin depends on, by plugin id. A
+	// plugin is launched once all the plugins it depends on are launched.
+	// Dependencies of plugins that are not requested are ignored.
+	Dependencies map[plugin.ID][]plugin.ID
+}
+
 // ToMap transforms the PluginOption struct into a map that can be consumed
 // by the C2P Plugin Manager.
+//
+// The profile is resolved with the following precedence: the PluginProfiles
+// option of the plugin, then the Profile option, then the profile default in the user plugin configuration file, then the
+// profile default in the installed plugin manifest. Other options set in the
+// environment, see OptionEnv, take precedence over the configuration file.
+// Required options without a default value in the configuration file must
+// then be set in the environment.
 func (p PluginOptions) ToMap(pluginId string, logger hclog.Logger) (map[string]string, error) {
+	pluginSelections, err := p.toSelections(pluginId, nil, make(configManifests), logger)
+	return pluginSelections.Values, err
+}
+
+// Selections are the configuration options resolved for a plugin.
+type Selections struct {
+	// Values are the option values by option name.
+	Values map[string]string
+	// Sensitive are the names of the options holding secrets.
+	Sensitive []string
+}
+
+// Redacted returns the option values with the values of sensitive options
+// replaced, so the selections can be logged or persisted.
+func (s Selections) Redacted() map[string]string {
+	redacted := maps.Clone(s.Values)
+	for _, name := range s.Sensitive {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = redactedValue
+		}
+	}
+	return redacted
+}
+
+// toSelections resolves the selections of a plugin like ToMap, with the user
+// plugin configuration files parsed through the given cache. The options of
+// the installed plugin manifest can also be set with environment variables.
+func (p PluginOptions) toSelections(pluginId string, installed []plugin.ConfigurationOption, manifests configManifests, logger hclog.Logger) (Selections, error) {
+	logger = logger.With("plugin", pluginId)
 	selections := make(map[string]string)
+	pluginSelections := Selections{Values: selections}
 	selections["workspace"] = p.Workspace
-	selections["profile"] = p.Profile
+	profile := p.Profile
+	if pluginProfile := p.PluginProfiles[pluginId]; pluginProfile != "" {
+		profile = pluginProfile
+	}
+	if profile != "" {
+		selections["profile"] = profile
+	}
 
-	if p.UserConfigRoot != "" {
-		configPath := filepath.Join(p.UserConfigRoot, "c2p-"+pluginId+"-manifest.json")
-		configFile, err := os.Open(configPath)
+	configPath, err := p.findPluginConfig(pluginId, logger)
+	if err != nil {
+		return pluginSelections, err
+	}
+	configured := make(map[string]bool)
+	if configPath != "" {
+		configManifest, err := manifests.load(configPath)
 		if err != nil {
-			if os.IsNotExist(err) {
-				logger.Debug(fmt.Sprintf("Plugin manifest file does not exist: %s", configPath))
-				return selections, nil
+			return pluginSelections, err
+		}
+		for _, configOption := range configManifest.Configuration {
+			configured[configOption.Name] = true
+			if configOption.Name == "profile" {
+				if profile == "" && configOption.Default != nil {
+					selections["profile"] = *configOption.Default
+				}
+				continue
+			}
+			if configOption.Name == "workspace" {
+				continue
 			}
-			return selections, fmt.Errorf("failed to open plugin config file: %w", err)
+			if configOption.Sensitive {
+				pluginSelections.Sensitive = append(pluginSelections.Sensitive, configOption.Name)
+			}
+			value, err := configOption.resolve(pluginId, configPath, logger)
+			if err != nil {
+				return pluginSelections, err
+			}
+			selections[configOption.Name] = value
+		}
+	}
+
+	// Options of the installed manifest without user configuration default
+	// to the manifest values, unless set in the environment.
+	for _, option := range installed {
+		if configured[option.Name] || option.Name == "workspace" || option.Name == "profile" {
+			continue
+		}
+		if value, ok := lookupOptionEnv(pluginId, option.Name, logger); ok {
+			selections[option.Name] = value
+		}
+	}
+
+	for _, option := range p.RequiredOptions {
+		if selections[option] == "" {
+			return pluginSelections, fmt.Errorf("option %s must be configured for plugin %s", option, pluginId)
+		}
+	}
+	return pluginSelections, nil
+}
+
+// OptionEnv returns the name of the environment variable overriding an option
+// of a plugin, COMPLYTIME_<PLUGIN ID>_<OPTION NAME>, in upper case with
+// characters other than letters and digits replaced by underscores.
+func OptionEnv(pluginId, option string) string {
+	toEnv := func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
 		}
-		defer configFile.Close()
+		return '_'
+	}
+	return strings.Map(toEnv, strings.ToUpper("complytime_"+pluginId+"_"+option))
+}
+
+// lookupOptionEnv returns the value of an option of a plugin set in the
+// environment, if any. Only the override is logged, not the value, which may
+// hold credentials even for options not marked as sensitive.
+func lookupOptionEnv(pluginId, option string, logger hclog.Logger) (string, bool) {
+	envName := OptionEnv(pluginId, option)
+	value, ok := os.LookupEnv(envName)
+	if !ok {
+		return "", false
+	}
+	logger.Debug("Option overridden by the environment", "option", option, "source", fmt.Sprintf("environment variable %s", envName))
+	return value, true
+}
+
+// redactedValue replaces the values of sensitive options in logs.
+const redactedValue = "[REDACTED]"
 
-		jsonParser := json.NewDecoder(configFile)
-		var configManifest plugin.Manifest
-		err = jsonParser.Decode(&configManifest)
+// configManifest is a user plugin configuration file. It has the format of a
+// plugin manifest, of which only the configuration options are used.
+type configManifest struct {
+	Configuration []configOption `json:"configuration"`
+}
+
+// configOption is a configuration option of a user plugin configuration file.
+//
+// Sensitive options, such as credentials, cannot have a default value. Their
+// value is read from the environment variable Env, if set, or else from the
+// file File.
+type configOption struct {
+	plugin.ConfigurationOption
+	Sensitive bool   `json:"sensitive,omitempty"`
+	Env       string `json:"env,omitempty"`
+	File      string `json:"file,omitempty"`
+}
+
+// secretValue returns the value of a sensitive option and where it was read
+// from. The source is empty if the option has no value.
+func (o configOption) secretValue() (string, string, error) {
+	if o.Env != "" {
+		if value, ok := os.LookupEnv(o.Env); ok {
+			return value, fmt.Sprintf("environment variable %s", o.Env), nil
+		}
+	}
+	if o.File != "" {
+		data, err := os.ReadFile(o.File)
+		if err != nil && !os.IsNotExist(err) {
+			return "", "", err
+		}
+		if err == nil {
+			return strings.TrimRight(string(data), "\r\n"), fmt.Sprintf("file %s", o.File), nil
+		}
+	}
+	return "", "", nil
+}
+
+// resolve returns the value of an option of the user plugin configuration file
+// configPath of a plugin: the value set in the environment, or else the secret
+// of a sensitive option or the default value. Options without a value are set
+// to an empty string, unless they are required.
+func (o configOption) resolve(pluginId, configPath string, logger hclog.Logger) (string, error) {
+	if value, ok := lookupOptionEnv(pluginId, o.Name, logger); ok {
+		return value, nil
+	}
+	if o.Sensitive {
+		value, source, err := o.secretValue()
 		if err != nil {
-			return selections, fmt.Errorf("failed to parse plugin config file: %w", err)
+			return "", fmt.Errorf("failed to read sensitive option %s in %s: %w", o.Name, configPath, err)
 		}
-		for _, configOption := range configManifest.Configuration {
-			if configOption.Name == "workspace" || configOption.Name == "profile" {
-				continue
-			} else {
-				if configOption.Default == nil {
-					if configOption.Required {
-						return selections, fmt.Errorf("missing default value for required option %s in %s", configOption.Name, configPath)
-					} else {
-						logger.Warn(fmt.Sprintf("Missing default value for %s in %s, it will be set to an empty string", configOption.Name, configPath))
-						selections[configOption.Name] = ""
-						continue
-					}
-				}
-				selections[configOption.Name] = *configOption.Default
+		if source == "" {
+			if o.Required {
+				return "", fmt.Errorf("missing value for required sensitive option %s in %s", o.Name, configPath)
 			}
+			logger.Warn("Missing value for sensitive option, it will be set to an empty string", "option", o.Name, "manifest", configPath)
+		} else {
+			logger.Debug("Option set", "option", o.Name, "value", redactedValue, "source", source)
+		}
+		return value, nil
+	}
+	if o.Default == nil {
+		// the environment variable was looked up first
+		if o.Required {
+			return "", fmt.Errorf("missing value for required option %s in %s: set a default value or the environment variable %s",
+				o.Name, configPath, OptionEnv(pluginId, o.Name))
+		}
+		logger.Warn("Missing default value, it will be set to an empty string", "option", o.Name, "manifest", configPath)
+		return "", nil
+	}
+	logger.Debug("Option set", "option", o.Name, "value", *o.Default, "source", configPath)
+	return *o.Default, nil
+}
+
+// readConfigManifest decodes a user plugin configuration file and validates
+// its configuration options. Errors point to the location of the problem in
+// the file.
+func readConfigManifest(configFile io.Reader, configPath string) (configManifest, error) {
+	data, err := io.ReadAll(configFile)
+	if err != nil {
+		return configManifest{}, fmt.Errorf("failed to read plugin config file %s: %w", configPath, err)
+	}
+
+	var manifest configManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			line, column := jsonPosition(data, syntaxErr.Offset)
+			return configManifest{}, fmt.Errorf("failed to parse plugin config file %s at line %d, column %d: %w", configPath, line, column, err)
+		case errors.As(err, &typeErr):
+			line, column := jsonPosition(data, typeErr.Offset)
+			return configManifest{}, fmt.Errorf("failed to parse plugin config file %s at line %d, column %d: %w", configPath, line, column, err)
+		}
+		return configManifest{}, fmt.Errorf("failed to parse plugin config file %s: %w", configPath, err)
+	}
+
+	if manifest.Configuration == nil {
+		return configManifest{}, fmt.Errorf("invalid plugin config file %s: missing \"configuration\" list", configPath)
+	}
+	for i, configOption := range manifest.Configuration {
+		if strings.TrimSpace(configOption.Name) == "" {
+			return configManifest{}, fmt.Errorf("invalid plugin config file %s: configuration option %d has no name", configPath, i)
+		}
+		if configOption.Sensitive && configOption.Default != nil {
+			return configManifest{}, fmt.Errorf("invalid plugin config file %s: sensitive option %s cannot have a default value, use env or file", configPath, configOption.Name)
+		}
+		if !configOption.Sensitive && (configOption.Env != "" || configOption.File != "") {
+			return configManifest{}, fmt.Errorf("invalid plugin config file %s: env and file are only supported for sensitive options, option %s is not sensitive", configPath, configOption.Name)
+		}
+	}
+	return manifest, nil
+}
+
+// jsonPosition returns the line and column of the byte offset in data,
+// starting at 1.
+func jsonPosition(data []byte, offset int64) (int, int) {
+	before := data[:min(int(offset), len(data))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// findPluginConfig returns the path of the user plugin configuration file of
+// a plugin, which is searched in the plugin config root of the plugin and then
+// in the user config root. It returns an empty path if no configuration file
+// exists.
+func (p PluginOptions) findPluginConfig(pluginId string, logger hclog.Logger) (string, error) {
+	var configRoots []string
+	if configRoot, ok := p.PluginConfigRoots[pluginId]; ok {
+		configRoots = append(configRoots, configRoot)
+	}
+	if p.UserConfigRoot != "" {
+		configRoots = append(configRoots, p.UserConfigRoot)
+	}
 
+	for _, configRoot := range configRoots {
+		configPath := filepath.Join(configRoot, "c2p-"+pluginId+"-manifest.json")
+		if _, err := os.Stat(configPath); err != nil {
+			if os.IsNotExist(err) {
+				logger.Debug("Plugin manifest file does not exist", "manifest", configPath)
+				continue
+			}
+			return configPath, fmt.Errorf("failed to open plugin config file: %w", err)
 		}
+		return configPath, nil
 	}
-	return selections, nil
+	return "", nil
+}
+
+// configManifests caches the user plugin configuration files by path, so each
+// file is parsed once when selections are built for several plugins.
+type configManifests map[string]configManifest
+
+// load returns the decoded configuration file at configPath, reading it on
+// first use.
+func (c configManifests) load(configPath string) (configManifest, error) {
+	if manifest, ok := c[configPath]; ok {
+		return manifest, nil
+	}
+	configFile, err := os.Open(configPath)
+	if err != nil {
+		return configManifest{}, fmt.Errorf("failed to open plugin config file: %w", err)
+	}
+	defer configFile.Close()
+
+	manifest, err := readConfigManifest(configFile, configPath)
+	if err != nil {
+		return configManifest{}, err
+	}
+	c[configPath] = manifest
+	return manifest, nil
 }
 
 // Plugins launches and configures plugins with the given complytime global options. This function returns the plugin map with the
 // launched plugins, a plugin cleanup function, and an error. The cleanup function should be used if it is not nil.
-func Plugins(manager *framework.PluginManager, inputs *actions.InputContext, selections PluginOptions, logger hclog.Logger) (map[plugin.ID]policy.Provider, func(), error) {
-	manifests, err := manager.FindRequestedPlugins(inputs.RequestedProviders())
+func Plugins(manager *framework.PluginManager, inputs *actions.InputContext, selections PluginOptions, launchOptions LaunchOptions, logger hclog.Logger) (map[plugin.ID]policy.Provider, func(), error) {
+	plugins, _, cleanup, err := PluginsWithSelections(manager, inputs, selections, launchOptions, logger)
+	return plugins, cleanup, err
+}
+
+// PluginsWithSelections is like Plugins and also returns the selections each plugin was configured with, by plugin id.
+// The selections are returned when the plugins fail to launch, to help troubleshooting.
+func PluginsWithSelections(manager *framework.PluginManager, inputs *actions.InputContext, selections PluginOptions, launchOptions LaunchOptions, logger hclog.Logger) (map[plugin.ID]policy.Provider, map[plugin.ID]Selections, func(), error) {
+	manifests, err := findPlugins(manager, inputs.RequestedProviders(), launchOptions.BestEffort, logger)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-
-	if selections.UserConfigRoot == "" {
-		if _, err := os.Stat(DefaultPluginConfigDir); err == nil {
-			selections.UserConfigRoot = DefaultPluginConfigDir
-		}
+	stages, err := launchStages(manifests, launchOptions.Dependencies)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+
+	selections = selections.withUserConfigRoot(logger)
 	if err := selections.Validate(); err != nil {
-		return nil, nil, fmt.Errorf("failed plugin config validation: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed plugin config validation: %w", err)
 	}
 
-	pluginSelectionsMap := make(map[plugin.ID]map[string]string)
-	for pluginId := range manifests {
-		selectionsMap, err := selections.ToMap(pluginId.String(), logger)
+	pluginSelectionsMap := make(map[plugin.ID]Selections)
+	configManifests := make(configManifests)
+	for pluginId, manifest := range manifests {
+		pluginSelections, err := selections.toSelections(pluginId.String(), manifest.Configuration, configManifests, logger)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
-		pluginSelectionsMap[pluginId] = selectionsMap
+		pluginSelectionsMap[pluginId] = pluginSelections
 	}
 	getSelections := func(pluginId plugin.ID) map[string]string {
-		return pluginSelectionsMap[pluginId]
+		return pluginSelectionsMap[pluginId].Values
+	}
+	plugins := make(map[plugin.ID]policy.Provider)
+	for i, stage := range stages {
+		if len(stages) > 1 {
+			logger.Debug("Launching plugins in dependency order", "stage", i+1, "plugins", slices.Sorted(maps.Keys(stage)))
+		}
+		launched, err := launchPlugins(manager, stage, getSelections, launchOptions.MaxConcurrentLaunches, launchOptions.LaunchTimeout, logger)
+		// Plugin subprocess has now been launched; cleanup always required below
+		if err != nil {
+			// plugins depending on the failed ones are not launched
+			return nil, pluginSelectionsMap, manager.Clean, err
+		}
+		maps.Copy(plugins, launched)
 	}
-	plugins, err := manager.LaunchPolicyPlugins(manifests, getSelections)
-	// Plugin subprocess has now been launched; cleanup always required below
+	return plugins, pluginSelectionsMap, manager.Clean, nil
+}
+
+// launchStages splits the manifests of the plugins to launch into stages
+// launched one after the other, so that every plugin is launched after the
+// plugins it depends on. Plugins without dependencies between them are in the
+// same stage. It fails if a plugin depends on a plugin that is not launched or
+// if the dependencies have a cycle.
+func launchStages(manifests plugin.Manifests, dependencies map[plugin.ID][]plugin.ID) ([]plugin.Manifests, error) {
+	remaining := make(map[plugin.ID][]plugin.ID, len(manifests))
+	for _, pluginId := range slices.Sorted(maps.Keys(manifests)) {
+		for _, dependency := range dependencies[pluginId] {
+			if _, ok := manifests[dependency]; !ok {
+				return nil, fmt.Errorf("plugin %s depends on plugin %s, which is not launched", pluginId, dependency)
+			}
+			if dependency == pluginId {
+				return nil, fmt.Errorf("plugin %s depends on itself", pluginId)
+			}
+		}
+		remaining[pluginId] = dependencies[pluginId]
+	}
+
+	var stages []plugin.Manifests
+	for len(remaining) > 0 {
+		stage := make(plugin.Manifests)
+		for pluginId, pluginDependencies := range remaining {
+			if !slices.ContainsFunc(pluginDependencies, func(dependency plugin.ID) bool {
+				_, ok := remaining[dependency]
+				return ok
+			}) {
+				stage[pluginId] = manifests[pluginId]
+			}
+		}
+		if len(stage) == 0 {
+			return nil, fmt.Errorf("plugin dependency cycle: %s", dependencyCycle(remaining))
+		}
+		for pluginId := range stage {
+			delete(remaining, pluginId)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// dependencyCycle returns a cycle of the dependencies of the plugins, such as
+// "a -> b -> a", given plugins that all depend on at least one of them.
+func dependencyCycle(dependencies map[plugin.ID][]plugin.ID) string {
+	var path []plugin.ID
+	visited := make(map[plugin.ID]int)
+	pluginId := slices.Min(slices.Collect(maps.Keys(dependencies)))
+	for {
+		if i, ok := visited[pluginId]; ok {
+			path = append(path[i:], pluginId)
+			break
+		}
+		visited[pluginId] = len(path)
+		path = append(path, pluginId)
+		for _, dependency := range slices.Sorted(slices.Values(dependencies[pluginId])) {
+			if _, ok := dependencies[dependency]; ok {
+				pluginId = dependency
+				break
+			}
+		}
+	}
+	ids := make([]string, 0, len(path))
+	for _, id := range path {
+		ids = append(ids, id.String())
+	}
+	return strings.Join(ids, " -> ")
+}
+
+// withUserConfigRoot returns the options with the UserConfigRoot set to the
+// first existing directory of PluginConfigDirs when it is not set.
+func (p PluginOptions) withUserConfigRoot(logger hclog.Logger) PluginOptions {
+	if p.UserConfigRoot == "" {
+		configDirs := PluginConfigDirs()
+		p.UserConfigRoot = findPluginConfigDir(configDirs)
+		if p.UserConfigRoot == "" {
+			logger.Debug("No plugin configuration directory found", "dirs", strings.Join(configDirs, ", "))
+		}
+	}
+	if p.UserConfigRoot != "" {
+		logger.Debug("Using plugin configuration directory", "dir", p.UserConfigRoot)
+	}
+	return p
+}
+
+// PluginReadiness reports whether a requested plugin is ready to be launched.
+type PluginReadiness struct {
+	// ID is the id of the requested plugin.
+	ID plugin.ID
+	// Selections are the selections the plugin would be configured with.
+	Selections Selections
+	// Err is the reason why the plugin cannot be launched, nil if the
+	// plugin is ready.
+	Err error
+}
+
+// Ready returns whether the plugin is ready to be launched.
+func (r PluginReadiness) Ready() bool {
+	return r.Err == nil
+}
+
+// ValidatePlugins checks that the requested plugins can be launched and configured with the given complytime global options,
+// without launching them. The requested plugins must be installed, their user configuration files must be valid and all their
+// required options must be set. It returns the readiness of each requested plugin, sorted by id, and an error if the global
+// options are invalid.
+func ValidatePlugins(manager *framework.PluginManager, inputs *actions.InputContext, selections PluginOptions, logger hclog.Logger) ([]PluginReadiness, error) {
+	requested := inputs.RequestedProviders()
+	slices.Sort(requested)
+	manifests, err := manager.FindRequestedPlugins(requested)
+	if err != nil {
+		manifests, _, err = findInstalledPlugins(manager, requested, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	selections = selections.withUserConfigRoot(logger)
+	if err := selections.Validate(); err != nil {
+		return nil, fmt.Errorf("failed plugin config validation: %w", err)
+	}
+
+	var report []PluginReadiness
+	configManifests := make(configManifests)
+	for _, pluginId := range requested {
+		readiness := PluginReadiness{ID: pluginId}
+		manifest, ok := manifests[pluginId]
+		if !ok {
+			readiness.Err = fmt.Errorf("plugin %s is not installed", pluginId)
+			report = append(report, readiness)
+			continue
+		}
+		readiness.Selections, readiness.Err = selections.toSelections(pluginId.String(), manifest.Configuration, configManifests, logger)
+		if readiness.Err == nil {
+			if _, err := manifest.ResolveOptions(readiness.Selections.Values); err != nil {
+				readiness.Err = fmt.Errorf("failed to configure plugin %s: %w", pluginId, err)
+			}
+		}
+		report = append(report, readiness)
+	}
+	return report, nil
+}
+
+// ValidatePluginConfig checks the user plugin configuration file at configPath of a plugin without launching it, and returns the
+// problems found. The file must parse and its required options must have a value, from the environment as when the plugin is
+// launched, from a default value or from the secret of a sensitive option. The options complyctl sets must not be redefined:
+// the workspace is always set by complyctl and the profile can only be given a default value, used when complyctl selects no
+// profile for the plugin.
+func ValidatePluginConfig(pluginId, configPath string) []error {
+	manifest, err := make(configManifests).load(configPath)
 	if err != nil {
-		return nil, manager.Clean, err
+		return []error{err}
+	}
+	var problems []error
+	defined := make(map[string]bool)
+	for _, configOption := range manifest.Configuration {
+		if defined[configOption.Name] {
+			problems = append(problems, fmt.Errorf("invalid plugin config file %s: option %s is defined several times", configPath, configOption.Name))
+			continue
+		}
+		defined[configOption.Name] = true
+		switch {
+		case configOption.Name == "workspace":
+			problems = append(problems, fmt.Errorf("invalid plugin config file %s: option workspace is set by complyctl and cannot be configured", configPath))
+		case configOption.Name == "profile" && configOption.Sensitive:
+			problems = append(problems, fmt.Errorf("invalid plugin config file %s: option profile cannot be sensitive, set a default value", configPath))
+		case configOption.Name == "profile":
+			// the default value is used when no profile is selected for
+			// the invocation
+		default:
+			if _, err := configOption.resolve(pluginId, configPath, hclog.NewNullLogger()); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	return problems
+}
+
+// findPlugins returns the manifests of the requested plugins. In best-effort
+// mode, the plugins that are not installed are skipped as long as one of the
+// requested plugins is found.
+func findPlugins(manager *framework.PluginManager, requested []plugin.ID, bestEffort bool, logger hclog.Logger) (plugin.Manifests, error) {
+	manifests, err := manager.FindRequestedPlugins(requested)
+	if err == nil || !bestEffort {
+		return manifests, err
+	}
+	manifests, missing, findErr := findInstalledPlugins(manager, requested, err)
+	if findErr != nil {
+		return nil, findErr
+	}
+	if len(manifests) == 0 {
+		return nil, err
+	}
+	for _, id := range missing {
+		logger.Warn("Plugin is not installed, skipping it", "plugin", id)
+	}
+	return manifests, nil
+}
+
+// findInstalledPlugins returns the manifests of the requested plugins that are
+// installed and the ids of the ones that are not, given the error returned
+// when finding all the requested plugins. The error is returned if it reports
+// other errors than missing plugins.
+func findInstalledPlugins(manager *framework.PluginManager, requested []plugin.ID, err error) (plugin.Manifests, []plugin.ID, error) {
+	missing := missingPlugins(err)
+	if len(missing) == 0 {
+		return nil, nil, err
+	}
+	available := slices.DeleteFunc(slices.Clone(requested), func(id plugin.ID) bool {
+		return slices.Contains(missing, id)
+	})
+	if len(available) == 0 {
+		return plugin.Manifests{}, missing, nil
+	}
+	manifests, err := manager.FindRequestedPlugins(available)
+	return manifests, missing, err
+}
+
+// missingPlugins returns the ids of the plugins reported as not found by err.
+// It returns nil if err also reports other errors, which cannot be skipped.
+func missingPlugins(err error) []plugin.ID {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var missing []plugin.ID
+	for _, err := range errs {
+		var notFound *plugin.NotFoundError
+		if !errors.As(err, &notFound) {
+			return nil
+		}
+		missing = append(missing, plugin.ID(notFound.PluginID))
+	}
+	return missing
+}
+
+// launchPlugins launches the plugins of the given manifests, with at most
+// maxConcurrent plugins being launched at the same time and each launch given
+// up after timeout, if positive. Errors of all the failed launches are returned
+// along with the plugins launched successfully. Plugins that did not launch in
+// time are stopped by the manager cleanup.
+func launchPlugins(manager *framework.PluginManager, manifests plugin.Manifests, getSelections framework.PluginConfig, maxConcurrent int, timeout time.Duration, logger hclog.Logger) (map[plugin.ID]policy.Provider, error) {
+	if timeout <= 0 && (maxConcurrent < 2 || len(manifests) < 2) {
+		return manager.LaunchPolicyPlugins(manifests, getSelections)
+	}
+	maxConcurrent = max(maxConcurrent, 1)
+	logger.Debug("Launching plugins", "plugins", len(manifests), "concurrency", maxConcurrent)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	plugins := make(map[plugin.ID]policy.Provider)
+	slots := make(chan struct{}, maxConcurrent)
+	for pluginId, manifest := range manifests {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			launched, err := launchPlugin(manager, pluginId, manifest, getSelections, timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			maps.Copy(plugins, launched)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return plugins, errors.Join(errs...)
+}
+
+// launchPlugin launches a single plugin, waiting at most timeout for it to be
+// launched and configured when timeout is positive.
+func launchPlugin(manager *framework.PluginManager, pluginId plugin.ID, manifest plugin.Manifest, getSelections framework.PluginConfig, timeout time.Duration) (map[plugin.ID]policy.Provider, error) {
+	manifests := plugin.Manifests{pluginId: manifest}
+	if timeout <= 0 {
+		return manager.LaunchPolicyPlugins(manifests, getSelections)
+	}
+
+	type launchResult struct {
+		plugins map[plugin.ID]policy.Provider
+		err     error
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// The launch cannot be interrupted, it is left to finish in the
+	// background until the plugin is stopped by the manager cleanup.
+	done := make(chan launchResult, 1)
+	go func() {
+		launched, err := manager.LaunchPolicyPlugins(manifests, getSelections)
+		done <- launchResult{plugins: launched, err: err}
+	}()
+	select {
+	case result := <-done:
+		return result.plugins, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("plugin %s did not launch within %s", pluginId, timeout)
 	}
-	return plugins, manager.Clean, nil
 }
@@ -3,14 +3,32 @@
 package complytime
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
 	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/framework"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
+	"github.com/oscal-compass/oscal-sdk-go/models/components"
 	"github.com/stretchr/testify/require"
 )
 
-var testPluginConfigRoot = filepath.Join("testdata", "complytime", "plugins")
+var (
+	testPluginConfigRoot        = filepath.Join("testdata", "complytime", "plugins")
+	testProfilePluginConfigRoot = filepath.Join("testdata", "complytime", "plugins-profile")
+)
 
 func TestPluginOptions(t *testing.T) {
 	testLogger := hclog.NewNullLogger()
@@ -44,6 +62,134 @@ func TestPluginOptions(t *testing.T) {
 				"results":   "results_test.xml",
 			},
 		},
+		{
+			name: "Valid/ProfileFromConfigFile",
+			selections: PluginOptions{
+				Workspace:      "testworkspace",
+				UserConfigRoot: testProfilePluginConfigRoot,
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "fileprofile",
+			},
+		},
+		{
+			name: "Valid/ProfileOverridesConfigFile",
+			selections: PluginOptions{
+				Workspace:      "testworkspace",
+				Profile:        "testprofile",
+				UserConfigRoot: testProfilePluginConfigRoot,
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "testprofile",
+			},
+		},
+		{
+			name: "Invalid/NoProfile",
+			selections: PluginOptions{
+				Workspace: "testworkspace",
+			},
+			wantErr: "profile must be set",
+		},
+		{
+			name: "Valid/PluginConfigRoot",
+			selections: PluginOptions{
+				Workspace:         "testworkspace",
+				UserConfigRoot:    testPluginConfigRoot,
+				PluginConfigRoots: map[string]string{"openscap": testProfilePluginConfigRoot},
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "fileprofile",
+			},
+		},
+		{
+			name: "Valid/PluginConfigRootOfOtherPlugin",
+			selections: PluginOptions{
+				Workspace:         "testworkspace",
+				Profile:           "testprofile",
+				UserConfigRoot:    testPluginConfigRoot,
+				PluginConfigRoots: map[string]string{"other": testProfilePluginConfigRoot},
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "testprofile",
+				"results":   "results_test.xml",
+			},
+		},
+		{
+			name: "Valid/PluginConfigRootWithoutManifest",
+			selections: PluginOptions{
+				Workspace:         "testworkspace",
+				Profile:           "testprofile",
+				UserConfigRoot:    testPluginConfigRoot,
+				PluginConfigRoots: map[string]string{"openscap": "testdata"},
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "testprofile",
+				"results":   "results_test.xml",
+			},
+		},
+		{
+			name: "Valid/PluginProfile",
+			selections: PluginOptions{
+				Workspace:      "testworkspace",
+				Profile:        "testprofile",
+				PluginProfiles: map[string]string{"openscap": "pluginprofile", "other": "otherprofile"},
+				UserConfigRoot: testProfilePluginConfigRoot,
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "pluginprofile",
+			},
+		},
+		{
+			name: "Valid/PluginProfileOfOtherPlugin",
+			selections: PluginOptions{
+				Workspace:      "testworkspace",
+				PluginProfiles: map[string]string{"other": "otherprofile"},
+				UserConfigRoot: testProfilePluginConfigRoot,
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "fileprofile",
+			},
+		},
+		{
+			name: "Valid/RequiredOptions",
+			selections: PluginOptions{
+				Workspace:       "testworkspace",
+				Profile:         "testprofile",
+				UserConfigRoot:  testPluginConfigRoot,
+				RequiredOptions: []string{"profile", "results"},
+			},
+			wantMap: map[string]string{
+				"workspace": "testworkspace",
+				"profile":   "testprofile",
+				"results":   "results_test.xml",
+			},
+		},
+		{
+			name: "Invalid/RequiredOptionNotConfigured",
+			selections: PluginOptions{
+				Workspace:       "testworkspace",
+				Profile:         "testprofile",
+				UserConfigRoot:  testPluginConfigRoot,
+				RequiredOptions: []string{"results", "policy"},
+			},
+			wantErr: "option policy must be configured for plugin openscap",
+		},
+		{
+			name: "Invalid/RequiredOptionEmptyName",
+			selections: PluginOptions{
+				Workspace:       "testworkspace",
+				Profile:         "testprofile",
+				RequiredOptions: []string{""},
+			},
+			wantErr: "required option names must not be empty",
+		},
 		{
 			name:       "Invalid/MissingOptions",
 			selections: PluginOptions{},
@@ -58,18 +204,505 @@ func TestPluginOptions(t *testing.T) {
 			},
 			wantErr: "user config root does not exist",
 		},
+		{
+			name: "Invalid/PluginConfigRoot",
+			selections: PluginOptions{
+				Workspace:         "testworkspace",
+				PluginConfigRoots: map[string]string{"openscap": "nonexistpath"},
+			},
+			wantErr: "user config root for plugin openscap does not exist",
+		},
+		{
+			name: "Invalid/WorkspaceIsUserConfigRoot",
+			selections: PluginOptions{
+				Workspace:      testPluginConfigRoot + "/",
+				UserConfigRoot: testPluginConfigRoot,
+			},
+			wantErr: "user config root testdata/complytime/plugins must not be in workspace testdata/complytime/plugins/",
+		},
+		{
+			name: "Invalid/WorkspaceContainsUserConfigRoot",
+			selections: PluginOptions{
+				Workspace:      "testdata",
+				UserConfigRoot: testPluginConfigRoot,
+			},
+			wantErr: "user config root testdata/complytime/plugins must not be in workspace testdata",
+		},
+		{
+			name: "Invalid/WorkspaceContainsPluginConfigRoot",
+			selections: PluginOptions{
+				Workspace:         "testdata",
+				PluginConfigRoots: map[string]string{"openscap": testPluginConfigRoot},
+			},
+			wantErr: "user config root for plugin openscap must not be in workspace testdata",
+		},
 	}
 
 	for _, c := range tests {
 		t.Run(c.name, func(t *testing.T) {
 			err := c.selections.Validate()
+			if err == nil {
+				var gotMap map[string]string
+				gotMap, err = c.selections.ToMap("openscap", testLogger)
+				if c.wantErr == "" {
+					require.Equal(t, c.wantMap, gotMap)
+				}
+			}
 			if c.wantErr != "" {
 				require.EqualError(t, err, c.wantErr)
 			} else {
 				require.NoError(t, err)
-				gotMap, _ := c.selections.ToMap("openscap", testLogger)
-				require.Equal(t, c.wantMap, gotMap)
 			}
 		})
 	}
 }
+
+func TestLaunchPlugins(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	cfg := framework.DefaultConfig()
+	cfg.PluginDir = t.TempDir()
+	cfg.Logger = testLogger
+	manager, err := framework.NewPluginManager(cfg)
+	require.NoError(t, err)
+	defer manager.Clean()
+
+	// plugins with missing executables fail to launch
+	manifests := make(plugin.Manifests)
+	for _, id := range []plugin.ID{"first", "second", "third"} {
+		manifests[id] = plugin.Manifest{
+			Metadata:       plugin.Metadata{ID: id},
+			ExecutablePath: filepath.Join(cfg.PluginDir, id.String()),
+			Checksum:       "00",
+		}
+	}
+	getSelections := func(plugin.ID) map[string]string { return nil }
+
+	for _, maxConcurrent := range []int{0, 2} {
+		plugins, err := launchPlugins(manager, manifests, getSelections, maxConcurrent, 0, testLogger)
+		require.Error(t, err)
+		require.Empty(t, plugins)
+	}
+
+	// launch errors are reported with a timeout as well
+	plugins, err := launchPlugins(manager, manifests, getSelections, 1, time.Minute, testLogger)
+	require.Error(t, err)
+	require.Empty(t, plugins)
+
+	// all failed launches are reported when launched concurrently
+	_, err = launchPlugins(manager, manifests, getSelections, 2, 0, testLogger)
+	for id := range manifests {
+		require.ErrorContains(t, err, id.String())
+	}
+}
+
+func TestLaunchStages(t *testing.T) {
+	manifests := make(plugin.Manifests)
+	for _, id := range []plugin.ID{"analyzer", "collector", "inventory", "reporter"} {
+		manifests[id] = plugin.Manifest{Metadata: plugin.Metadata{ID: id}}
+	}
+	stageIds := func(stages []plugin.Manifests) [][]plugin.ID {
+		var ids [][]plugin.ID
+		for _, stage := range stages {
+			ids = append(ids, slices.Sorted(maps.Keys(stage)))
+		}
+		return ids
+	}
+
+	stages, err := launchStages(manifests, nil)
+	require.NoError(t, err)
+	require.Equal(t, [][]plugin.ID{{"analyzer", "collector", "inventory", "reporter"}}, stageIds(stages))
+
+	stages, err = launchStages(manifests, map[plugin.ID][]plugin.ID{
+		"reporter":  {"analyzer"},
+		"analyzer":  {"collector", "inventory"},
+		"collector": {"inventory"},
+		"other":     {"missing"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]plugin.ID{{"inventory"}, {"collector"}, {"analyzer"}, {"reporter"}}, stageIds(stages))
+
+	_, err = launchStages(manifests, map[plugin.ID][]plugin.ID{"analyzer": {"missing"}})
+	require.EqualError(t, err, "plugin analyzer depends on plugin missing, which is not launched")
+
+	_, err = launchStages(manifests, map[plugin.ID][]plugin.ID{"analyzer": {"analyzer"}})
+	require.EqualError(t, err, "plugin analyzer depends on itself")
+
+	_, err = launchStages(manifests, map[plugin.ID][]plugin.ID{
+		"analyzer":  {"reporter"},
+		"collector": {"inventory"},
+		"inventory": {"analyzer"},
+		"reporter":  {"collector"},
+	})
+	require.EqualError(t, err, "plugin dependency cycle: analyzer -> reporter -> collector -> inventory -> analyzer")
+
+	_, err = launchStages(manifests, map[plugin.ID][]plugin.ID{
+		"analyzer":  {"inventory"},
+		"collector": {"reporter"},
+		"reporter":  {"collector"},
+	})
+	require.EqualError(t, err, "plugin dependency cycle: collector -> reporter -> collector")
+}
+
+func TestFindPlugins(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	cfg := framework.DefaultConfig()
+	cfg.PluginDir = t.TempDir()
+	cfg.PluginManifestDir = cfg.PluginDir
+	cfg.Logger = testLogger
+	manifest := `{"metadata": {"id": "present", "types": ["pvp"]}, "executablePath": "present-plugin", "sha256": "00"}`
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.PluginDir, "c2p-present-manifest.json"), []byte(manifest), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.PluginDir, "present-plugin"), []byte{}, 0700))
+	manager, err := framework.NewPluginManager(cfg)
+	require.NoError(t, err)
+
+	requested := []plugin.ID{"present", "missing"}
+	_, err = findPlugins(manager, requested, false, testLogger)
+	require.EqualError(t, err, "failed to find plugin \"missing\" in plugin installation location")
+
+	manifests, err := findPlugins(manager, requested, true, testLogger)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	require.Contains(t, manifests, plugin.ID("present"))
+
+	// best effort requires at least one installed plugin
+	_, err = findPlugins(manager, []plugin.ID{"missing"}, true, testLogger)
+	require.EqualError(t, err, "failed to find plugin \"missing\" in plugin installation location")
+}
+
+func TestReadConfigManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "Valid/Configuration",
+			manifest: `{"configuration": [{"name": "workspace"}]}`,
+		},
+		{
+			name:     "Invalid/Syntax",
+			manifest: "{\n  \"configuration\": [\n    {\"name\": \"workspace\",}\n  ]\n}",
+			wantErr:  "failed to parse plugin config file test.json at line 3, column 27: invalid character '}' looking for beginning of object key string",
+		},
+		{
+			name:     "Invalid/Type",
+			manifest: "{\n  \"configuration\": {\"name\": \"workspace\"}\n}",
+			wantErr:  "failed to parse plugin config file test.json at line 2, column 21",
+		},
+		{
+			name:     "Valid/SensitiveOption",
+			manifest: `{"configuration": [{"name": "password", "sensitive": true, "env": "SCAN_PASSWORD"}]}`,
+		},
+		{
+			name:     "Invalid/SensitiveOptionDefault",
+			manifest: `{"configuration": [{"name": "password", "sensitive": true, "default": "secret"}]}`,
+			wantErr:  "invalid plugin config file test.json: sensitive option password cannot have a default value, use env or file",
+		},
+		{
+			name:     "Invalid/NotSensitiveOptionEnv",
+			manifest: `{"configuration": [{"name": "password", "env": "SCAN_PASSWORD"}]}`,
+			wantErr:  "invalid plugin config file test.json: env and file are only supported for sensitive options, option password is not sensitive",
+		},
+		{
+			name:     "Invalid/MissingConfiguration",
+			manifest: `{"metadata": {"id": "openscap"}}`,
+			wantErr:  "invalid plugin config file test.json: missing \"configuration\" list",
+		},
+		{
+			name:     "Invalid/MissingName",
+			manifest: `{"configuration": [{"name": "workspace"}, {"default": "cis"}]}`,
+			wantErr:  "invalid plugin config file test.json: configuration option 1 has no name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readConfigManifest(strings.NewReader(tt.manifest), "test.json")
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigManifestsLoad(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "c2p-openscap-manifest.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"configuration": [{"name": "policy", "default": "tailoring_policy.xml"}]}`), 0600))
+
+	manifests := make(configManifests)
+	manifest, err := manifests.load(configPath)
+	require.NoError(t, err)
+	require.Len(t, manifest.Configuration, 1)
+
+	// the file is parsed once, later loads use the cached manifest
+	require.NoError(t, os.Remove(configPath))
+	cached, err := manifests.load(configPath)
+	require.NoError(t, err)
+	require.Equal(t, manifest, cached)
+}
+
+func TestSensitiveOptions(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	configRoot := t.TempDir()
+	secretFile := filepath.Join(configRoot, "token")
+	require.NoError(t, os.WriteFile(secretFile, []byte("filetoken\n"), 0600))
+	manifest := fmt.Sprintf(`{"configuration": [
+		{"name": "password", "sensitive": true, "required": true, "env": "COMPLYTIME_TEST_PASSWORD"},
+		{"name": "token", "sensitive": true, "env": "COMPLYTIME_TEST_TOKEN", "file": %q},
+		{"name": "key", "sensitive": true, "file": "nonexistent"}
+	]}`, secretFile)
+	require.NoError(t, os.WriteFile(filepath.Join(configRoot, "c2p-openscap-manifest.json"), []byte(manifest), 0600))
+	selections := PluginOptions{
+		Workspace:      "testworkspace",
+		UserConfigRoot: configRoot,
+	}
+
+	_, err := selections.ToMap("openscap", testLogger)
+	require.ErrorContains(t, err, "missing value for required sensitive option password")
+
+	t.Setenv("COMPLYTIME_TEST_PASSWORD", "envpassword")
+	var logs bytes.Buffer
+	debugLogger := hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Debug})
+	gotMap, err := selections.ToMap("openscap", debugLogger)
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), `Option set: plugin=openscap option=password value=[REDACTED] source="environment variable COMPLYTIME_TEST_PASSWORD"`)
+	require.NotContains(t, logs.String(), "envpassword")
+	require.NotContains(t, logs.String(), "filetoken")
+	require.Equal(t, map[string]string{
+		"workspace": "testworkspace",
+		"password":  "envpassword",
+		"token":     "filetoken",
+		"key":       "",
+	}, gotMap)
+
+	pluginSelections, err := selections.toSelections("openscap", nil, make(configManifests), testLogger)
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token", "key"}, pluginSelections.Sensitive)
+
+	// the environment variable takes precedence over the file
+	t.Setenv("COMPLYTIME_TEST_TOKEN", "envtoken")
+	gotMap, err = selections.ToMap("openscap", testLogger)
+	require.NoError(t, err)
+	require.Equal(t, "envtoken", gotMap["token"])
+}
+
+func TestRequiredOptionsFromEnv(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	configRoot := t.TempDir()
+	manifest := `{"configuration": [
+		{"name": "datastream", "required": true},
+		{"name": "results", "required": true, "default": "results.xml"},
+		{"name": "arf"}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(configRoot, "c2p-openscap-manifest.json"), []byte(manifest), 0600))
+	selections := PluginOptions{
+		Workspace:      "testworkspace",
+		UserConfigRoot: configRoot,
+	}
+
+	_, err := selections.ToMap("openscap", testLogger)
+	require.EqualError(t, err, fmt.Sprintf("missing value for required option datastream in %s: set a default value or the environment variable COMPLYTIME_OPENSCAP_DATASTREAM",
+		filepath.Join(configRoot, "c2p-openscap-manifest.json")))
+
+	// a required option without default value is satisfied by the environment
+	t.Setenv("COMPLYTIME_OPENSCAP_DATASTREAM", "ssg-rhel10-ds.xml")
+	var logs bytes.Buffer
+	debugLogger := hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Debug})
+	gotMap, err := selections.ToMap("openscap", debugLogger)
+	require.NoError(t, err)
+	// the override is logged without its value
+	require.Contains(t, logs.String(), `Option overridden by the environment: plugin=openscap option=datastream source="environment variable COMPLYTIME_OPENSCAP_DATASTREAM"`)
+	require.NotContains(t, logs.String(), "ssg-rhel10-ds.xml")
+	require.Equal(t, map[string]string{
+		"workspace":  "testworkspace",
+		"datastream": "ssg-rhel10-ds.xml",
+		"results":    "results.xml",
+		"arf":        "",
+	}, gotMap)
+}
+
+func TestValidatePluginConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "c2p-openscap-manifest.json")
+	manifest := `{"configuration": [
+		{"name": "workspace", "default": "/tmp"},
+		{"name": "profile", "default": "cis"},
+		{"name": "datastream", "required": true},
+		{"name": "results", "required": true, "default": "results.xml"},
+		{"name": "results", "default": "other.xml"},
+		{"name": "token", "required": true, "sensitive": true, "env": "TEST_OPENSCAP_TOKEN"},
+		{"name": "arf"}
+	]}`
+	require.NoError(t, os.WriteFile(configPath, []byte(manifest), 0600))
+
+	problems := ValidatePluginConfig("openscap", configPath)
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	require.Equal(t, []string{
+		fmt.Sprintf("invalid plugin config file %s: option workspace is set by complyctl and cannot be configured", configPath),
+		fmt.Sprintf("missing value for required option datastream in %s: set a default value or the environment variable COMPLYTIME_OPENSCAP_DATASTREAM", configPath),
+		fmt.Sprintf("invalid plugin config file %s: option results is defined several times", configPath),
+		fmt.Sprintf("missing value for required sensitive option token in %s", configPath),
+	}, messages)
+
+	// required options can be set in the environment
+	t.Setenv("COMPLYTIME_OPENSCAP_DATASTREAM", "ssg-rhel10-ds.xml")
+	t.Setenv("TEST_OPENSCAP_TOKEN", "secret")
+	require.Len(t, ValidatePluginConfig("openscap", configPath), 2)
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"configuration": [{"name": "profile", "sensitive": true}]}`), 0600))
+	require.EqualError(t, errors.Join(ValidatePluginConfig("openscap", configPath)...),
+		fmt.Sprintf("invalid plugin config file %s: option profile cannot be sensitive, set a default value", configPath))
+
+	// a file that does not parse is the only problem reported
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"configuration": [`), 0600))
+	problems = ValidatePluginConfig("openscap", configPath)
+	require.Len(t, problems, 1)
+	require.ErrorContains(t, problems[0], "failed to parse plugin config file")
+
+	problems = ValidatePluginConfig("openscap", filepath.Join(t.TempDir(), "missing.json"))
+	require.Len(t, problems, 1)
+	require.ErrorContains(t, problems[0], "failed to open plugin config file")
+}
+
+func TestSelectionsRedacted(t *testing.T) {
+	selections := Selections{
+		Values: map[string]string{
+			"workspace": "testworkspace",
+			"password":  "secret",
+		},
+		Sensitive: []string{"password", "token"},
+	}
+	require.Equal(t, map[string]string{
+		"workspace": "testworkspace",
+		"password":  "[REDACTED]",
+	}, selections.Redacted())
+	// the selections are left unchanged
+	require.Equal(t, "secret", selections.Values["password"])
+}
+
+func TestContainsPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	configRoot := filepath.Join(tmpDir, "config")
+	require.NoError(t, os.Mkdir(configRoot, 0700))
+	link := filepath.Join(tmpDir, "link")
+	require.NoError(t, os.Symlink(configRoot, link))
+
+	require.True(t, containsPath(tmpDir, configRoot))
+	require.True(t, containsPath(configRoot, configRoot))
+	require.True(t, containsPath(link, configRoot))
+	require.True(t, containsPath(configRoot, link))
+	require.False(t, containsPath(configRoot, tmpDir))
+	require.False(t, containsPath(filepath.Join(tmpDir, "workspace"), configRoot))
+	// sibling directories sharing a prefix
+	require.False(t, containsPath(configRoot, configRoot+"-backup"))
+}
+
+func TestOptionEnv(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	require.Equal(t, "COMPLYTIME_OPENSCAP_RESULTS", OptionEnv("openscap", "results"))
+	require.Equal(t, "COMPLYTIME_MY_PLUGIN_SCAN_ROOT", OptionEnv("my-plugin", "scan.root"))
+
+	defaultPolicy := "default_policy.xml"
+	installed := []plugin.ConfigurationOption{
+		{Name: "workspace"},
+		{Name: "profile"},
+		{Name: "results"},
+		{Name: "policy", Default: &defaultPolicy},
+		{Name: "arf"},
+	}
+	selections := PluginOptions{
+		Workspace:      "testworkspace",
+		Profile:        "testprofile",
+		UserConfigRoot: testPluginConfigRoot,
+	}
+	t.Setenv("COMPLYTIME_OPENSCAP_WORKSPACE", "envworkspace")
+	t.Setenv("COMPLYTIME_OPENSCAP_PROFILE", "envprofile")
+	t.Setenv("COMPLYTIME_OPENSCAP_RESULTS", "env_results.xml")
+	t.Setenv("COMPLYTIME_OPENSCAP_POLICY", "env_policy.xml")
+	pluginSelections, err := selections.toSelections("openscap", installed, make(configManifests), testLogger)
+	require.NoError(t, err)
+	// workspace and profile are selected by complyctl and cannot be set in
+	// the environment, options without environment variable are left to the
+	// manifest defaults
+	require.Equal(t, map[string]string{
+		"workspace": "testworkspace",
+		"profile":   "testprofile",
+		"results":   "env_results.xml",
+		"policy":    "env_policy.xml",
+	}, pluginSelections.Values)
+}
+
+func TestLaunchPluginTimeout(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	cfg := framework.DefaultConfig()
+	cfg.PluginDir = t.TempDir()
+	cfg.Logger = testLogger
+	manager, err := framework.NewPluginManager(cfg)
+	require.NoError(t, err)
+	defer manager.Clean()
+
+	// the plugin does not complete the handshake before the timeout
+	executable := []byte("#!/bin/sh\nexec sleep 2\n")
+	executablePath := filepath.Join(cfg.PluginDir, "stuck-plugin")
+	require.NoError(t, os.WriteFile(executablePath, executable, 0700))
+	checksum := sha256.Sum256(executable)
+	manifest := plugin.Manifest{
+		Metadata:       plugin.Metadata{ID: "stuck"},
+		ExecutablePath: executablePath,
+		Checksum:       hex.EncodeToString(checksum[:]),
+	}
+	getSelections := func(plugin.ID) map[string]string { return nil }
+
+	plugins, err := launchPlugin(manager, "stuck", manifest, getSelections, 100*time.Millisecond)
+	require.EqualError(t, err, "plugin stuck did not launch within 100ms")
+	require.Empty(t, plugins)
+}
+
+func TestValidatePlugins(t *testing.T) {
+	testLogger := hclog.NewNullLogger()
+	cfg := framework.DefaultConfig()
+	cfg.PluginDir = t.TempDir()
+	cfg.PluginManifestDir = cfg.PluginDir
+	cfg.Logger = testLogger
+	for _, id := range []string{"configured", "unconfigured"} {
+		manifest := fmt.Sprintf(`{"metadata": {"id": %q, "types": ["pvp"]}, "executablePath": "%s-plugin", "sha256": "00",
+			"configuration": [{"name": "workspace", "required": true}, {"name": "token", "required": true}]}`, id, id)
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.PluginDir, "c2p-"+id+"-manifest.json"), []byte(manifest), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.PluginDir, id+"-plugin"), []byte{}, 0700))
+	}
+	manager, err := framework.NewPluginManager(cfg)
+	require.NoError(t, err)
+
+	var validationComponents []components.Component
+	for _, id := range []string{"unconfigured", "missing", "configured"} {
+		validationComponents = append(validationComponents, components.NewDefinedComponentAdapter(oscalTypes.DefinedComponent{
+			Type:  "validation",
+			Title: id,
+		}))
+	}
+	inputs, err := actions.NewContext(validationComponents)
+	require.NoError(t, err)
+
+	t.Setenv("COMPLYTIME_CONFIGURED_TOKEN", "secret")
+	selections := PluginOptions{
+		Workspace:      "testworkspace",
+		UserConfigRoot: t.TempDir(),
+	}
+	report, err := ValidatePlugins(manager, inputs, selections, testLogger)
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+
+	require.Equal(t, plugin.ID("configured"), report[0].ID)
+	require.True(t, report[0].Ready())
+	require.Equal(t, map[string]string{"workspace": "testworkspace", "token": "secret"}, report[0].Selections.Values)
+	require.Equal(t, plugin.ID("missing"), report[1].ID)
+	require.EqualError(t, report[1].Err, "plugin missing is not installed")
+	require.Equal(t, plugin.ID("unconfigured"), report[2].ID)
+	require.EqualError(t, report[2].Err, "failed to configure plugin unconfigured: required value not supplied for option \"token\"")
+
+	_, err = ValidatePlugins(manager, inputs, PluginOptions{}, testLogger)
+	require.EqualError(t, err, "failed plugin config validation: workspace must be set")
+}
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package complytime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/oscal-compass/oscal-sdk-go/rules"
+	"github.com/oscal-compass/oscal-sdk-go/settings"
+)
+
+// PluginIDProp is the subject property holding the id of the plugin that
+// reported the observation. The properties of the observations themselves are
+// not kept in the assessment results.
+const PluginIDProp = "plugin-id"
+
+// AggregateResults gets the results of each launched plugin for the policy of
+// its component and merges them into a single PVPResult, with the subjects of
+// every observation tagged with the id of its plugin. A plugin failing to get
+// its results does not stop the others: the merged results of the successful
+// plugins are returned along with the errors of the failed ones.
+func AggregateResults(ctx context.Context, inputContext *actions.InputContext, providers map[plugin.ID]policy.Provider, logger hclog.Logger) (policy.PVPResult, error) {
+	var aggregated policy.PVPResult
+	var errs []error
+	// plugins are queried in id order for reproducible results
+	for _, pluginId := range slices.Sorted(maps.Keys(providers)) {
+		pluginResults, err := getPluginResults(ctx, inputContext, pluginId, providers[pluginId])
+		if err != nil {
+			logger.Error("Failed to get the results of a plugin", "plugin", pluginId, "err", err)
+			errs = append(errs, fmt.Errorf("plugin %s: %w", pluginId, err))
+			continue
+		}
+		logger.Debug("Aggregating plugin results", "plugin", pluginId, "observations", len(pluginResults.ObservationsByCheck))
+		for _, observation := range pluginResults.ObservationsByCheck {
+			aggregated.ObservationsByCheck = append(aggregated.ObservationsByCheck, tagObservation(observation, pluginId))
+		}
+		aggregated.Links = append(aggregated.Links, pluginResults.Links...)
+	}
+	return aggregated, errors.Join(errs...)
+}
+
+// getPluginResults gets the results of a plugin for the policy of its
+// component, with the settings of the input context applied.
+func getPluginResults(ctx context.Context, inputContext *actions.InputContext, pluginId plugin.ID, provider policy.Provider) (policy.PVPResult, error) {
+	componentTitle, err := inputContext.ProviderTitle(pluginId)
+	if err != nil {
+		return policy.PVPResult{}, err
+	}
+	appliedRuleSet, err := settings.ApplyToComponent(ctx, componentTitle, inputContext.Store(), inputContext.Settings)
+	if err != nil {
+		return policy.PVPResult{}, fmt.Errorf("failed to get rule sets for component %s: %w", componentTitle, err)
+	}
+	return provider.GetResults(appliedRuleSet)
+}
+
+// tagObservation returns a copy of the observation with the id of the plugin
+// added to the properties of its subjects.
+func tagObservation(observation policy.ObservationByCheck, pluginId plugin.ID) policy.ObservationByCheck {
+	subjects := make([]policy.Subject, 0, len(observation.Subjects))
+	for _, subject := range observation.Subjects {
+		subject.Props = append(slices.Clip(subject.Props), policy.Property{Name: PluginIDProp, Value: string(pluginId)})
+		subjects = append(subjects, subject)
+	}
+	observation.Subjects = subjects
+	return observation
+}
+
+// ControlStatus is the status of a control rolled up from the results of
+// its rules.
+type ControlStatus string
+
+const (
+	// ControlPass is the status of a control all the rules of which
+	// passed.
+	ControlPass ControlStatus = "pass"
+	// ControlFail is the status of a control one of the rules of which
+	// failed.
+	ControlFail ControlStatus = "fail"
+	// ControlPartial is the status of a control none of the rules of which
+	// failed, but some rules did not pass, because they have errors,
+	// warnings or no results.
+	ControlPartial ControlStatus = "partial"
+)
+
+// RuleStatus is the worst result of a rule across its checks and subjects.
+type RuleStatus struct {
+	RuleID string
+	// Result is policy.ResultInvalid when the rule has no result.
+	Result policy.Result
+}
+
+// ControlRollup is the status of a control with the rules it is rolled up
+// from.
+type ControlRollup struct {
+	ControlID string
+	Status    ControlStatus
+	// Rules are the rules of the control, sorted by rule id.
+	Rules []RuleStatus
+}
+
+// resultSeverities orders the results from the best to the worst.
+var resultSeverities = map[policy.Result]int{
+	policy.ResultInvalid: 0,
+	policy.ResultPass:    1,
+	policy.ResultWarning: 2,
+	policy.ResultError:   3,
+	policy.ResultFail:    4,
+}
+
+// RollupControls rolls up the results of the rules to the controls of
+// rulesByControl, the rule ids by control id. The rule of a check is found in
+// the store. A control fails when one of its rules failed on any subject, and
+// passes when all its rules passed. Observations of checks without rule are
+// ignored, as in the assessment results. The rollups are sorted by control id.
+func RollupControls(ctx context.Context, result policy.PVPResult, store rules.Store, rulesByControl map[string][]string) ([]ControlRollup, error) {
+	ruleResults := make(map[string]policy.Result)
+	for _, observation := range result.ObservationsByCheck {
+		ruleSet, err := store.GetByCheckID(ctx, observation.CheckID)
+		if err != nil {
+			if errors.Is(err, rules.ErrRuleNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to find the rule of check %s: %w", observation.CheckID, err)
+		}
+		ruleID := ruleSet.Rule.ID
+		for _, subject := range observation.Subjects {
+			if resultSeverities[subject.Result] > resultSeverities[ruleResults[ruleID]] {
+				ruleResults[ruleID] = subject.Result
+			}
+		}
+	}
+
+	rollups := make([]ControlRollup, 0, len(rulesByControl))
+	for _, controlID := range slices.Sorted(maps.Keys(rulesByControl)) {
+		rollup := ControlRollup{ControlID: controlID, Status: ControlPass}
+		for _, ruleID := range slices.Sorted(slices.Values(rulesByControl[controlID])) {
+			ruleResult := ruleResults[ruleID]
+			rollup.Rules = append(rollup.Rules, RuleStatus{RuleID: ruleID, Result: ruleResult})
+			switch {
+			case ruleResult == policy.ResultFail:
+				rollup.Status = ControlFail
+			case ruleResult != policy.ResultPass && rollup.Status == ControlPass:
+				rollup.Status = ControlPartial
+			}
+		}
+		rollups = append(rollups, rollup)
+	}
+	return rollups, nil
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package complytime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	oscalTypes "github.com/defenseunicorns/go-oscal/src/types/oscal-1-1-3"
+	"github.com/hashicorp/go-hclog"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/framework/actions"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/plugin"
+	"github.com/oscal-compass/compliance-to-policy-go/v2/policy"
+	"github.com/oscal-compass/oscal-sdk-go/extensions"
+	"github.com/oscal-compass/oscal-sdk-go/models/components"
+	"github.com/oscal-compass/oscal-sdk-go/settings"
+	"github.com/stretchr/testify/require"
+)
+
+// resultsProvider is a policy.Provider returning fixed results.
+type resultsProvider struct {
+	results policy.PVPResult
+	err     error
+	called  bool
+}
+
+func (p *resultsProvider) Configure(map[string]string) error { return nil }
+
+func (p *resultsProvider) Generate(policy.Policy) error { return nil }
+
+func (p *resultsProvider) GetResults(policy.Policy) (policy.PVPResult, error) {
+	p.called = true
+	return p.results, p.err
+}
+
+func TestAggregateResults(t *testing.T) {
+	var validationComponents []components.Component
+	for _, id := range []string{"first", "second", "failing"} {
+		props := []oscalTypes.Property{
+			{Name: extensions.RuleIdProp, Value: "rule_" + id, Ns: extensions.TrestleNameSpace, Remarks: "rule_set_" + id},
+			{Name: extensions.CheckIdProp, Value: "check_" + id, Ns: extensions.TrestleNameSpace, Remarks: "rule_set_" + id},
+		}
+		validationComponents = append(validationComponents, components.NewDefinedComponentAdapter(oscalTypes.DefinedComponent{
+			Type:  "validation",
+			Title: id,
+			Props: &props,
+		}))
+	}
+	inputContext, err := actions.NewContext(validationComponents)
+	require.NoError(t, err)
+	inputContext.Settings = settings.NewSettings(map[string]struct{}{
+		"rule_first": {}, "rule_second": {}, "rule_failing": {},
+	}, nil)
+
+	observation := func(checkID string) policy.ObservationByCheck {
+		return policy.ObservationByCheck{
+			CheckID: checkID,
+			Subjects: []policy.Subject{{
+				ResourceID: "localhost",
+				Result:     policy.ResultPass,
+				Props:      []policy.Property{{Name: "severity", Value: "high"}},
+			}},
+		}
+	}
+	first := &resultsProvider{results: policy.PVPResult{
+		ObservationsByCheck: []policy.ObservationByCheck{observation("check_first")},
+		Links:               []policy.Link{{Href: "file:///first.xml"}},
+	}}
+	second := &resultsProvider{results: policy.PVPResult{
+		ObservationsByCheck: []policy.ObservationByCheck{observation("check_second")},
+	}}
+	failing := &resultsProvider{err: errors.New("scan failed")}
+	providers := map[plugin.ID]policy.Provider{
+		"second":  second,
+		"failing": failing,
+		"first":   first,
+		"missing": &resultsProvider{},
+	}
+
+	results, err := AggregateResults(context.Background(), inputContext, providers, hclog.NewNullLogger())
+	require.ErrorContains(t, err, "plugin failing: scan failed")
+	require.ErrorContains(t, err, "plugin missing:")
+	require.True(t, first.called)
+	require.True(t, second.called)
+	require.True(t, failing.called)
+
+	require.Len(t, results.ObservationsByCheck, 2)
+	require.Equal(t, "check_first", results.ObservationsByCheck[0].CheckID)
+	require.Equal(t, []policy.Property{
+		{Name: "severity", Value: "high"},
+		{Name: PluginIDProp, Value: "first"},
+	}, results.ObservationsByCheck[0].Subjects[0].Props)
+	require.Equal(t, "check_second", results.ObservationsByCheck[1].CheckID)
+	require.Equal(t, []policy.Property{
+		{Name: "severity", Value: "high"},
+		{Name: PluginIDProp, Value: "second"},
+	}, results.ObservationsByCheck[1].Subjects[0].Props)
+	require.Equal(t, []policy.Link{{Href: "file:///first.xml"}}, results.Links)
+
+	// the observations of the plugins are left untouched
+	require.Len(t, first.results.ObservationsByCheck[0].Subjects[0].Props, 1)
+
+	delete(providers, "failing")
+	delete(providers, "missing")
+	_, err = AggregateResults(context.Background(), inputContext, providers, hclog.NewNullLogger())
+	require.NoError(t, err)
+}
+
+func TestRollupControls(t *testing.T) {
+	var props []oscalTypes.Property
+	for _, rule := range []string{"rule_a", "rule_b", "rule_c", "rule_d"} {
+		props = append(props,
+			oscalTypes.Property{Name: extensions.RuleIdProp, Value: rule, Ns: extensions.TrestleNameSpace, Remarks: rule},
+			oscalTypes.Property{Name: extensions.CheckIdProp, Value: "check_" + rule, Ns: extensions.TrestleNameSpace, Remarks: rule},
+		)
+	}
+	inputContext, err := actions.NewContext([]components.Component{components.NewDefinedComponentAdapter(oscalTypes.DefinedComponent{
+		Type:  "validation",
+		Title: "openscap",
+		Props: &props,
+	})})
+	require.NoError(t, err)
+
+	observation := func(checkID string, results ...policy.Result) policy.ObservationByCheck {
+		observation := policy.ObservationByCheck{CheckID: checkID}
+		for _, result := range results {
+			observation.Subjects = append(observation.Subjects, policy.Subject{ResourceID: "localhost", Result: result})
+		}
+		return observation
+	}
+	results := policy.PVPResult{ObservationsByCheck: []policy.ObservationByCheck{
+		observation("check_rule_a", policy.ResultPass),
+		// the worst result across the subjects is kept
+		observation("check_rule_b", policy.ResultPass, policy.ResultFail, policy.ResultError),
+		observation("check_rule_c", policy.ResultPass, policy.ResultError),
+		observation("unknown_check", policy.ResultFail),
+	}}
+	rulesByControl := map[string][]string{
+		"ac-2": {"rule_a"},
+		"ac-1": {"rule_b", "rule_a"},
+		"cm-6": {"rule_c", "rule_a"},
+		"si-4": {"rule_d"},
+	}
+
+	rollups, err := RollupControls(context.Background(), results, inputContext.Store(), rulesByControl)
+	require.NoError(t, err)
+	require.Equal(t, []ControlRollup{
+		{ControlID: "ac-1", Status: ControlFail, Rules: []RuleStatus{
+			{RuleID: "rule_a", Result: policy.ResultPass},
+			{RuleID: "rule_b", Result: policy.ResultFail},
+		}},
+		{ControlID: "ac-2", Status: ControlPass, Rules: []RuleStatus{
+			{RuleID: "rule_a", Result: policy.ResultPass},
+		}},
+		{ControlID: "cm-6", Status: ControlPartial, Rules: []RuleStatus{
+			{RuleID: "rule_a", Result: policy.ResultPass},
+			{RuleID: "rule_c", Result: policy.ResultError},
+		}},
+		{ControlID: "si-4", Status: ControlPartial, Rules: []RuleStatus{
+			{RuleID: "rule_d", Result: policy.ResultInvalid},
+		}},
+	}, rollups)
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pluginerr defines the failure categories shared by complyctl and its
+// plugins.
+//
+// Plugins run in their own process and their errors reach complyctl over gRPC
+// with their message only, so errors.Is cannot match them on the complyctl
+// side. The message of an Error therefore carries its code, and Is matches the
+// code found in the message of an error instead of its wording.
+package pluginerr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// codeRegex captures the codes carried by the message of an error.
+var codeRegex = regexp.MustCompile(`\[code=([a-z][a-z-]*)\]`)
+
+// ErrDatastreamInvalid is returned when the datastream cannot be found or is
+// not a valid SCAP content file.
+var ErrDatastreamInvalid = New("datastream-invalid", "invalid datastream")
+
+// ErrProfileNotFound is returned when a profile is not defined in the
+// datastream.
+var ErrProfileNotFound = New("profile-not-found", "profile not found")
+
+// ErrScanFailed is returned when oscap fails to evaluate the system.
+var ErrScanFailed = New("scan-failed", "failed during scan")
+
+// ErrARFParse is returned when the rule results cannot be read from an ARF.
+var ErrARFParse = New("arf-parse", "error parsing ARF")
+
+// ErrRemediationFailed is returned by a plugin that generated its policy but
+// not its remediation files, such as the openscap plugin when oscap fails to
+// generate the remediation of the tailoring it wrote.
+var ErrRemediationFailed = New("remediation-failed", "tailoring generated but remediation generation failed")
+
+// Error is a failure category of a plugin, identified by its code.
+type Error struct {
+	code    string
+	message string
+}
+
+// New returns an Error with the given code, made of lowercase letters and
+// dashes, and message.
+func New(code, message string) *Error {
+	return &Error{code: code, message: message}
+}
+
+// Code returns the code of the error.
+func (e *Error) Code() string {
+	return e.code
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s [code=%s]", e.message, e.code)
+}
+
+// Is reports whether err is target, either in its chain of wrapped errors or,
+// for an error received from a plugin, by the code carried in its message.
+func Is(err error, target *Error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, target) {
+		return true
+	}
+	for _, match := range codeRegex.FindAllStringSubmatch(err.Error(), -1) {
+		if match[1] == target.code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIs(t *testing.T) {
+	require.False(t, Is(nil, ErrRemediationFailed))
+	require.True(t, Is(fmt.Errorf("plugin openscap: %w", ErrRemediationFailed), ErrRemediationFailed))
+
+	// the message of a plugin error received over gRPC
+	wrapped := fmt.Errorf("%w: oscap failed", ErrRemediationFailed)
+	grpcErr := errors.New("rpc error: code = Internal desc = " + wrapped.Error())
+	require.True(t, Is(grpcErr, ErrRemediationFailed))
+	require.Equal(t, "remediation-failed", ErrRemediationFailed.Code())
+
+	// errors are matched by code, not by wording
+	require.False(t, Is(errors.New("rpc error: code = Internal desc = tailoring generated but remediation generation failed"), ErrRemediationFailed))
+	other := New("other-failed", "failed")
+	require.False(t, Is(errors.New("rpc error: code = Internal desc = "+other.Error()), ErrRemediationFailed))
+	require.True(t, Is(errors.New("rpc error: code = Internal desc = "+other.Error()), other))
+}